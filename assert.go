@@ -1,11 +1,17 @@
 package dbtestify
 
 import (
+	"bytes"
 	"cmp"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // AssertResult represents the result of an assertion operation on a dataset.
@@ -13,6 +19,77 @@ type AssertResult struct {
 	Tables []AssertTableResult
 }
 
+// NewAssertResult wraps the table results returned by Assert.
+func NewAssertResult(tables []AssertTableResult) AssertResult {
+	return AssertResult{Tables: tables}
+}
+
+// AssertResult.IsMatch returns true if all tables match.
+func (r AssertResult) IsMatch() bool {
+	for _, t := range r.Tables {
+		if t.Status != Match {
+			return false
+		}
+	}
+	return true
+}
+
+// AssertResult.FailedTables returns the tables that don't match.
+func (r AssertResult) FailedTables() []AssertTableResult {
+	var result []AssertTableResult
+	for _, t := range r.Tables {
+		if t.Status != Match {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// AssertResult.DiffCount returns the number of different rows in all tables, including the rows omitted by AssertOpt.MaxDiffRows.
+func (r AssertResult) DiffCount() int {
+	var count int
+	for _, t := range r.Tables {
+		count += t.diffCount()
+	}
+	return count
+}
+
+// AssertResult.Summary returns a one-line description of the result like "1 of 3 tables does not match: user (2 rows)".
+func (r AssertResult) Summary() string {
+	failed := r.FailedTables()
+	if len(failed) == 0 {
+		return fmt.Sprintf("%d %s match", len(r.Tables), plural(len(r.Tables), "table", "tables"))
+	}
+	details := make([]string, len(failed))
+	for i, t := range failed {
+		c := t.diffCount()
+		details[i] = fmt.Sprintf("%s (%d %s)", t.Name, c, plural(c, "row", "rows"))
+	}
+	return fmt.Sprintf("%d of %d %s %s not match: %s", len(failed), len(r.Tables), plural(len(r.Tables), "table", "tables"), plural(len(failed), "does", "do"), strings.Join(details, ", "))
+}
+
+func (t AssertTableResult) diffCount() int {
+	var count int
+	for _, row := range t.Rows {
+		switch row.Status {
+		case Match:
+		case Truncated:
+			omitted, _ := strconv.Atoi(row.Fields[0].Key)
+			count += omitted
+		default:
+			count++
+		}
+	}
+	return count
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
 // AssertStatus defines the status of an assertion result.
 type AssertStatus string
 
@@ -22,14 +99,15 @@ const (
 	OnlyOnExpect AssertStatus = "only-e"
 	OnlyOnActual AssertStatus = "only-a"
 	WrongDataSet AssertStatus = "wrongDataSet" // primary keys are missing
+	Truncated    AssertStatus = "truncated"    // sentinel row that reports the number of omitted rows (see AssertOpt.MaxDiffRows)
 )
 
 // AssertTableResult represents the result of an assertion on a single table in AssertResult.
 type AssertTableResult struct {
-	Name        string
-	PrimaryKeys []string
-	Rows        []RowDiff
-	Status      AssertStatus
+	Name        string       `json:"name"`
+	PrimaryKeys []string     `json:"primary_keys"`
+	Rows        []RowDiff    `json:"rows"`
+	Status      AssertStatus `json:"status"`
 }
 
 // RowDiff represents the difference in a row between the expected and actual data.
@@ -38,6 +116,49 @@ type RowDiff struct {
 	Status AssertStatus `json:"status"`
 }
 
+// RowDiff.IsMatch returns true if the row matches.
+func (r RowDiff) IsMatch() bool {
+	return r.Status == Match
+}
+
+// RowDiff.MismatchedFields returns the fields that exist in both rows but have different values.
+func (r RowDiff) MismatchedFields() []Diff {
+	return r.fieldsWith(func(d Diff) bool {
+		return d.Status == NotMatch
+	})
+}
+
+// RowDiff.MissingFields returns the fields that are only in the expected row.
+//
+// If the whole row is only on expected, all fields are returned.
+func (r RowDiff) MissingFields() []Diff {
+	return r.fieldsWith(func(d Diff) bool {
+		return r.Status == OnlyOnExpect || d.Status == WrongDataSet || d.Status == OnlyOnExpect
+	})
+}
+
+// RowDiff.ExtraFields returns the fields that are only in the actual row.
+//
+// Fields that are not in the data set are not compared, so it returns fields only if the whole row is only on actual.
+func (r RowDiff) ExtraFields() []Diff {
+	return r.fieldsWith(func(d Diff) bool {
+		return r.Status == OnlyOnActual || d.Status == OnlyOnActual
+	})
+}
+
+func (r RowDiff) fieldsWith(cond func(d Diff) bool) []Diff {
+	if r.Status == Truncated {
+		return nil
+	}
+	var result []Diff
+	for _, f := range r.Fields {
+		if cond(f) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
 // Value represents a single value in a row, including its key and value.
 type Diff struct {
 	Key    string       `json:"key"`
@@ -46,13 +167,33 @@ type Diff struct {
 	Status AssertStatus `json:"status"`
 }
 
+// ErrAssertFailed is returned by Assert when AssertOpt.FailFast is true and a table doesn't match.
+//
+// RowCount is the number of different rows of the table. The result returned with this error is still available.
+type ErrAssertFailed struct {
+	TableName string
+	RowCount  int
+}
+
+func (e ErrAssertFailed) Error() string {
+	return fmt.Sprintf("table '%s' does not match: %d different %s", e.TableName, e.RowCount, plural(e.RowCount, "row", "rows"))
+}
+
 // MatchStrategy defines the strategy for matching rows in a table.
 type AssertOpt struct {
-	IncludeTags  []string                                                            // Tags to filter rows of dataset.
-	ExcludeTags  []string                                                            // Tags to filter rows of dataset.
-	TargetTables []string                                                            // Only specified tables will be processed. If empty, all tables will be processed.
-	Callback     func(targetTable string, mode MatchStrategy, start bool, err error) // Callback function to report progress and errors during the assertion process.
-	DiffCallback func(result AssertTableResult)                                      // Callback function to report differences in rows during the assertion process.
+	IncludeTags     []string                                                            // Tags to filter rows of dataset.
+	ExcludeTags     []string                                                            // Tags to filter rows of dataset.
+	TargetTables    []string                                                            // Only specified tables will be processed. If empty, all tables will be processed. Glob patterns like `audit_*` are allowed.
+	Callback        func(targetTable string, mode MatchStrategy, start bool, err error) // Callback function to report progress and errors during the assertion process.
+	DiffCallback    func(result AssertTableResult)                                      // Callback function to report differences in rows during the assertion process.
+	AllDiffCallback func(results []AssertTableResult)                                   // Callback function called once with the results of all tables after they are processed.
+	RowFilter       func(tableName string, row []Value) bool                            // Rows for which it returns false are excluded from both expected and actual rows.
+	FailFast        bool                                                                // Stop after the first table that doesn't match. Remaining tables are skipped.
+	MaxDiffRows     int                                                                 // Maximum number of different rows reported per table. If zero, all rows are reported.
+	IgnoreColumns   map[string][]string                                                 // Columns excluded from comparison per table. Primary keys are always compared.
+	OrderBy         map[string][]string                                                 // ORDER BY columns per table. Rows are compared in this order, so the dataset should have the rows in the same order.
+	SelectColumns   map[string][]string                                                 // Columns fetched from the database per table instead of `*`. It overrides ColumnSelector of DBConnector.
+	TimeZone        *time.Location                                                      // If set, time values and time strings in the dataset are converted to this location before comparison.
 }
 
 // Assert performs an assertion on the provided dataset against the database.
@@ -61,10 +202,8 @@ func Assert(ctx context.Context, dbc DBConnector, expected *DataSet, opt AssertO
 	var result []AssertTableResult
 	ok := true
 	for _, t := range expected.Tables {
-		if len(opt.TargetTables) > 0 {
-			if !slices.Contains(opt.TargetTables, t.Name) {
-				continue
-			}
+		if !MatchTargetTables(opt.TargetTables, t.Name) {
+			continue
 		}
 		strategy := ExactMatchStrategy
 		if s, ok := expected.Match[t.Name]; ok {
@@ -73,7 +212,24 @@ func Assert(ctx context.Context, dbc DBConnector, expected *DataSet, opt AssertO
 		if opt.Callback != nil {
 			opt.Callback(t.Name, strategy, true, nil)
 		}
-		actual, sortKeys, err := fetchTableData(ctx, dbc, t.Name)
+		fOpt := fetchOpt{
+			Where:   expected.Where[t.Name],
+			OrderBy: expected.Order[t.Name],
+			Columns: opt.SelectColumns[t.Name],
+		}
+		if override, ok := expected.PKOverride[t.Name]; ok {
+			fOpt.PrimaryKeys = slices.Sorted(slices.Values(override))
+		} else if order, ok := expected.Order[t.Name]; ok {
+			// _order columns are used to pair rows when _pkey is not specified.
+			// They keep the declared order so that sorting by them matches ORDER BY
+			fOpt.PrimaryKeys = order
+		}
+		orderBy, keepOrder := opt.OrderBy[t.Name]
+		if keepOrder {
+			fOpt.OrderBy = orderBy
+			fOpt.KeepOrder = true
+		}
+		actual, sortKeys, err := fetchTableData(ctx, dbc, qualifyTable(expected.DefaultSchema, t.Name), fOpt)
 		if opt.Callback != nil {
 			opt.Callback(t.Name, strategy, false, err)
 		}
@@ -81,12 +237,36 @@ func Assert(ctx context.Context, dbc DBConnector, expected *DataSet, opt AssertO
 			errs = append(errs, err)
 			continue
 		}
-		expectedNormalizedTable, err := t.SortAndFilter(sortKeys, opt.IncludeTags, opt.ExcludeTags)
+		// the keys are not re-sorted to pair rows in the same order as fetchTableData
+		expectedNormalizedTable, err := t.normalize(sortKeys, opt.IncludeTags, opt.ExcludeTags)
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
-		r := compareTable(t.Name, strategy, sortKeys, expectedNormalizedTable.Rows, actual)
+		if !keepOrder {
+			sortRow(expectedNormalizedTable.Rows, sortKeys)
+		}
+		expectedRows := expectedNormalizedTable.Rows
+		if opt.RowFilter != nil {
+			actual = filterRows(t.Name, actual, opt.RowFilter)
+			expectedRows = filterRows(t.Name, expectedRows, opt.RowFilter)
+		}
+		if ignore := opt.IgnoreColumns[t.Name]; len(ignore) > 0 {
+			actual = dropColumns(actual, len(sortKeys), ignore)
+			expectedRows = dropColumns(expectedRows, len(sortKeys), ignore)
+		}
+		if opt.TimeZone != nil {
+			actual = normalizeTimes(actual, len(sortKeys), opt.TimeZone)
+			expectedRows = normalizeTimes(expectedRows, len(sortKeys), opt.TimeZone)
+		}
+		var r AssertTableResult
+		if keepOrder {
+			// without primary keys, rows are paired by the position and the keys are compared as normal fields
+			r = compareTable(t.Name, strategy, nil, expectedRows, actual, opt.MaxDiffRows)
+			r.PrimaryKeys = sortKeys
+		} else {
+			r = compareTable(t.Name, strategy, sortKeys, expectedRows, actual, opt.MaxDiffRows)
+		}
 		result = append(result, r)
 		if r.Status == NotMatch {
 			ok = false
@@ -94,23 +274,106 @@ func Assert(ctx context.Context, dbc DBConnector, expected *DataSet, opt AssertO
 		if opt.DiffCallback != nil {
 			opt.DiffCallback(r)
 		}
+		if opt.FailFast && r.Status == NotMatch {
+			if len(errs) > 0 {
+				break
+			}
+			if opt.AllDiffCallback != nil {
+				opt.AllDiffCallback(result)
+			}
+			return false, result, ErrAssertFailed{TableName: r.Name, RowCount: r.diffCount()}
+		}
 	}
 	if len(errs) > 0 {
 		return false, nil, errors.Join(errs...)
 	}
+	if opt.AllDiffCallback != nil {
+		opt.AllDiffCallback(result)
+	}
 	return ok, result, nil
 }
 
-func compareTable(tableName string, strategy MatchStrategy, pKeys []string, expected, actual [][]Value) AssertTableResult {
+func filterRows(tableName string, rows [][]Value, rowFilter func(tableName string, row []Value) bool) [][]Value {
+	result := make([][]Value, 0, len(rows))
+	for _, row := range rows {
+		if rowFilter(tableName, row) {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+// dropColumns removes the columns from the rows. First pkeyCount fields are primary keys, and they are kept.
+func dropColumns(rows [][]Value, pkeyCount int, columns []string) [][]Value {
+	result := make([][]Value, 0, len(rows))
+	for _, row := range rows {
+		newRow := slices.Clone(row[:pkeyCount])
+		for _, v := range row[pkeyCount:] {
+			if !slices.Contains(columns, v.Key) {
+				newRow = append(newRow, v)
+			}
+		}
+		result = append(result, newRow)
+	}
+	return result
+}
+
+// timeLayouts are the layouts of time strings in the dataset that are compared as time with AssertOpt.TimeZone.
+// Date-only strings are compared as they are.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// normalizeTimes converts time.Time values and time strings to the location. Strings without offset are parsed in the location.
+// First pkeyCount fields are primary keys, and they are kept not to change the order of rows.
+func normalizeTimes(rows [][]Value, pkeyCount int, loc *time.Location) [][]Value {
+	result := make([][]Value, 0, len(rows))
+	for _, row := range rows {
+		newRow := slices.Clone(row)
+		for i, v := range row[pkeyCount:] {
+			switch vv := v.Value.(type) {
+			case time.Time:
+				v.Value = vv.In(loc)
+			case string:
+				for _, layout := range timeLayouts {
+					if t, err := time.ParseInLocation(layout, vv, loc); err == nil {
+						v.Value = t.In(loc)
+						break
+					}
+				}
+			}
+			newRow[pkeyCount+i] = v
+		}
+		result = append(result, newRow)
+	}
+	return result
+}
+
+func compareTable(tableName string, strategy MatchStrategy, pKeys []string, expected, actual [][]Value, maxDiffRows int) AssertTableResult {
 	result := AssertTableResult{
 		Name:        tableName,
 		PrimaryKeys: pKeys,
 	}
+	var diffRows, truncatedRows int
+	appendRow := func(row RowDiff) {
+		if maxDiffRows > 0 && diffRows >= maxDiffRows {
+			if row.Status != Match {
+				truncatedRows++
+			}
+			return
+		}
+		if row.Status != Match {
+			diffRows++
+		}
+		result.Rows = append(result.Rows, row)
+	}
 	var i, j int
-	var c int
 	ok := true
-	for i < len(expected) && j < len(actual) && c < 10 {
-		c++
+	for i < len(expected) && j < len(actual) {
 		e := expected[i]
 		a := actual[j]
 		cr := comparePkey(len(pKeys), e, a)
@@ -122,14 +385,14 @@ func compareTable(tableName string, strategy MatchStrategy, pKeys []string, expe
 			if row.Status != Match {
 				ok = false
 			}
-			result.Rows = append(result.Rows, row)
+			appendRow(row)
 		case -1: // only on expected
 			i++
 			row := make([]Diff, len(e))
 			for i, f := range e {
 				row[i] = Diff{Key: f.Key, Expect: f.Value}
 			}
-			result.Rows = append(result.Rows, RowDiff{
+			appendRow(RowDiff{
 				Fields: row,
 				Status: OnlyOnExpect,
 			})
@@ -141,7 +404,7 @@ func compareTable(tableName string, strategy MatchStrategy, pKeys []string, expe
 				for i, f := range a {
 					row[i] = Diff{Key: f.Key, Actual: f.Value}
 				}
-				result.Rows = append(result.Rows, RowDiff{
+				appendRow(RowDiff{
 					Fields: row,
 					Status: OnlyOnActual,
 				})
@@ -156,7 +419,7 @@ func compareTable(tableName string, strategy MatchStrategy, pKeys []string, expe
 		for i, f := range e {
 			row[i] = Diff{Key: f.Key, Expect: f.Value}
 		}
-		result.Rows = append(result.Rows, RowDiff{
+		appendRow(RowDiff{
 			Fields: row,
 			Status: OnlyOnExpect,
 		})
@@ -170,13 +433,20 @@ func compareTable(tableName string, strategy MatchStrategy, pKeys []string, expe
 			for i, f := range a {
 				row[i] = Diff{Key: f.Key, Actual: f.Value}
 			}
-			result.Rows = append(result.Rows, RowDiff{
+			appendRow(RowDiff{
 				Fields: row,
 				Status: OnlyOnActual,
 			})
 			ok = false
 		}
 	}
+	if truncatedRows > 0 {
+		// sentinel row: Fields[0].Key holds the number of omitted rows
+		result.Rows = append(result.Rows, RowDiff{
+			Fields: []Diff{{Key: strconv.Itoa(truncatedRows)}},
+			Status: Truncated,
+		})
+	}
 	if ok {
 		result.Status = Match
 	} else {
@@ -187,37 +457,38 @@ func compareTable(tableName string, strategy MatchStrategy, pKeys []string, expe
 
 func comparePkey(pkeyCount int, key1, key2 []Value) int {
 	for i := range pkeyCount {
-		v1 := key1[i].Value
-		v2 := key2[i].Value
-		c := -1
-		switch v1t := v1.(type) { // todo other types
+		if c := compareKeyValue(key1[i].Value, key2[i].Value); c != 0 { // check next primary key
+			return c
+		}
+	}
+	return 0
+}
+
+// compareKeyValue compares values of the primary keys. int and float64 are compared as float64,
+// because YAML may have `1.0` for the integer column.
+func compareKeyValue(v1, v2 any) int {
+	switch v1t := v1.(type) { // todo other types
+	case int:
+		switch v2t := v2.(type) {
 		case int:
-			if v2t, ok := v2.(int); !ok {
-				break
-			} else {
-				c = cmp.Compare(v1t, v2t)
-			}
+			return cmp.Compare(v1t, v2t)
 		case float64:
-			if v2t, ok := v2.(float64); !ok {
-				break
-			} else {
-				c = cmp.Compare(v1t, v2t)
-			}
-		case string:
-			if v2t, ok := v2.(string); !ok {
-				break
-			} else {
-				c = cmp.Compare(v1t, v2t)
-			}
+			return cmp.Compare(float64(v1t), v2t)
 		}
-		if c == -1 { // can't convert to primitive
-			c = cmp.Compare(fmt.Sprint(v1), fmt.Sprint(v2))
+	case float64:
+		switch v2t := v2.(type) {
+		case int:
+			return cmp.Compare(v1t, float64(v2t))
+		case float64:
+			return cmp.Compare(v1t, v2t)
 		}
-		if c != 0 { // check next primary key
-			return c
+	case string:
+		if v2t, ok := v2.(string); ok {
+			return cmp.Compare(v1t, v2t)
 		}
 	}
-	return 0
+	// can't convert to primitive
+	return cmp.Compare(fmt.Sprint(v1), fmt.Sprint(v2))
 }
 
 // compareRow compares Row
@@ -259,12 +530,37 @@ func compareRow(offset int, expected, actual []Value) RowDiff {
 						result = append(result, Diff{Key: e.Key, Expect: e.Value, Actual: a.Value, Status: NotMatch})
 						allOk = false
 					}
+				case "empty", "notempty":
+					if isEmpty(a.Value) == (s[0] == "empty") {
+						result = append(result, Diff{Key: e.Key, Expect: e.Value, Actual: a.Value, Status: Match})
+					} else {
+						result = append(result, Diff{Key: e.Key, Expect: e.Value, Actual: a.Value, Status: NotMatch})
+						allOk = false
+					}
 				case "any":
 					result = append(result, Diff{Key: e.Key, Expect: e.Value, Actual: a.Value, Status: Match})
+				case "positive", "negative", "zero":
+					status := matchSign(s[0].(string), a.Value)
+					result = append(result, Diff{Key: e.Key, Expect: e.Value, Actual: a.Value, Status: status})
+					if status != Match {
+						allOk = false
+					}
+				case "base64":
+					status := matchBase64(s, a.Value)
+					result = append(result, Diff{Key: e.Key, Expect: e.Value, Actual: a.Value, Status: status})
+					if status != Match {
+						allOk = false
+					}
+				case "json":
+					status := matchJSONPath(s, a.Value)
+					result = append(result, Diff{Key: e.Key, Expect: e.Value, Actual: a.Value, Status: status})
+					if status != Match {
+						allOk = false
+					}
 				default:
 					panic("not implemented: [" + s[0].(string) + "]")
 				}
-			} else if e.Value == a.Value {
+			} else if valueEqual(e.Value, a.Value) {
 				result = append(result, Diff{Key: e.Key, Expect: e.Value, Actual: a.Value, Status: Match})
 			} else {
 				result = append(result, Diff{Key: e.Key, Expect: e.Value, Actual: a.Value, Status: NotMatch})
@@ -299,13 +595,283 @@ func compareRow(offset int, expected, actual []Value) RowDiff {
 	}
 }
 
-func fetchTableData(ctx context.Context, dbc DBConnector, tableName string) ([][]Value, []string, error) {
-	pkeys, err := dbc.PrimaryKeys(ctx, tableName)
+// isEmpty reports whether the value in the database matches the `[empty]` placeholder.
+//
+// Strings (and binaries) are empty if their length is 0, and integers are empty if they are 0. Other values are empty only if they are NULL.
+func isEmpty(v any) bool {
+	switch vt := v.(type) {
+	case string:
+		return len(vt) == 0
+	case []byte:
+		return len(vt) == 0
+	case int:
+		return vt == 0
+	}
+	return v == nil
+}
+
+// matchSign checks the sign of the value for `[positive]`, `[negative]` and `[zero]` placeholders.
+//
+// Strings are parsed as float, because some drivers return DECIMAL columns as strings.
+// NULL doesn't match any of them, and it returns WrongDataSet for non-numeric values.
+func matchSign(placeholder string, actual any) AssertStatus {
+	var v float64
+	switch at := actual.(type) {
+	case nil:
+		return NotMatch
+	case int:
+		v = float64(at)
+	case float32:
+		v = float64(at)
+	case float64:
+		v = at
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(at), 64)
+		if err != nil {
+			return WrongDataSet
+		}
+		v = f
+	default:
+		return WrongDataSet
+	}
+	var ok bool
+	switch placeholder {
+	case "positive":
+		ok = v > 0
+	case "negative":
+		ok = v < 0
+	case "zero":
+		ok = v == 0
+	}
+	if !ok {
+		return NotMatch
+	}
+	return Match
+}
+
+// valueEqual compares the value of the data set with the value in the database.
+func valueEqual(expected, actual any) bool {
+	// []byte is not comparable by ==
+	if eb, ok := expected.([]byte); ok {
+		ab, ok := actual.([]byte)
+		return ok && bytes.Equal(eb, ab)
+	}
+	if et, ok := expected.(time.Time); ok {
+		at, ok := actual.(time.Time)
+		return ok && et.Equal(at)
+	}
+	return jsonAwareEqual(expected, actual) || expected == actual
+}
+
+// matchBase64 decodes the value of the `[base64, dGVzdA==]` placeholder and compares it with the actual binary.
+//
+// It returns WrongDataSet if the placeholder is malformed.
+func matchBase64(placeholder []any, actual any) AssertStatus {
+	if len(placeholder) != 2 {
+		return WrongDataSet
+	}
+	src, ok := placeholder[1].(string)
+	if !ok {
+		return WrongDataSet
+	}
+	expected, err := base64.StdEncoding.DecodeString(src)
+	if err != nil {
+		return WrongDataSet
+	}
+	var ab []byte
+	switch a := actual.(type) {
+	case []byte:
+		ab = a
+	case string:
+		ab = []byte(a)
+	default:
+		return NotMatch
+	}
+	if !bytes.Equal(expected, ab) {
+		return NotMatch
+	}
+	return Match
+}
+
+// jsonAwareEqual reports whether both values are strings of the same JSON object or array.
+//
+// json and jsonb columns are fetched as strings, and their key order differs from the data set,
+// so they are compared after normalizing by encoding/json round-trip. Scalar JSON like "1" is compared as a plain string.
+func jsonAwareEqual(expected, actual any) bool {
+	es, ok := expected.(string)
+	if !ok {
+		return false
+	}
+	as, ok := actual.(string)
+	if !ok {
+		return false
+	}
+	en, ok := normalizeJSON(es)
+	if !ok {
+		return false
+	}
+	an, ok := normalizeJSON(as)
+	if !ok {
+		return false
+	}
+	return en == an
+}
+
+// matchJSONPath compares the value at the JSONPath in the actual JSON with the expected value
+// of the `[json, $.path, value]` placeholder.
+//
+// It returns WrongDataSet if the placeholder is malformed or the actual value is not JSON.
+func matchJSONPath(placeholder []any, actual any) AssertStatus {
+	if len(placeholder) != 3 {
+		return WrongDataSet
+	}
+	path, ok := placeholder[1].(string)
+	if !ok {
+		return WrongDataSet
+	}
+	if actual == nil {
+		return NotMatch
+	}
+	var src []byte
+	switch a := actual.(type) {
+	case string:
+		src = []byte(a)
+	case []byte:
+		src = a
+	default:
+		return WrongDataSet
+	}
+	var doc any
+	if err := json.Unmarshal(src, &doc); err != nil {
+		return WrongDataSet
+	}
+	value, found, err := evalJSONPath(doc, path)
+	if err != nil {
+		return WrongDataSet
+	}
+	if !found {
+		return NotMatch
+	}
+	// both are encoded to compare numbers of YAML and JSON in the same form
+	ev, err := json.Marshal(placeholder[2])
+	if err != nil {
+		return WrongDataSet
+	}
+	av, _ := json.Marshal(value)
+	if string(ev) != string(av) {
+		return NotMatch
+	}
+	return Match
+}
+
+// normalizeJSON re-encodes a JSON object or array with sorted keys.
+func normalizeJSON(src string) (string, bool) {
+	src = strings.TrimSpace(src)
+	if !strings.HasPrefix(src, "{") && !strings.HasPrefix(src, "[") || !json.Valid([]byte(src)) {
+		return "", false
+	}
+	d := json.NewDecoder(strings.NewReader(src))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return "", false
+	}
+	b, err := json.Marshal(v)
 	if err != nil {
-		return nil, nil, err
+		return "", false
+	}
+	return string(b), true
+}
+
+// binaryTypes are the database type names of binary columns in each database.
+var binaryTypes = []string{"BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BYTEA", "BINARY", "VARBINARY", "IMAGE"}
+
+// isBinaryType reports whether the column type keeps the value as []byte.
+func isBinaryType(dbType string) bool {
+	return slices.Contains(binaryTypes, strings.ToUpper(dbType))
+}
+
+// NormalizeValue converts the value scanned from the database into the type of the value in the dataset.
+//
+// Integer types become int. []byte becomes string unless dbType (sql.ColumnType.DatabaseTypeName) is a binary type.
+func NormalizeValue(val any, dbType string) any {
+	switch val2 := val.(type) {
+	case []byte:
+		// some drivers return text columns as []byte too
+		if isBinaryType(dbType) {
+			return val2
+		}
+		return string(val2)
+	case int64:
+		return int(val2)
+	case int32: // DuckDB returns INTEGER as int32
+		return int(val2)
+	case int16:
+		return int(val2)
+	case int8:
+		return int(val2)
+	default:
+		return val2
+	}
+}
+
+// fetchOpt controls how fetchTableData reads the table.
+type fetchOpt struct {
+	PrimaryKeys []string // If empty, the primary keys registered in the database are used. If the table doesn't have them, the first unique constraint is used.
+	Where       string   // SQL expression appended as WHERE clause verbatim.
+	OrderBy     []string // Columns used for ORDER BY clause.
+	KeepOrder   bool     // Keep the order of ORDER BY instead of sorting rows by the primary keys.
+	Columns     []string // Columns to fetch. If empty, ColumnSelector of DBConnector is used, or all columns are fetched.
+}
+
+// fetchTableData fetches rows of the table sorted by the primary keys.
+func fetchTableData(ctx context.Context, dbc DBConnector, tableName string, opt fetchOpt) ([][]Value, []string, error) {
+	pkeys := opt.PrimaryKeys
+	if len(pkeys) == 0 {
+		var err error
+		pkeys, err = dbc.PrimaryKeys(ctx, tableName)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(pkeys) == 0 {
+		// the first unique constraint is used to pair rows instead
+		uniqueKeys, err := dbc.UniqueKeys(ctx, tableName)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(uniqueKeys) > 0 {
+			pkeys = uniqueKeys[0]
+		}
+	}
+
+	selectColumns := opt.Columns
+	if cs, ok := dbc.(ColumnSelector); ok && len(selectColumns) == 0 {
+		var err error
+		selectColumns, err = cs.SelectColumns(ctx, tableName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get columns to select of table %s: %w", tableName, err)
+		}
+	}
+	columnStr := "*"
+	if len(selectColumns) > 0 {
+		// primary keys are needed to pair rows
+		for _, k := range pkeys {
+			if !slices.Contains(selectColumns, k) {
+				selectColumns = append(slices.Clip(selectColumns), k)
+			}
+		}
+		columnStr = strings.Join(selectColumns, ", ")
 	}
 
-	rows, err := dbc.DB().QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s", tableName))
+	query := fmt.Sprintf("SELECT %s FROM %s", columnStr, tableName)
+	if opt.Where != "" {
+		query = fmt.Sprintf("SELECT %s FROM %s WHERE %s", columnStr, tableName, opt.Where)
+	}
+	if len(opt.OrderBy) > 0 {
+		query += " ORDER BY " + strings.Join(opt.OrderBy, ", ")
+	}
+	rows, err := dbc.DB().QueryContext(ctx, query)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to query table %s: %w", tableName, err)
 	}
@@ -315,6 +881,10 @@ func fetchTableData(ctx context.Context, dbc DBConnector, tableName string) ([][
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get columns: %w", err)
 	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get column types: %w", err)
+	}
 
 	var result [][]Value
 	for rows.Next() {
@@ -330,21 +900,12 @@ func fetchTableData(ctx context.Context, dbc DBConnector, tableName string) ([][
 		}
 
 		for i, colName := range columns {
-			val := values[i]
-			if val != nil {
-				switch val2 := val.(type) {
-				case []byte:
-					row[colName] = string(val2)
-				case int64:
-					row[colName] = int(val2)
-				default:
-					row[colName] = val2
-				}
-			} else {
-				row[colName] = nil
-			}
+			row[colName] = NormalizeValue(values[i], columnTypes[i].DatabaseTypeName())
+		}
+		sliceRow, err := mapToValues(row, pkeys)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid primary keys for table %s: %w", tableName, err)
 		}
-		sliceRow, _ := mapToValues(row, pkeys)
 		result = append(result, sliceRow)
 	}
 
@@ -352,7 +913,9 @@ func fetchTableData(ctx context.Context, dbc DBConnector, tableName string) ([][
 		return nil, nil, fmt.Errorf("row iteration error: %w", err)
 	}
 
-	sortRow(result, pkeys)
+	if !opt.KeepOrder {
+		sortRow(result, pkeys)
+	}
 
 	return result, pkeys, nil
 }
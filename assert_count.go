@@ -0,0 +1,72 @@
+package dbtestify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CountResult represents the result of AssertCount on a single table.
+type CountResult struct {
+	TableName string
+	Expected  int
+	Actual    int
+}
+
+// AssertCount compares only the number of rows of each table with the dataset.
+//
+// Rows are filtered by tags and `_where` directive like Assert. RowFilter and match strategies are not used.
+func AssertCount(ctx context.Context, dbc DBConnector, expected *DataSet, opt AssertOpt) (bool, []CountResult, error) {
+	var errs []error
+	var result []CountResult
+	ok := true
+	for _, t := range expected.Tables {
+		if !MatchTargetTables(opt.TargetTables, t.Name) {
+			continue
+		}
+		strategy := ExactMatchStrategy
+		if s, ok := expected.Match[t.Name]; ok {
+			strategy = s
+		}
+		if opt.Callback != nil {
+			opt.Callback(t.Name, strategy, true, nil)
+		}
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s", t.Name)
+		if where := expected.Where[t.Name]; where != "" {
+			query += " WHERE " + where
+		}
+		var actual int
+		err := dbc.DB().QueryRowContext(ctx, query).Scan(&actual)
+		if err != nil {
+			err = fmt.Errorf("failed to count rows of table %s: %w", t.Name, err)
+		}
+		if opt.Callback != nil {
+			opt.Callback(t.Name, strategy, false, err)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		normalized, err := t.SortAndFilter(nil, opt.IncludeTags, opt.ExcludeTags)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r := CountResult{
+			TableName: t.Name,
+			Expected:  len(normalized.Rows),
+			Actual:    actual,
+		}
+		result = append(result, r)
+		if r.Expected != r.Actual {
+			ok = false
+			if opt.FailFast {
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return false, nil, errors.Join(errs...)
+	}
+	return ok, result, nil
+}
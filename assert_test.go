@@ -1,18 +1,25 @@
 package dbtestify
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
 	"github.com/goccy/go-yaml"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
 )
 
 type Opt struct {
@@ -42,6 +49,7 @@ func TestAssertSQLite(t *testing.T) {
 
 			dbc, err := NewDBConnector(ctx2, "sqlite3://"+connStr)
 			assert.NoError(t, err)
+			defer dbc.DB().Close()
 
 			_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
 				CREATE TABLE IF NOT EXISTS member (
@@ -134,7 +142,7 @@ func Test_fetchTableData(t *testing.T) {
 	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
 	assert.NoError(t, err)
 
-	actual, _, err := fetchTableData(ctx, dbc, "user")
+	actual, _, err := fetchTableData(ctx, dbc, "user", fetchOpt{})
 	assert.NoError(t, err)
 
 	expected := [][]Value{
@@ -144,232 +152,515 @@ func Test_fetchTableData(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
-func Test_compareRow(t *testing.T) {
-	type args struct {
-		offset   int
-		expected []Value
-		actual   []Value
-	}
-	tests := []struct {
-		name          string
-		args          args
-		wantRowStatus AssertStatus
-		wantDetail    RowDiff
-	}{
-		{
-			name: "completely match",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
+type compareRowArgs struct {
+	offset   int
+	expected []Value
+	actual   []Value
+}
+
+// compareRowTests are also used by TestRowDiffHelpers
+var compareRowTests = []struct {
+	name          string
+	args          compareRowArgs
+	wantRowStatus AssertStatus
+	wantDetail    RowDiff
+}{
+	{
+		name: "completely match",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: 2, Actual: 2, Status: Match},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: 2, Actual: 2, Status: Match},
-				},
-				Status: Match,
+			Status: Match,
+		},
+	},
+	{
+		name: "not match",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 3}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: 2, Actual: 3, Status: NotMatch},
 			},
+			Status: NotMatch,
 		},
-		{
-			name: "not match",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 3}},
+	},
+	{
+		name: "only in actual (1): inside list: this is ignored",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key3", Value: 3}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}, {Key: "key3", Value: 3}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key3", Expect: 3, Actual: 3, Status: Match},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: 2, Actual: 3, Status: NotMatch},
-				},
-				Status: NotMatch,
+			Status: Match,
+		},
+	},
+	{
+		name: "only in actual (2): end of line: this is ignored",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}, {Key: "key3", Value: 3}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: 2, Actual: 2, Status: Match},
 			},
+			Status: Match,
 		},
-		{
-			name: "only in actual (1): inside list: this is ignored",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key3", Value: 3}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}, {Key: "key3", Value: 3}},
+	},
+	{
+		name: "only in expected (1): inside list: wrong-data-set",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}, {Key: "key3", Value: 3}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key3", Value: 3}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: 2, Status: WrongDataSet},
+				{Key: "key3", Expect: 3, Actual: 3, Status: Match},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key3", Expect: 3, Actual: 3, Status: Match},
-				},
-				Status: Match,
+			Status: NotMatch,
+		},
+	},
+	{
+		name: "only in expected (2): end of line: wrong-data-set",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}, {Key: "key3", Value: 3}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: 2, Actual: 2, Status: Match},
+				{Key: "key3", Expect: 3, Status: WrongDataSet},
 			},
+			Status: NotMatch,
 		},
-		{
-			name: "only in actual (2): end of line: this is ignored",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}, {Key: "key3", Value: 3}},
+	},
+	{
+		name: "completely match: nil & nil",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: nil}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: nil}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: nil, Actual: nil, Status: Match},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: 2, Actual: 2, Status: Match},
-				},
-				Status: Match,
+			Status: Match,
+		},
+	},
+	{
+		name: "completely match: [null] placeholder (1): ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{"null"}}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: nil}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: []any{"null"}, Actual: nil, Status: Match},
 			},
+			Status: Match,
 		},
-		{
-			name: "only in expected (1): inside list: wrong-data-set",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}, {Key: "key3", Value: 3}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key3", Value: 3}},
+	},
+	{
+		name: "completely match: [null] placeholder (2): ng",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{"null"}}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: []any{"null"}, Actual: 2, Status: NotMatch},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: 2, Status: WrongDataSet},
-					{Key: "key3", Expect: 3, Actual: 3, Status: Match},
-				},
-				Status: NotMatch,
+			Status: NotMatch,
+		},
+	},
+	{
+		name: "completely match: [null] placeholder (3): ok(primitive)",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{nil}}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: nil}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: []any{nil}, Actual: nil, Status: Match},
 			},
+			Status: Match,
 		},
-		{
-			name: "only in expected (2): end of line: wrong-data-set",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}, {Key: "key3", Value: 3}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
+	},
+	{
+		name: "completely match: [null] placeholder (4): ng(primitive)",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{nil}}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: []any{nil}, Actual: 2, Status: NotMatch},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: 2, Actual: 2, Status: Match},
-					{Key: "key3", Expect: 3, Status: WrongDataSet},
-				},
-				Status: NotMatch,
+			Status: NotMatch,
+		},
+	},
+	{
+		name: "completely match: [notnull] placeholder (1): ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{"notnull"}}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 3}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: []any{"notnull"}, Actual: 3, Status: Match},
 			},
+			Status: Match,
 		},
-		{
-			name: "completely match: nil & nil",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: nil}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: nil}},
+	},
+	{
+		name: "completely match: [notnull] placeholder (2): ng",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{"notnull"}}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: nil}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: []any{"notnull"}, Actual: nil, Status: NotMatch},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: nil, Actual: nil, Status: Match},
-				},
-				Status: Match,
+			Status: NotMatch,
+		},
+	},
+	{
+		name: "[empty] placeholder (1): empty string: ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"empty"}}},
+			actual:   []Value{{Key: "key1", Value: ""}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"empty"}, Actual: "", Status: Match},
 			},
+			Status: Match,
 		},
-		{
-			name: "completely match: [null] placeholder (1): ok",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{"null"}}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: nil}},
+	},
+	{
+		name: "[empty] placeholder (2): nil: ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"empty"}}},
+			actual:   []Value{{Key: "key1", Value: nil}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"empty"}, Actual: nil, Status: Match},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: []any{"null"}, Actual: nil, Status: Match},
-				},
-				Status: Match,
+			Status: Match,
+		},
+	},
+	{
+		name: "[empty] placeholder (3): zero: ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"empty"}}},
+			actual:   []Value{{Key: "key1", Value: 0}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"empty"}, Actual: 0, Status: Match},
 			},
+			Status: Match,
 		},
-		{
-			name: "completely match: [null] placeholder (2): ng",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{"null"}}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
+	},
+	{
+		name: "[empty] placeholder (4): string: ng",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"empty"}}},
+			actual:   []Value{{Key: "key1", Value: "Frank"}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"empty"}, Actual: "Frank", Status: NotMatch},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: []any{"null"}, Actual: 2, Status: NotMatch},
-				},
-				Status: NotMatch,
+			Status: NotMatch,
+		},
+	},
+	{
+		name: "[empty] placeholder (5): int: ng",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"empty"}}},
+			actual:   []Value{{Key: "key1", Value: 3}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"empty"}, Actual: 3, Status: NotMatch},
 			},
+			Status: NotMatch,
 		},
-		{
-			name: "completely match: [null] placeholder (3): ok(primitive)",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{nil}}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: nil}},
+	},
+	{
+		name: "[empty] placeholder (6): other type: ng",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"empty"}}},
+			actual:   []Value{{Key: "key1", Value: true}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"empty"}, Actual: true, Status: NotMatch},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: []any{nil}, Actual: nil, Status: Match},
-				},
-				Status: Match,
+			Status: NotMatch,
+		},
+	},
+	{
+		name: "[notempty] placeholder (1): string: ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"notempty"}}},
+			actual:   []Value{{Key: "key1", Value: "Frank"}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"notempty"}, Actual: "Frank", Status: Match},
 			},
+			Status: Match,
 		},
-		{
-			name: "completely match: [null] placeholder (4): ng(primitive)",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{nil}}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 2}},
+	},
+	{
+		name: "[notempty] placeholder (2): int: ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"notempty"}}},
+			actual:   []Value{{Key: "key1", Value: 3}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"notempty"}, Actual: 3, Status: Match},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: []any{nil}, Actual: 2, Status: NotMatch},
-				},
-				Status: NotMatch,
+			Status: Match,
+		},
+	},
+	{
+		name: "[notempty] placeholder (3): other type: ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"notempty"}}},
+			actual:   []Value{{Key: "key1", Value: true}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"notempty"}, Actual: true, Status: Match},
 			},
+			Status: Match,
 		},
-		{
-			name: "completely match: [notnull] placeholder (1): ok",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{"notnull"}}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 3}},
+	},
+	{
+		name: "[notempty] placeholder (4): empty string: ng",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"notempty"}}},
+			actual:   []Value{{Key: "key1", Value: ""}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"notempty"}, Actual: "", Status: NotMatch},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: []any{"notnull"}, Actual: 3, Status: Match},
-				},
-				Status: Match,
+			Status: NotMatch,
+		},
+	},
+	{
+		name: "[notempty] placeholder (5): nil: ng",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"notempty"}}},
+			actual:   []Value{{Key: "key1", Value: nil}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"notempty"}, Actual: nil, Status: NotMatch},
 			},
+			Status: NotMatch,
 		},
-		{
-			name: "completely match: [notnull] placeholder (2): ng",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{"notnull"}}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: nil}},
+	},
+	{
+		name: "[notempty] placeholder (6): zero: ng",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"notempty"}}},
+			actual:   []Value{{Key: "key1", Value: 0}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"notempty"}, Actual: 0, Status: NotMatch},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: []any{"notnull"}, Actual: nil, Status: NotMatch},
-				},
-				Status: NotMatch,
+			Status: NotMatch,
+		},
+	},
+	{
+		name: "[positive] placeholder (1): ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"positive"}}},
+			actual:   []Value{{Key: "key1", Value: 3}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"positive"}, Actual: 3, Status: Match},
 			},
+			Status: Match,
 		},
-		{
-			name: "completely match: [any] placeholder: ok",
-			args: args{
-				offset:   0,
-				expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{"any"}}},
-				actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 3}},
+	},
+	{
+		name: "[negative] placeholder (1): ng",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"negative"}}},
+			actual:   []Value{{Key: "key1", Value: 3}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"negative"}, Actual: 3, Status: NotMatch},
 			},
-			wantDetail: RowDiff{
-				Fields: []Diff{
-					{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
-					{Key: "key2", Expect: []any{"any"}, Actual: 3, Status: Match},
-				},
-				Status: Match,
+			Status: NotMatch,
+		},
+	},
+	{
+		name: "[zero] placeholder (1): not numeric: wrong-data-set",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"zero"}}},
+			actual:   []Value{{Key: "key1", Value: "Frank"}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"zero"}, Actual: "Frank", Status: WrongDataSet},
 			},
+			Status: NotMatch,
 		},
-	}
-	for _, tt := range tests {
+	},
+	{
+		name: "completely match: [any] placeholder: ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: []any{"any"}}},
+			actual:   []Value{{Key: "key1", Value: "value1"}, {Key: "key2", Value: 3}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: "value1", Actual: "value1", Status: Match},
+				{Key: "key2", Expect: []any{"any"}, Actual: 3, Status: Match},
+			},
+			Status: Match,
+		},
+	},
+	{
+		name: "[json] placeholder (1): ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"json", "$.user.ids[1]", 42}}},
+			actual:   []Value{{Key: "key1", Value: `{"user":{"ids":[41,42]}}`}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"json", "$.user.ids[1]", 42}, Actual: `{"user":{"ids":[41,42]}}`, Status: Match},
+			},
+			Status: Match,
+		},
+	},
+	{
+		name: "[json] placeholder (2): ng",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"json", "$.user.id", 42}}},
+			actual:   []Value{{Key: "key1", Value: `{"user":{"id":"42"}}`}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"json", "$.user.id", 42}, Actual: `{"user":{"id":"42"}}`, Status: NotMatch},
+			},
+			Status: NotMatch,
+		},
+	},
+	{
+		name: "[json] placeholder (3): not json: wrong-data-set",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: []any{"json", "$.user.id", 42}}},
+			actual:   []Value{{Key: "key1", Value: "frank@example.com"}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: []any{"json", "$.user.id", 42}, Actual: "frank@example.com", Status: WrongDataSet},
+			},
+			Status: NotMatch,
+		},
+	},
+	{
+		name: "json: different key order: ok",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: `{"a": 1, "b": [2, {"c": 3, "d": 4}]}`}},
+			actual:   []Value{{Key: "key1", Value: `{"b":[2,{"d":4,"c":3}],"a":1}`}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: `{"a": 1, "b": [2, {"c": 3, "d": 4}]}`, Actual: `{"b":[2,{"d":4,"c":3}],"a":1}`, Status: Match},
+			},
+			Status: Match,
+		},
+	},
+	{
+		name: "json: different value: ng",
+		args: compareRowArgs{
+			offset:   0,
+			expected: []Value{{Key: "key1", Value: `{"a":1,"b":2}`}},
+			actual:   []Value{{Key: "key1", Value: `{"b":3,"a":1}`}},
+		},
+		wantDetail: RowDiff{
+			Fields: []Diff{
+				{Key: "key1", Expect: `{"a":1,"b":2}`, Actual: `{"b":3,"a":1}`, Status: NotMatch},
+			},
+			Status: NotMatch,
+		},
+	},
+}
+
+func Test_compareRow(t *testing.T) {
+	for _, tt := range compareRowTests {
 		t.Run(tt.name, func(t *testing.T) {
 			gotDetail := compareRow(tt.args.offset, tt.args.expected, tt.args.actual)
 			if !reflect.DeepEqual(gotDetail, tt.wantDetail) {
@@ -379,6 +670,131 @@ func Test_compareRow(t *testing.T) {
 	}
 }
 
+func Test_matchSign(t *testing.T) {
+	tests := []struct {
+		name        string
+		placeholder string
+		actual      any
+		want        AssertStatus
+	}{
+		{name: "positive int", placeholder: "positive", actual: 10, want: Match},
+		{name: "positive float", placeholder: "positive", actual: 0.5, want: Match},
+		{name: "positive decimal string", placeholder: "positive", actual: "12.50", want: Match},
+		{name: "positive: zero", placeholder: "positive", actual: 0, want: NotMatch},
+		{name: "positive: negative", placeholder: "positive", actual: -1, want: NotMatch},
+		{name: "positive: nil", placeholder: "positive", actual: nil, want: NotMatch},
+		{name: "negative int", placeholder: "negative", actual: -10, want: Match},
+		{name: "negative float", placeholder: "negative", actual: -0.5, want: Match},
+		{name: "negative decimal string", placeholder: "negative", actual: "-3", want: Match},
+		{name: "negative: zero", placeholder: "negative", actual: 0.0, want: NotMatch},
+		{name: "negative: positive", placeholder: "negative", actual: 1, want: NotMatch},
+		{name: "negative: nil", placeholder: "negative", actual: nil, want: NotMatch},
+		{name: "zero int", placeholder: "zero", actual: 0, want: Match},
+		{name: "zero float", placeholder: "zero", actual: 0.0, want: Match},
+		{name: "zero decimal string", placeholder: "zero", actual: "0.00", want: Match},
+		{name: "zero: positive", placeholder: "zero", actual: 0.1, want: NotMatch},
+		{name: "zero: negative", placeholder: "zero", actual: -1, want: NotMatch},
+		{name: "zero: nil", placeholder: "zero", actual: nil, want: NotMatch},
+		{name: "not numeric string", placeholder: "positive", actual: "Frank", want: WrongDataSet},
+		{name: "not numeric type", placeholder: "zero", actual: true, want: WrongDataSet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchSign(tt.placeholder, tt.actual))
+		})
+	}
+}
+
+func Test_jsonAwareEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected any
+		actual   any
+		want     bool
+	}{
+		{name: "object with different key order", expected: `{"a":1,"b":2}`, actual: `{"b":2,"a":1}`, want: true},
+		{name: "nested object and whitespace", expected: `{"a": {"x": [1, 2], "y": null}}`, actual: "{\"a\":{\"y\":null,\"x\":[1,2]}}\n", want: true},
+		{name: "array order matters", expected: `[1,2]`, actual: `[2,1]`, want: false},
+		{name: "different value", expected: `{"a":1}`, actual: `{"a":"1"}`, want: false},
+		{name: "large number", expected: `{"a":12345678901234567890}`, actual: `{"a":12345678901234567891}`, want: false},
+		{name: "scalar json is a plain string", expected: `1`, actual: `1.0`, want: false},
+		{name: "invalid json", expected: `{"a":1}}`, actual: `{"a":1}`, want: false},
+		{name: "not string", expected: `{"a":1}`, actual: []byte(`{"a":1}`), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, jsonAwareEqual(tt.expected, tt.actual))
+		})
+	}
+}
+
+func TestRowDiffHelpers(t *testing.T) {
+	keys := func(diffs []Diff) []string {
+		var result []string
+		for _, d := range diffs {
+			result = append(result, d.Key)
+		}
+		return result
+	}
+	// expected keys of MismatchedFields and MissingFields for each compareRowTests case
+	want := map[string]struct {
+		mismatched []string
+		missing    []string
+	}{
+		"not match": {mismatched: []string{"key2"}},
+		"only in expected (1): inside list: wrong-data-set":       {missing: []string{"key2"}},
+		"only in expected (2): end of line: wrong-data-set":       {missing: []string{"key3"}},
+		"completely match: [null] placeholder (2): ng":            {mismatched: []string{"key2"}},
+		"completely match: [null] placeholder (4): ng(primitive)": {mismatched: []string{"key2"}},
+		"completely match: [notnull] placeholder (2): ng":         {mismatched: []string{"key2"}},
+		"json: different value: ng":                               {mismatched: []string{"key1"}},
+		"[negative] placeholder (1): ng":                          {mismatched: []string{"key1"}},
+		"[zero] placeholder (1): not numeric: wrong-data-set":     {missing: []string{"key1"}},
+		"[empty] placeholder (4): string: ng":                     {mismatched: []string{"key1"}},
+		"[empty] placeholder (5): int: ng":                        {mismatched: []string{"key1"}},
+		"[empty] placeholder (6): other type: ng":                 {mismatched: []string{"key1"}},
+		"[notempty] placeholder (4): empty string: ng":            {mismatched: []string{"key1"}},
+		"[notempty] placeholder (5): nil: ng":                     {mismatched: []string{"key1"}},
+		"[notempty] placeholder (6): zero: ng":                    {mismatched: []string{"key1"}},
+		"[json] placeholder (2): ng":                              {mismatched: []string{"key1"}},
+		"[json] placeholder (3): not json: wrong-data-set":        {missing: []string{"key1"}},
+	}
+	for _, tt := range compareRowTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareRow(tt.args.offset, tt.args.expected, tt.args.actual)
+			assert.Equal(t, tt.wantDetail.Status == Match, got.IsMatch())
+			w := want[tt.name]
+			assert.Equal(t, w.mismatched, keys(got.MismatchedFields()))
+			assert.Equal(t, w.missing, keys(got.MissingFields()))
+			assert.Equal(t, 0, len(got.ExtraFields()))
+			if got.IsMatch() {
+				assert.Zero(t, w)
+			}
+		})
+	}
+
+	t.Run("only on expected row", func(t *testing.T) {
+		r := RowDiff{Status: OnlyOnExpect, Fields: []Diff{{Key: "id", Expect: 1}, {Key: "name", Expect: "Frank"}}}
+		assert.False(t, r.IsMatch())
+		assert.Equal(t, []string{"id", "name"}, keys(r.MissingFields()))
+		assert.Equal(t, 0, len(r.ExtraFields()))
+		assert.Equal(t, 0, len(r.MismatchedFields()))
+	})
+	t.Run("only on actual row", func(t *testing.T) {
+		r := RowDiff{Status: OnlyOnActual, Fields: []Diff{{Key: "id", Actual: 1}, {Key: "name", Actual: "Frank"}}}
+		assert.False(t, r.IsMatch())
+		assert.Equal(t, []string{"id", "name"}, keys(r.ExtraFields()))
+		assert.Equal(t, 0, len(r.MissingFields()))
+		assert.Equal(t, 0, len(r.MismatchedFields()))
+	})
+	t.Run("truncated sentinel", func(t *testing.T) {
+		r := RowDiff{Status: Truncated, Fields: []Diff{{Key: "3"}}}
+		assert.False(t, r.IsMatch())
+		assert.Equal(t, 0, len(r.MissingFields()))
+		assert.Equal(t, 0, len(r.ExtraFields()))
+	})
+}
+
 func Test_compareTable(t *testing.T) {
 	type args struct {
 		tableName string
@@ -893,9 +1309,1530 @@ func Test_compareTable(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := compareTable(tt.args.tableName, tt.args.strategy, tt.args.pkeys, tt.args.expected, tt.args.actual); !reflect.DeepEqual(got, tt.want) {
+			if got := compareTable(tt.args.tableName, tt.args.strategy, tt.args.pkeys, tt.args.expected, tt.args.actual, 0); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("compareTable() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+// Test_compareTableManyRows checks tables that have more than 10 rows are compared to the end.
+func Test_compareTableManyRows(t *testing.T) {
+	rows := func(n int, value func(i int) int) [][]Value {
+		var result [][]Value
+		for i := 1; i <= n; i++ {
+			if v := value(i); v >= 0 {
+				result = append(result, []Value{{Key: "key", Value: i}, {Key: "value", Value: v}})
+			}
+		}
+		return result
+	}
+	statuses := func(r AssertTableResult) map[int]AssertStatus {
+		result := map[int]AssertStatus{}
+		for _, row := range r.Rows {
+			key := row.Fields[0].Expect
+			if key == nil {
+				key = row.Fields[0].Actual
+			}
+			result[key.(int)] = row.Status
+		}
+		return result
+	}
+	identity := func(i int) int { return i }
+
+	t.Run("same rows", func(t *testing.T) {
+		got := compareTable("table1", ExactMatchStrategy, []string{"key"}, rows(20, identity), rows(20, identity), 0)
+		assert.Equal(t, Match, got.Status)
+		assert.Equal(t, 20, len(got.Rows))
+		for _, r := range got.Rows {
+			assert.Equal(t, Match, r.Status)
+		}
+	})
+
+	t.Run("different value after 10th row", func(t *testing.T) {
+		actual := rows(20, func(i int) int {
+			if i == 15 {
+				return 150
+			}
+			return i
+		})
+		got := compareTable("table1", ExactMatchStrategy, []string{"key"}, rows(20, identity), actual, 0)
+		assert.Equal(t, NotMatch, got.Status)
+		assert.Equal(t, 20, len(got.Rows))
+		for i, r := range got.Rows {
+			if i+1 == 15 {
+				assert.Equal(t, NotMatch, r.Status)
+				assert.Equal(t, Diff{Key: "value", Expect: 15, Actual: 150, Status: NotMatch}, r.Fields[1])
+			} else {
+				assert.Equal(t, Match, r.Status, "row %d", i+1)
+			}
+		}
+	})
+
+	t.Run("missing and extra rows after 10th row", func(t *testing.T) {
+		expected := rows(20, func(i int) int {
+			if i == 18 {
+				return -1 // only on actual
+			}
+			return i
+		})
+		actual := rows(20, func(i int) int {
+			if i == 12 {
+				return -1 // only on expected
+			}
+			return i
+		})
+		got := compareTable("table1", ExactMatchStrategy, []string{"key"}, expected, actual, 0)
+		assert.Equal(t, NotMatch, got.Status)
+		assert.Equal(t, 20, len(got.Rows))
+		want := map[int]AssertStatus{}
+		for i := 1; i <= 20; i++ {
+			want[i] = Match
+		}
+		want[12] = OnlyOnExpect
+		want[18] = OnlyOnActual
+		assert.Equal(t, want, statuses(got))
+
+		got = compareTable("table1", SubMatchStrategy, []string{"key"}, expected, actual, 0)
+		assert.Equal(t, NotMatch, got.Status)
+		assert.Equal(t, 19, len(got.Rows))
+		delete(want, 18)
+		assert.Equal(t, want, statuses(got))
+	})
+}
+
+// FuzzCompareTable generates sorted expected/actual rows and checks every row is reported at the right position.
+//
+// Each byte of rows decides the row with the key of its index: bit 0 is on expected, bit 1 is on actual
+// and bit 2 makes the value different.
+func FuzzCompareTable(f *testing.F) {
+	f.Add([]byte{3, 3, 3}, false)
+	f.Add([]byte{1, 2, 3, 7, 0, 1}, false)
+	f.Add([]byte{2, 2, 1, 1, 3, 2, 3, 3, 3, 3, 1, 3}, true)
+	// 9 and 10: comparing as string makes wrong order
+	f.Add([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 3}, false)
+	f.Fuzz(func(t *testing.T, rows []byte, subMatch bool) {
+		strategy := ExactMatchStrategy
+		if subMatch {
+			strategy = SubMatchStrategy
+		}
+		var expected, actual [][]Value
+		want := map[int]AssertStatus{}
+		for key, b := range rows {
+			onExpected, onActual, different := b&1 != 0, b&2 != 0, b&4 != 0
+			if onExpected {
+				expected = append(expected, []Value{{Key: "key", Value: key}, {Key: "value", Value: key}})
+			}
+			if onActual {
+				if different {
+					actual = append(actual, []Value{{Key: "key", Value: key}, {Key: "value", Value: -key - 1}})
+				} else {
+					actual = append(actual, []Value{{Key: "key", Value: key}, {Key: "value", Value: key}})
+				}
+			}
+			switch {
+			case onExpected && onActual && different:
+				want[key] = NotMatch
+			case onExpected && onActual:
+				want[key] = Match
+			case onExpected:
+				want[key] = OnlyOnExpect
+			case onActual && strategy == ExactMatchStrategy:
+				want[key] = OnlyOnActual
+			}
+		}
+
+		got := compareTable("table1", strategy, []string{"key"}, expected, actual, 0)
+		gotStatus := map[int]AssertStatus{}
+		for _, r := range got.Rows {
+			key := r.Fields[0].Expect
+			if r.Status == OnlyOnActual {
+				key = r.Fields[0].Actual
+			}
+			if _, ok := gotStatus[key.(int)]; ok {
+				t.Fatalf("row %d is reported twice", key)
+			}
+			gotStatus[key.(int)] = r.Status
+		}
+		assert.Equal(t, want, gotStatus)
+
+		wantStatus := Match
+		for _, s := range want {
+			if s != Match {
+				wantStatus = NotMatch
+			}
+		}
+		assert.Equal(t, wantStatus, got.Status)
+	})
+}
+
+func TestAssertResult(t *testing.T) {
+	user := AssertTableResult{
+		Name:   "user",
+		Status: NotMatch,
+		Rows: []RowDiff{
+			{Status: Match},
+			{Status: NotMatch},
+			{Status: OnlyOnExpect},
+			{Status: OnlyOnActual},
+			{Status: Truncated, Fields: []Diff{{Key: "3"}}},
+		},
+	}
+	group := AssertTableResult{
+		Name:   "group",
+		Status: NotMatch,
+		Rows: []RowDiff{
+			{Status: OnlyOnExpect},
+		},
+	}
+	tag := AssertTableResult{
+		Name:   "tag",
+		Status: Match,
+		Rows: []RowDiff{
+			{Status: Match},
+		},
+	}
+
+	t.Run("match", func(t *testing.T) {
+		r := NewAssertResult([]AssertTableResult{tag})
+		assert.True(t, r.IsMatch())
+		assert.Equal(t, 0, len(r.FailedTables()))
+		assert.Equal(t, 0, r.DiffCount())
+		assert.Equal(t, "1 table match", r.Summary())
+	})
+	t.Run("not match", func(t *testing.T) {
+		r := NewAssertResult([]AssertTableResult{user, tag, group})
+		assert.False(t, r.IsMatch())
+		assert.Equal(t, []AssertTableResult{user, group}, r.FailedTables())
+		assert.Equal(t, 7, r.DiffCount())
+		assert.Equal(t, "2 of 3 tables do not match: user (6 rows), group (1 row)", r.Summary())
+	})
+	t.Run("empty", func(t *testing.T) {
+		r := NewAssertResult(nil)
+		assert.True(t, r.IsMatch())
+		assert.Equal(t, "0 tables match", r.Summary())
+	})
+}
+
+func TestAssertRowFilter(t *testing.T) {
+	os.Remove("assert_row_filter_test.db")
+	connStr := "file:assert_row_filter_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS member (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL
+		);
+
+		INSERT INTO member (id, name)
+		VALUES
+			(0, 'system'),
+			(1, 'Frank'),
+			(2, 'Grace');
+		`))
+	assert.NoError(t, err)
+
+	skipSystemRow := func(tableName string, row []Value) bool {
+		return !(tableName == "member" && row[0].Key == "id" && row[0].Value == 0)
+	}
+
+	tests := []struct {
+		name      string
+		src       string
+		rowFilter func(tableName string, row []Value) bool
+		wantMatch bool
+	}{
+		{
+			name: "without filter: system row is reported",
+			src: TrimIndent(t, `
+				member:
+				- { id: 1, name: Frank }
+				- { id: 2, name: Grace }
+				`),
+			wantMatch: false,
+		},
+		{
+			name: "with filter: system row on actual is ignored",
+			src: TrimIndent(t, `
+				member:
+				- { id: 1, name: Frank }
+				- { id: 2, name: Grace }
+				`),
+			rowFilter: skipSystemRow,
+			wantMatch: true,
+		},
+		{
+			name: "with filter: system row on expected is ignored",
+			src: TrimIndent(t, `
+				member:
+				- { id: 0, name: wrong-system-name }
+				- { id: 1, name: Frank }
+				- { id: 2, name: Grace }
+				`),
+			rowFilter: skipSystemRow,
+			wantMatch: true,
+		},
+		{
+			name: "with filter: other rows are still compared",
+			src: TrimIndent(t, `
+				member:
+				- { id: 1, name: Frank }
+				- { id: 2, name: Heidi }
+				`),
+			rowFilter: skipSystemRow,
+			wantMatch: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect, err := ParseYAML(strings.NewReader(tt.src))
+			assert.NoError(t, err)
+
+			ok, result, err := Assert(ctx, dbc, expect, AssertOpt{
+				RowFilter: tt.rowFilter,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMatch, ok)
+			if tt.rowFilter != nil {
+				for _, r := range result[0].Rows {
+					assert.NotEqual(t, 0, r.Fields[0].Expect)
+					assert.NotEqual(t, 0, r.Fields[0].Actual)
+				}
+			}
+		})
+	}
+}
+
+func TestAssertFailFast(t *testing.T) {
+	os.Remove("assert_fail_fast_test.db")
+	connStr := "file:assert_fail_fast_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS member (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS team (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL
+		);
+
+		INSERT INTO member (id, name) VALUES (1, 'Frank');
+		INSERT INTO team (id, name) VALUES (1, 'Blue');
+		`))
+	assert.NoError(t, err)
+
+	expect := &DataSet{
+		Tables: []*Table{
+			{
+				Name: "member",
+				Rows: []map[string]any{{"id": 1, "name": "Grace"}}, // not match
+				Tags: [][]string{nil},
+			},
+			{
+				Name: "team",
+				Rows: []map[string]any{{"id": 1, "name": "Blue"}},
+				Tags: [][]string{nil},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		failFast     bool
+		wantTables   []string
+		wantCallback []string
+	}{
+		{
+			name:         "without fail fast: all tables are processed",
+			failFast:     false,
+			wantTables:   []string{"member", "team"},
+			wantCallback: []string{"member", "team"},
+		},
+		{
+			name:         "with fail fast: stop after first mismatch",
+			failFast:     true,
+			wantTables:   []string{"member"},
+			wantCallback: []string{"member"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called []string
+			ok, result, err := Assert(ctx, dbc, expect, AssertOpt{
+				FailFast: tt.failFast,
+				Callback: func(targetTable string, mode MatchStrategy, start bool, err error) {
+					if start {
+						called = append(called, targetTable)
+					}
+				},
+			})
+			if tt.failFast {
+				var e ErrAssertFailed
+				assert.True(t, errors.As(err, &e))
+				assert.Equal(t, ErrAssertFailed{TableName: "member", RowCount: 1}, e)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.False(t, ok)
+			var tables []string
+			for _, r := range result {
+				tables = append(tables, r.Name)
+			}
+			assert.Equal(t, tt.wantTables, tables)
+			assert.Equal(t, tt.wantCallback, called)
+		})
+	}
+}
+
+func TestAssertAllDiffCallback(t *testing.T) {
+	os.Remove("assert_all_diff_callback_test.db")
+	connStr := "file:assert_all_diff_callback_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS member (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS team (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL
+		);
+
+		INSERT INTO member (id, name) VALUES (1, 'Frank');
+		INSERT INTO team (id, name) VALUES (1, 'Blue');
+		`))
+	assert.NoError(t, err)
+
+	expect := &DataSet{
+		Tables: []*Table{
+			{
+				Name: "member",
+				Rows: []map[string]any{{"id": 1, "name": "Grace"}}, // not match
+				Tags: [][]string{nil},
+			},
+			{
+				Name: "team",
+				Rows: []map[string]any{{"id": 1, "name": "Blue"}},
+				Tags: [][]string{nil},
+			},
+		},
+	}
+
+	for name, failFast := range map[string]bool{"all tables": false, "fail fast": true} {
+		t.Run(name, func(t *testing.T) {
+			var perTable []AssertTableResult
+			var all [][]AssertTableResult
+			_, result, _ := Assert(ctx, dbc, expect, AssertOpt{
+				FailFast: failFast,
+				DiffCallback: func(r AssertTableResult) {
+					perTable = append(perTable, r)
+				},
+				AllDiffCallback: func(results []AssertTableResult) {
+					all = append(all, results)
+				},
+			})
+			assert.Equal(t, 1, len(all))
+			assert.Equal(t, perTable, all[0])
+			assert.Equal(t, result, all[0])
+			if failFast {
+				assert.Equal(t, 1, len(all[0]))
+			} else {
+				assert.Equal(t, 2, len(all[0]))
+			}
+		})
+	}
+
+	t.Run("not called on error", func(t *testing.T) {
+		var called bool
+		_, _, err := Assert(ctx, dbc, &DataSet{
+			Tables: []*Table{{Name: "missing", Rows: []map[string]any{{"id": 1}}, Tags: [][]string{nil}}},
+		}, AssertOpt{
+			AllDiffCallback: func(results []AssertTableResult) {
+				called = true
+			},
+		})
+		assert.Error(t, err)
+		assert.False(t, called)
+	})
+}
+
+func Test_compareTableMaxDiffRows(t *testing.T) {
+	var expected, actual [][]Value
+	for i := 1; i <= 6; i++ {
+		expected = append(expected, []Value{{Key: "key", Value: i}, {Key: "value", Value: i}})
+		if i == 1 {
+			actual = append(actual, []Value{{Key: "key", Value: i}, {Key: "value", Value: i}})
+		} else {
+			actual = append(actual, []Value{{Key: "key", Value: i}, {Key: "value", Value: i * 10}})
+		}
+	}
+
+	tests := []struct {
+		name          string
+		maxDiffRows   int
+		wantRows      int
+		wantDiffRows  int
+		wantTruncated string
+	}{
+		{
+			name:         "no limit",
+			maxDiffRows:  0,
+			wantRows:     6,
+			wantDiffRows: 5,
+		},
+		{
+			name:          "limit is smaller than diff rows",
+			maxDiffRows:   2,
+			wantRows:      4, // 1 match + 2 diff + sentinel
+			wantDiffRows:  2,
+			wantTruncated: "3",
+		},
+		{
+			name:         "limit is same as diff rows",
+			maxDiffRows:  5,
+			wantRows:     6,
+			wantDiffRows: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareTable("table1", ExactMatchStrategy, []string{"key"}, expected, actual, tt.maxDiffRows)
+			assert.Equal(t, NotMatch, got.Status)
+			assert.Equal(t, tt.wantRows, len(got.Rows))
+			var diffRows int
+			var truncated string
+			for _, r := range got.Rows {
+				switch r.Status {
+				case NotMatch:
+					diffRows++
+				case Truncated:
+					truncated = r.Fields[0].Key
+				}
+			}
+			assert.Equal(t, tt.wantDiffRows, diffRows)
+			assert.Equal(t, tt.wantTruncated, truncated)
+			DumpDiffCLICallback(true, false)(got)
+		})
+	}
+}
+
+func TestAssertPKOverride(t *testing.T) {
+	os.Remove("assert_pk_override_test.db")
+	connStr := "file:assert_pk_override_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	// no primary key at DDL level
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS event_log (
+			event_id INTEGER NOT NULL,
+			occurred_at TEXT NOT NULL,
+			message TEXT
+		);
+
+		INSERT INTO event_log (event_id, occurred_at, message)
+		VALUES
+			(2, '2024-01-01', 'stop'),
+			(1, '2024-01-02', 'restart'),
+			(1, '2024-01-01', 'start');
+		`))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		src       string
+		wantMatch bool
+		wantPKeys []string
+		wantErr   bool
+	}{
+		{
+			name: "match",
+			src: TrimIndent(t, `
+				_pkey:
+				    event_log: [occurred_at, event_id]
+				event_log:
+				- { event_id: 1, occurred_at: "2024-01-02", message: restart }
+				- { event_id: 1, occurred_at: "2024-01-01", message: start }
+				- { event_id: 2, occurred_at: "2024-01-01", message: stop }
+				`),
+			wantMatch: true,
+			wantPKeys: []string{"event_id", "occurred_at"},
+		},
+		{
+			name: "not match",
+			src: TrimIndent(t, `
+				_pkey:
+				    event_log: [event_id, occurred_at]
+				event_log:
+				- { event_id: 1, occurred_at: "2024-01-01", message: start }
+				- { event_id: 1, occurred_at: "2024-01-02", message: restart }
+				- { event_id: 2, occurred_at: "2024-01-01", message: shutdown }
+				`),
+			wantMatch: false,
+			wantPKeys: []string{"event_id", "occurred_at"},
+		},
+		{
+			name: "unknown column in _pkey",
+			src: TrimIndent(t, `
+				_pkey:
+				    event_log: [id]
+				event_log:
+				- { id: 1, message: start }
+				`),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect, err := ParseYAML(strings.NewReader(tt.src))
+			assert.NoError(t, err)
+
+			ok, result, err := Assert(ctx, dbc, expect, AssertOpt{})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMatch, ok)
+			assert.Equal(t, tt.wantPKeys, result[0].PrimaryKeys)
+			assert.Equal(t, 3, len(result[0].Rows))
+			for _, r := range result[0].Rows {
+				// rows are always paired by the overridden keys
+				assert.NotEqual(t, OnlyOnExpect, r.Status)
+				assert.NotEqual(t, OnlyOnActual, r.Status)
+			}
+		})
+	}
+}
+
+func TestAssertWhereSQLite(t *testing.T) {
+	os.Remove("assert_where_test.db")
+	connStr := "file:assert_where_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS events (
+			id INTEGER PRIMARY KEY,
+			tenant_id INTEGER NOT NULL,
+			name TEXT NOT NULL
+		);
+
+		INSERT INTO events (id, tenant_id, name)
+		VALUES
+			(1, 42, 'login'),
+			(2, 7, 'login'),
+			(3, 42, 'logout');
+		`))
+	assert.NoError(t, err)
+
+	testAssertWhere(t, ctx, dbc)
+}
+
+func TestAssertWherePostgreSQL(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := context.Background()
+
+	pgContainer, err := postgres.Run(ctx, "postgres:15.3-alpine",
+		postgres.WithDatabase("asserttest"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).WithStartupTimeout(5*time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := pgContainer.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	assert.NoError(t, err)
+
+	ctx2, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx2, connStr)
+	assert.NoError(t, err)
+
+	_, err = dbc.DB().ExecContext(ctx2, TrimIndent(t, `
+		CREATE TABLE events (
+			id INTEGER NOT NULL,
+			tenant_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			PRIMARY KEY (id)
+		);
+
+		INSERT INTO events (id, tenant_id, name)
+		VALUES
+			(1, 42, 'login'),
+			(2, 7, 'login'),
+			(3, 42, 'logout');
+		`))
+	assert.NoError(t, err)
+
+	testAssertWhere(t, ctx2, dbc)
+}
+
+func testAssertWhere(t *testing.T, ctx context.Context, dbc DBConnector) {
+	t.Helper()
+	tests := []struct {
+		name      string
+		src       string
+		wantMatch bool
+	}{
+		{
+			name: "without where: other tenant's row is reported",
+			src: TrimIndent(t, `
+				events:
+				- { id: 1, tenant_id: 42, name: login }
+				- { id: 3, tenant_id: 42, name: logout }
+				`),
+			wantMatch: false,
+		},
+		{
+			name: "with where: only rows of the tenant are fetched",
+			src: TrimIndent(t, `
+				_where:
+				    events: "tenant_id = 42"
+				events:
+				- { id: 1, tenant_id: 42, name: login }
+				- { id: 3, tenant_id: 42, name: logout }
+				`),
+			wantMatch: true,
+		},
+		{
+			name: "with where: fetched rows are still compared",
+			src: TrimIndent(t, `
+				_where:
+				    events: "tenant_id = 42"
+				events:
+				- { id: 1, tenant_id: 42, name: login }
+				`),
+			wantMatch: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect, err := ParseYAML(strings.NewReader(tt.src))
+			assert.NoError(t, err)
+
+			ok, _, err := Assert(ctx, dbc, expect, AssertOpt{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMatch, ok)
+		})
+	}
+}
+
+func TestAssertOrder(t *testing.T) {
+	os.Remove("assert_order_test.db")
+	connStr := "file:assert_order_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	// no primary key at DDL level
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS event_log (
+			event_id INTEGER NOT NULL,
+			occurred_at TEXT NOT NULL,
+			message TEXT
+		);
+
+		INSERT INTO event_log (event_id, occurred_at, message)
+		VALUES
+			(2, '2024-01-02', 'stop'),
+			(3, '2024-01-01', 'restart'),
+			(1, '2024-01-01', 'start');
+		`))
+	assert.NoError(t, err)
+
+	t.Run("fetch: rows are sorted by ORDER BY", func(t *testing.T) {
+		actual, _, err := fetchTableData(ctx, dbc, "event_log", fetchOpt{
+			OrderBy: []string{"occurred_at", "event_id"},
+		})
+		assert.NoError(t, err)
+		var messages []any
+		for _, row := range actual {
+			messages = append(messages, row[1].Value)
+		}
+		assert.Equal(t, []any{"start", "restart", "stop"}, messages)
+	})
+
+	tests := []struct {
+		name         string
+		src          string
+		wantMatch    bool
+		wantPKeys    []string
+		wantMessages []any
+	}{
+		{
+			name: "_order only: order columns are used to pair rows",
+			src: TrimIndent(t, `
+				_order:
+				    event_log: [occurred_at, event_id]
+				event_log:
+				- { event_id: 2, occurred_at: "2024-01-02", message: stop }
+				- { event_id: 1, occurred_at: "2024-01-01", message: start }
+				- { event_id: 3, occurred_at: "2024-01-01", message: restart }
+				`),
+			wantMatch:    true,
+			wantPKeys:    []string{"occurred_at", "event_id"},
+			wantMessages: []any{"start", "restart", "stop"},
+		},
+		{
+			name: "_order only: not match",
+			src: TrimIndent(t, `
+				_order:
+				    event_log: [occurred_at, event_id]
+				event_log:
+				- { event_id: 2, occurred_at: "2024-01-02", message: shutdown }
+				- { event_id: 1, occurred_at: "2024-01-01", message: start }
+				- { event_id: 3, occurred_at: "2024-01-01", message: restart }
+				`),
+			wantMatch:    false,
+			wantPKeys:    []string{"occurred_at", "event_id"},
+			wantMessages: []any{"start", "restart", "stop"},
+		},
+		{
+			name: "_order and _pkey: _pkey is used to pair rows",
+			src: TrimIndent(t, `
+				_order:
+				    event_log: [occurred_at, event_id]
+				_pkey:
+				    event_log: [event_id]
+				event_log:
+				- { event_id: 3, occurred_at: "2024-01-01", message: restart }
+				- { event_id: 2, occurred_at: "2024-01-02", message: stop }
+				- { event_id: 1, occurred_at: "2024-01-01", message: start }
+				`),
+			wantMatch:    true,
+			wantPKeys:    []string{"event_id"},
+			wantMessages: []any{"start", "stop", "restart"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect, err := ParseYAML(strings.NewReader(tt.src))
+			assert.NoError(t, err)
+
+			ok, result, err := Assert(ctx, dbc, expect, AssertOpt{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMatch, ok)
+			assert.Equal(t, tt.wantPKeys, result[0].PrimaryKeys)
+			var messages []any
+			for _, row := range result[0].Rows {
+				for _, f := range row.Fields {
+					if f.Key == "message" {
+						messages = append(messages, f.Actual)
+					}
+				}
+			}
+			assert.Equal(t, tt.wantMessages, messages)
+		})
+	}
+}
+
+func TestAssertOptOrderBy(t *testing.T) {
+	os.Remove("assert_opt_order_by_test.db")
+	connStr := "file:assert_opt_order_by_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS task (
+			id INTEGER PRIMARY KEY,
+			created_at TEXT NOT NULL,
+			title TEXT NOT NULL
+		);
+
+		INSERT INTO task (id, created_at, title)
+		VALUES
+			(1, '2024-01-03', 'deploy'),
+			(2, '2024-01-01', 'design'),
+			(3, '2024-01-02', 'implement');
+		`))
+	assert.NoError(t, err)
+
+	inCreatedOrder := TrimIndent(t, `
+		task:
+		- { id: 2, created_at: "2024-01-01", title: design }
+		- { id: 3, created_at: "2024-01-02", title: implement }
+		- { id: 1, created_at: "2024-01-03", title: deploy }
+		`)
+	inIDOrder := TrimIndent(t, `
+		task:
+		- { id: 1, created_at: "2024-01-03", title: deploy }
+		- { id: 2, created_at: "2024-01-01", title: design }
+		- { id: 3, created_at: "2024-01-02", title: implement }
+		`)
+
+	tests := []struct {
+		name      string
+		src       string
+		orderBy   map[string][]string
+		wantMatch bool
+	}{
+		{
+			name:      "order by created_at: same order",
+			src:       inCreatedOrder,
+			orderBy:   map[string][]string{"task": {"created_at"}},
+			wantMatch: true,
+		},
+		{
+			name:      "order by created_at: different order",
+			src:       inIDOrder,
+			orderBy:   map[string][]string{"task": {"created_at"}},
+			wantMatch: false,
+		},
+		{
+			name:      "without order by: rows are paired by primary keys",
+			src:       inCreatedOrder,
+			wantMatch: true,
+		},
+		{
+			name:      "order by of other table",
+			src:       inCreatedOrder,
+			orderBy:   map[string][]string{"other": {"created_at"}},
+			wantMatch: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect, err := ParseYAML(strings.NewReader(tt.src))
+			assert.NoError(t, err)
+
+			ok, result, err := Assert(ctx, dbc, expect, AssertOpt{OrderBy: tt.orderBy})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMatch, ok)
+			assert.Equal(t, []string{"id"}, result[0].PrimaryKeys)
+			assert.Equal(t, 3, len(result[0].Rows))
+		})
+	}
+
+	t.Run("mismatched rows", func(t *testing.T) {
+		expect, err := ParseYAML(strings.NewReader(inIDOrder))
+		assert.NoError(t, err)
+		_, result, err := Assert(ctx, dbc, expect, AssertOpt{OrderBy: map[string][]string{"task": {"created_at"}}})
+		assert.NoError(t, err)
+		// first row: expected id 1 but actual id 2
+		assert.Equal(t, NotMatch, result[0].Rows[0].Status)
+		assert.Equal(t, Diff{Key: "id", Expect: 1, Actual: 2, Status: NotMatch}, result[0].Rows[0].Fields[0])
+	})
+}
+
+// columnSelector limits the columns like ColumnSelector implementation of DBConnector.
+type columnSelector struct {
+	DBConnector
+	columns map[string][]string
+}
+
+func (c columnSelector) SelectColumns(ctx context.Context, tableName string) ([]string, error) {
+	return c.columns[tableName], nil
+}
+
+var _ ColumnSelector = columnSelector{}
+
+func TestAssertSelectColumns(t *testing.T) {
+	os.Remove("assert_select_columns_test.db")
+	connStr := "file:assert_select_columns_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS image (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			data BLOB
+		);
+
+		INSERT INTO image (id, name, data)
+		VALUES
+			(1, 'logo', x'89504e47'),
+			(2, 'icon', x'47494638');
+		`))
+	assert.NoError(t, err)
+
+	expect, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		image:
+		- { id: 1, name: logo }
+		- { id: 2, name: icon }
+		`)))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		dbc      DBConnector
+		opt      AssertOpt
+		wantKeys []string
+	}{
+		{
+			name:     "all columns",
+			dbc:      dbc,
+			wantKeys: []string{"id", "data", "name"},
+		},
+		{
+			name:     "AssertOpt.SelectColumns: primary keys are added",
+			dbc:      dbc,
+			opt:      AssertOpt{SelectColumns: map[string][]string{"image": {"name"}}},
+			wantKeys: []string{"id", "name"},
+		},
+		{
+			name:     "ColumnSelector",
+			dbc:      columnSelector{DBConnector: dbc, columns: map[string][]string{"image": {"id", "name"}}},
+			wantKeys: []string{"id", "name"},
+		},
+		{
+			name:     "ColumnSelector returns nothing for the table",
+			dbc:      columnSelector{DBConnector: dbc, columns: map[string][]string{}},
+			wantKeys: []string{"id", "data", "name"},
+		},
+		{
+			name:     "AssertOpt.SelectColumns overrides ColumnSelector",
+			dbc:      columnSelector{DBConnector: dbc, columns: map[string][]string{"image": {"id", "data"}}},
+			opt:      AssertOpt{SelectColumns: map[string][]string{"image": {"id", "name"}}},
+			wantKeys: []string{"id", "name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, _, err := Assert(ctx, tt.dbc, expect, tt.opt)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			rows, _, err := fetchTableData(ctx, tt.dbc, "image", fetchOpt{Columns: tt.opt.SelectColumns["image"]})
+			assert.NoError(t, err)
+			var keys []string
+			for _, v := range rows[0] {
+				keys = append(keys, v.Key)
+			}
+			assert.Equal(t, tt.wantKeys, keys)
+		})
+	}
+}
+
+func TestAssertIgnoreColumns(t *testing.T) {
+	os.Remove("assert_ignore_columns_test.db")
+	connStr := "file:assert_ignore_columns_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS orders (
+			id INTEGER PRIMARY KEY,
+			status TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		);
+
+		INSERT INTO orders (id, status, updated_at)
+		VALUES
+			(1, 'paid', '2025-01-01'),
+			(2, 'shipped', '2025-01-02');
+		`))
+	assert.NoError(t, err)
+
+	expect, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		orders:
+		- { id: 1, status: paid, updated_at: "2000-01-01" }
+		- { id: 2, status: shipped, updated_at: "2000-01-01" }
+		`)))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		ignoreColumns map[string][]string
+		wantMatch     bool
+	}{
+		{
+			name:      "without ignore columns",
+			wantMatch: false,
+		},
+		{
+			name:          "updated_at is ignored",
+			ignoreColumns: map[string][]string{"orders": {"updated_at"}},
+			wantMatch:     true,
+		},
+		{
+			name:          "ignore columns of other table",
+			ignoreColumns: map[string][]string{"users": {"updated_at"}},
+			wantMatch:     false,
+		},
+		{
+			name:          "primary key is not ignored",
+			ignoreColumns: map[string][]string{"orders": {"id", "updated_at"}},
+			wantMatch:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, result, err := Assert(ctx, dbc, expect, AssertOpt{
+				IgnoreColumns: tt.ignoreColumns,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMatch, ok)
+			if tt.wantMatch {
+				for _, r := range result[0].Rows {
+					assert.Equal(t, []string{"id", "status"}, []string{r.Fields[0].Key, r.Fields[1].Key})
+					assert.Equal(t, 2, len(r.Fields))
+				}
+			}
+		})
+	}
+}
+
+func TestAssertCount(t *testing.T) {
+	os.Remove("assert_count_test.db")
+	connStr := "file:assert_count_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS member (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS item (
+			id INTEGER PRIMARY KEY
+		);
+
+		INSERT INTO member (id, name)
+		VALUES
+			(1, 'Frank'),
+			(2, 'Grace'),
+			(3, 'Heidi');
+		INSERT INTO item (id) VALUES (1);
+		`))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		src        string
+		opt        AssertOpt
+		wantMatch  bool
+		wantResult []CountResult
+	}{
+		{
+			name: "match: values are not compared",
+			src: TrimIndent(t, `
+				member:
+				- { id: 1, name: wrong }
+				- { id: 2 }
+				- { id: 3 }
+				item:
+				- { id: 100 }
+				`),
+			wantMatch:  true,
+			wantResult: []CountResult{{"member", 3, 3}, {"item", 1, 1}},
+		},
+		{
+			name: "not match",
+			src: TrimIndent(t, `
+				member:
+				- { id: 1 }
+				item:
+				- { id: 1 }
+				`),
+			wantMatch:  false,
+			wantResult: []CountResult{{"member", 1, 3}, {"item", 1, 1}},
+		},
+		{
+			name: "tags and where",
+			src: TrimIndent(t, `
+				_where:
+				  member: id > 1
+				member:
+				- { id: 2, _tag: [a] }
+				- { id: 3, _tag: [a] }
+				- { id: 4, _tag: [b] }
+				`),
+			opt:        AssertOpt{IncludeTags: []string{"a"}},
+			wantMatch:  true,
+			wantResult: []CountResult{{"member", 2, 2}},
+		},
+		{
+			name: "target tables",
+			src: TrimIndent(t, `
+				member:
+				- { id: 1 }
+				item:
+				- { id: 1 }
+				`),
+			opt:        AssertOpt{TargetTables: []string{"item"}},
+			wantMatch:  true,
+			wantResult: []CountResult{{"item", 1, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect, err := ParseYAML(strings.NewReader(tt.src))
+			assert.NoError(t, err)
+			slices.SortFunc(expect.Tables, func(a, b *Table) int { return -strings.Compare(a.Name, b.Name) })
+
+			ok, result, err := AssertCount(ctx, dbc, expect, tt.opt)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMatch, ok)
+			assert.Equal(t, tt.wantResult, result)
+		})
+	}
+}
+
+func TestAssertBinarySQLite(t *testing.T) {
+	os.Remove("assert_binary_test.db")
+	connStr := "file:assert_binary_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS image (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			data BLOB
+		);
+		`))
+	assert.NoError(t, err)
+
+	seed, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		image:
+		- { id: 1, name: logo, data: !!binary iVBORw== }
+		- { id: 2, name: empty, data: !!binary "" }
+		`)))
+	assert.NoError(t, err)
+	assert.Equal[any](t, []byte{0x89, 'P', 'N', 'G'}, findTable(t, seed, "image").Rows[0]["data"])
+	assert.NoError(t, Seed(ctx, dbc, seed, SeedOpt{}))
+
+	var data []byte
+	assert.NoError(t, dbc.DB().QueryRowContext(ctx, "SELECT data FROM image WHERE id = 1").Scan(&data))
+	assert.Equal(t, []byte{0x89, 'P', 'N', 'G'}, data)
+
+	tests := []struct {
+		name string
+		src  string
+		want bool
+	}{
+		{
+			name: "!!binary",
+			src:  "image:\n- { id: 1, name: logo, data: !!binary iVBORw== }\n- { id: 2, name: empty, data: !!binary \"\" }\n",
+			want: true,
+		},
+		{
+			name: "[base64] placeholder",
+			src:  "image:\n- { id: 1, name: logo, data: [base64, iVBORw==] }\n- { id: 2, name: empty, data: [base64, ''] }\n",
+			want: true,
+		},
+		{
+			name: "!!binary: ng",
+			src:  "image:\n- { id: 1, name: logo, data: !!binary R0lGOA== }\n- { id: 2, name: empty }\n",
+			want: false,
+		},
+		{
+			name: "[base64] placeholder: ng",
+			src:  "image:\n- { id: 1, name: logo, data: [base64, R0lGOA==] }\n- { id: 2, name: empty }\n",
+			want: false,
+		},
+		{
+			name: "[base64] placeholder: invalid base64",
+			src:  "image:\n- { id: 1, name: logo, data: [base64, '!!!'] }\n- { id: 2, name: empty }\n",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect, err := ParseYAML(strings.NewReader(tt.src))
+			assert.NoError(t, err)
+			ok, _, err := Assert(ctx, dbc, expect, AssertOpt{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, ok)
+		})
+	}
+
+	t.Run("snapshot round trip", func(t *testing.T) {
+		snapshot, err := Snapshot(ctx, dbc, []string{"image"})
+		assert.NoError(t, err)
+		// text columns are still strings
+		assert.Equal[any](t, "logo", findTable(t, snapshot, "image").Rows[0]["name"])
+		var buf bytes.Buffer
+		assert.NoError(t, snapshot.WriteYAML(&buf))
+		expect, err := ParseYAML(&buf)
+		assert.NoError(t, err)
+		ok, _, err := Assert(ctx, dbc, expect, AssertOpt{})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
+
+func TestAssertTimeZone(t *testing.T) {
+	os.Remove("assert_time_zone_test.db")
+	connStr := "file:assert_time_zone_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS event (
+			id INTEGER PRIMARY KEY,
+			occurred_at DATETIME NOT NULL
+		);
+
+		INSERT INTO event (id, occurred_at)
+		VALUES
+			(1, '2024-01-01 12:00:00+00:00'),
+			(2, '2024-06-30 23:30:00.5+00:00');
+		`))
+	assert.NoError(t, err)
+
+	est := time.FixedZone("EST", -5*60*60)
+	jst := time.FixedZone("JST", 9*60*60)
+
+	tests := []struct {
+		name     string
+		timeZone *time.Location
+		src      string
+		want     bool
+	}{
+		{
+			name:     "UTC",
+			timeZone: time.UTC,
+			src:      "event:\n- { id: 1, occurred_at: 2024-01-01T12:00:00Z }\n- { id: 2, occurred_at: '2024-06-30 23:30:00.5+00' }\n",
+			want:     true,
+		},
+		{
+			name:     "different offsets",
+			timeZone: est,
+			src:      "event:\n- { id: 1, occurred_at: '2024-01-01 07:00:00-05:00' }\n- { id: 2, occurred_at: 2024-07-01T08:30:00.5+09:00 }\n",
+			want:     true,
+		},
+		{
+			name:     "no offset: parsed in TimeZone",
+			timeZone: jst,
+			src:      "event:\n- { id: 1, occurred_at: '2024-01-01 21:00:00' }\n- { id: 2, occurred_at: 2024-07-01T08:30:00.5 }\n",
+			want:     true,
+		},
+		{
+			name:     "no offset: ng",
+			timeZone: est,
+			src:      "event:\n- { id: 1, occurred_at: '2024-01-01 12:00:00' }\n- { id: 2, occurred_at: '2024-06-30 18:30:00.5' }\n",
+			want:     false,
+		},
+		{
+			name: "without TimeZone, strings are not parsed",
+			src:  "event:\n- { id: 1, occurred_at: 2024-01-01T12:00:00Z }\n- { id: 2, occurred_at: '2024-06-30 23:30:00.5+00' }\n",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect, err := ParseYAML(strings.NewReader(tt.src))
+			assert.NoError(t, err)
+			ok, result, err := Assert(ctx, dbc, expect, AssertOpt{TimeZone: tt.timeZone})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, ok, "%v", result)
+			if tt.timeZone != nil {
+				// actual values are reported in TimeZone
+				actual := result[0].Rows[0].Fields[1].Actual.(time.Time)
+				assert.Equal(t, tt.timeZone, actual.Location())
+			}
+		})
+	}
+}
+
+func Test_comparePkey(t *testing.T) {
+	tests := []struct {
+		name string
+		v1   any
+		v2   any
+		want int
+	}{
+		{name: "int", v1: 9, v2: 10, want: -1},
+		{name: "float64", v1: 10.5, v2: 9.0, want: 1},
+		{name: "int and float64", v1: 1, v2: 1.0, want: 0},
+		{name: "int and float64: less", v1: 9, v2: 10.0, want: -1},
+		{name: "float64 and int", v1: 10.0, v2: 9, want: 1},
+		{name: "string", v1: "a", v2: "b", want: -1},
+		{name: "different types", v1: "10", v2: 9, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := comparePkey(1, []Value{{Key: "id", Value: tt.v1}}, []Value{{Key: "id", Value: tt.v2}})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalizeValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		val    any
+		dbType string
+		want   any
+	}{
+		{name: "int64", val: int64(1), dbType: "INTEGER", want: 1},
+		{name: "int32", val: int32(1), dbType: "INTEGER", want: 1},
+		{name: "int16", val: int16(1), dbType: "SMALLINT", want: 1},
+		{name: "int8", val: int8(1), dbType: "TINYINT", want: 1},
+		{name: "text as []byte", val: []byte("a"), dbType: "TEXT", want: "a"},
+		{name: "binary", val: []byte("a"), dbType: "blob", want: []byte("a")},
+		{name: "nil", val: nil, dbType: "TEXT", want: nil},
+		{name: "others", val: 1.5, dbType: "REAL", want: 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeValue(tt.val, tt.dbType))
+		})
+	}
+}
+
+func TestAssertFloatPrimaryKey(t *testing.T) {
+	os.Remove("assert_float_pkey_test.db")
+	connStr := "file:assert_float_pkey_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS item (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL
+		);
+
+		INSERT INTO item (id, name)
+		VALUES (1, 'one'), (2, 'two'), (9, 'nine'), (10, 'ten');
+		`))
+	assert.NoError(t, err)
+
+	// 9 and 10 are sorted as numbers, not as strings
+	expect, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		item:
+		- { id: 10.0, name: ten }
+		- { id: 9, name: nine }
+		- { id: 2.0, name: two }
+		- { id: 1.0, name: one }
+		`)))
+	assert.NoError(t, err)
+	assert.Equal[any](t, 10.0, findTable(t, expect, "item").Rows[0]["id"])
+	ok, result, err := Assert(ctx, dbc, expect, AssertOpt{})
+	assert.NoError(t, err)
+	assert.True(t, ok, "%v", result)
+}
+
+func TestAssertUniqueKeys(t *testing.T) {
+	os.Remove("assert_unique_keys_test.db")
+	connStr := "file:assert_unique_keys_test.db?cache=shared&mode=rwc"
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	dbc, err := NewDBConnector(ctx, "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	// no primary key, but code is unique
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE IF NOT EXISTS currency (
+			code TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL
+		);
+
+		INSERT INTO currency (code, name)
+		VALUES ('USD', 'US Dollar'), ('EUR', 'Euro'), ('JPY', 'Yen');
+		`))
+	assert.NoError(t, err)
+
+	expect, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		currency:
+		- { code: JPY, name: Japanese Yen }
+		- { code: USD, name: US Dollar }
+		- { code: EUR, name: Euro }
+		`)))
+	assert.NoError(t, err)
+	ok, result, err := Assert(ctx, dbc, expect, AssertOpt{})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, []string{"code"}, result[0].PrimaryKeys)
+	// rows are paired by the unique key, so only the name of JPY is different
+	var diffs []string
+	for _, row := range result[0].Rows {
+		for _, f := range row.MismatchedFields() {
+			diffs = append(diffs, f.Key+":"+f.Expect.(string))
+		}
+		assert.NotEqual(t, OnlyOnExpect, row.Status)
+		assert.NotEqual(t, OnlyOnActual, row.Status)
+	}
+	assert.Equal(t, []string{"name:Japanese Yen"}, diffs)
+}
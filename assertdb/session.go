@@ -0,0 +1,304 @@
+package assertdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shibukawa/dbtestify"
+)
+
+// Session runs the helpers with a shared DBConnector instead of connecting for each call.
+//
+// It doesn't have any other state, so it can be used from parallel sub tests.
+//
+//	dbc, _ := dbtestify.NewDBConnector(ctx, "sqlite://file:database.db")
+//	s := assertdb.NewSession(dbc)
+//
+//	t.Run("case", func(t *testing.T) {
+//	    s.SeedDataSet(t, dataSet, "initial.yaml", nil)
+//	    s.AssertDB(t, dataSet, "expect.yaml", nil)
+//	})
+type Session struct {
+	dbc dbtestify.DBConnector
+}
+
+// NewSession creates a Session. The caller is responsible for closing the connector.
+func NewSession(dbc dbtestify.DBConnector) *Session {
+	return &Session{dbc: dbc}
+}
+
+// SeedDataSet is the same as the package-level SeedDataSet.
+func (s *Session) SeedDataSet(t testing.TB, folder fs.FS, fileName string, opt *dbtestify.SeedOpt) {
+	t.Helper()
+	data := readDataSet(t, folder, fileName)
+	if data == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	seedWith(t, ctx, s.dbc, data, fileName, opt)
+}
+
+// AssertDB is the same as the package-level AssertDB.
+func (s *Session) AssertDB(t testing.TB, folder fs.FS, fileName string, opt *dbtestify.AssertOpt) {
+	t.Helper()
+	data := readDataSet(t, folder, fileName)
+	if data == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	assertWith(t, ctx, s.dbc, data, fileName, opt)
+}
+
+// AssertDBParallel is the same as the package-level AssertDBParallel.
+func (s *Session) AssertDBParallel(t testing.TB, folder fs.FS, fileNames []string, opt *dbtestify.AssertOpt) {
+	t.Helper()
+	collectors := make([]*collector, len(fileNames))
+	var wg sync.WaitGroup
+	for i, fileName := range fileNames {
+		c := &collector{TB: t}
+		collectors[i] = c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.AssertDB(c, folder, fileName, opt)
+		}()
+	}
+	wg.Wait()
+	for _, c := range collectors {
+		c.report(t)
+	}
+}
+
+// AssertCounts is the same as the package-level AssertCounts.
+func (s *Session) AssertCounts(t testing.TB, folder fs.FS, fileName string, opt *dbtestify.AssertOpt) {
+	t.Helper()
+	data := readDataSet(t, folder, fileName)
+	if data == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	var o dbtestify.AssertOpt
+	if opt != nil {
+		o = *opt
+	}
+	_, result, err := dbtestify.AssertCount(ctx, s.dbc, data, o)
+	if err != nil {
+		t.Fatalf("Failed to assert dataset %s: %v", fileName, err)
+		return
+	}
+	for _, r := range result {
+		if r.Expected != r.Actual {
+			t.Errorf("%s: table %s has %d rows, but expected %d", fileName, r.TableName, r.Actual, r.Expected)
+		}
+	}
+}
+
+// SeedAndAssert is the same as the package-level SeedAndAssert.
+func (s *Session) SeedAndAssert(t testing.TB, folder fs.FS, seedFile, assertFile string, seedOpt *dbtestify.SeedOpt, assertOpt *dbtestify.AssertOpt) {
+	t.Helper()
+	seedData := readDataSet(t, folder, seedFile)
+	if seedData == nil {
+		return
+	}
+	assertData := readDataSet(t, folder, assertFile)
+	if assertData == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	if !seedWith(t, ctx, s.dbc, seedData, seedFile, seedOpt) {
+		return
+	}
+	assertWith(t, ctx, s.dbc, assertData, assertFile, assertOpt)
+}
+
+// SnapshotAndAssert is the same as the package-level SnapshotAndAssert.
+func (s *Session) SnapshotAndAssert(t testing.TB, folder fs.FS, fileName string, tables []string) {
+	t.Helper()
+	_, err := fs.Stat(folder, fileName)
+	if err == nil && !*update {
+		s.AssertDB(t, folder, fileName, nil)
+		return
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Failed to open snapshot %s: %v", fileName, err)
+		return
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	data, err := dbtestify.Snapshot(ctx, s.dbc, tables)
+	if err != nil {
+		t.Fatalf("Failed to take snapshot %s: %v", fileName, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := data.WriteYAML(&buf); err != nil {
+		t.Fatalf("Failed to write snapshot %s: %v", fileName, err)
+		return
+	}
+	if old, err := os.ReadFile(fileName); err == nil && bytes.Equal(old, buf.Bytes()) {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+		t.Fatalf("Failed to create folder for snapshot %s: %v", fileName, err)
+		return
+	}
+	if err := os.WriteFile(fileName, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("Failed to write snapshot %s: %v", fileName, err)
+		return
+	}
+	t.Logf("Snapshot %s is written", fileName)
+}
+
+// AssertRowCount is the same as the package-level AssertRowCount.
+func (s *Session) AssertRowCount(t testing.TB, tableName string, expectedCount int, whereClause string) {
+	t.Helper()
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)
+	if whereClause != "" {
+		query += " WHERE " + whereClause
+	}
+	var count int
+	if err := s.dbc.DB().QueryRowContext(t.Context(), query).Scan(&count); err != nil {
+		t.Fatalf("Failed to count rows of table %s: %v", tableName, err)
+		return
+	}
+	if count != expectedCount {
+		if whereClause != "" {
+			t.Errorf("Row count of table %s (WHERE %s) is %d, but expected %d", tableName, whereClause, count, expectedCount)
+		} else {
+			t.Errorf("Row count of table %s is %d, but expected %d", tableName, count, expectedCount)
+		}
+	}
+}
+
+// AssertOrder is the same as the package-level AssertOrder.
+func (s *Session) AssertOrder(t testing.TB, tableName, orderBy string, expectedIDs []any) {
+	t.Helper()
+	pkeys, err := s.dbc.PrimaryKeys(t.Context(), tableName)
+	if err != nil {
+		t.Fatalf("Failed to get primary keys of table %s: %v", tableName, err)
+		return
+	}
+	if len(pkeys) == 0 {
+		t.Fatalf("Table %s doesn't have primary keys", tableName)
+		return
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", strings.Join(pkeys, ", "), tableName, orderBy)
+	rows, err := s.dbc.DB().QueryContext(t.Context(), query)
+	if err != nil {
+		t.Fatalf("Failed to query table %s: %v", tableName, err)
+		return
+	}
+	defer rows.Close()
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("Failed to get column types of table %s: %v", tableName, err)
+		return
+	}
+	actualIDs := []any{}
+	for rows.Next() {
+		values := make([]any, len(pkeys))
+		ptrs := make([]any, len(pkeys))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			t.Fatalf("Failed to scan row of table %s: %v", tableName, err)
+			return
+		}
+		for i, v := range values {
+			values[i] = dbtestify.NormalizeValue(v, columnTypes[i].DatabaseTypeName())
+		}
+		if len(values) == 1 {
+			actualIDs = append(actualIDs, values[0])
+		} else {
+			actualIDs = append(actualIDs, values)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Failed to read rows of table %s: %v", tableName, err)
+		return
+	}
+	if expectedIDs == nil {
+		expectedIDs = []any{}
+	}
+	if !reflect.DeepEqual(expectedIDs, actualIDs) {
+		t.Errorf("Order of table %s (ORDER BY %s) is different (-expected +actual):\n%s", tableName, orderBy, cmp.Diff(expectedIDs, actualIDs))
+	}
+}
+
+// collector records the results of a helper running in another goroutine.
+//
+// testing.TB.FailNow (and Fatal) can't be called from other goroutines than the test, so the results are reported by the test goroutine later.
+// The diff of the assertion is buffered too, so that the diffs of data sets are not interleaved.
+type collector struct {
+	testing.TB
+	logs   []string
+	errors []string
+	diff   bytes.Buffer
+}
+
+func (c *collector) Log(args ...any) {
+	c.logs = append(c.logs, fmt.Sprint(args...))
+}
+
+func (c *collector) Logf(format string, args ...any) {
+	c.logs = append(c.logs, fmt.Sprintf(format, args...))
+}
+
+func (c *collector) Error(args ...any) {
+	c.errors = append(c.errors, fmt.Sprint(args...))
+}
+
+func (c *collector) Errorf(format string, args ...any) {
+	c.errors = append(c.errors, fmt.Sprintf(format, args...))
+}
+
+func (c *collector) Fatal(args ...any) {
+	c.Error(args...)
+	runtime.Goexit()
+}
+
+func (c *collector) Fatalf(format string, args ...any) {
+	c.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func (c *collector) Fail() {
+	c.errors = append(c.errors, "failed")
+}
+
+func (c *collector) FailNow() {
+	c.Fail()
+	runtime.Goexit()
+}
+
+func (c *collector) Failed() bool {
+	return len(c.errors) > 0
+}
+
+func (c *collector) report(t testing.TB) {
+	t.Helper()
+	for _, l := range c.logs {
+		t.Logf("%s", l)
+	}
+	if c.diff.Len() > 0 {
+		t.Logf("%s", c.diff.String())
+	}
+	for _, e := range c.errors {
+		t.Errorf("%s", e)
+	}
+}
@@ -16,73 +16,463 @@ package assertdb
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"io/fs"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/shibukawa/dbtestify"
 )
 
 // SeedDataSet seeds the database with the data from the specified YAML file.
-func SeedDataSet(t *testing.T, dbConn string, folder fs.FS, fileName string, opt *dbtestify.SeedOpt) {
+func SeedDataSet(t testing.TB, dbConn string, folder fs.FS, fileName string, opt *dbtestify.SeedOpt) {
+	t.Helper()
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
+	}
+	defer dbc.DB().Close()
+	NewSession(dbc).SeedDataSet(t, folder, fileName, opt)
+}
+
+// SeedDataSetEnv is the same as SeedDataSet, but the connection string is read from environment variables.
+//
+// See dbtestify.ConnectionStringFromEnv for the environment variables.
+func SeedDataSetEnv(t testing.TB, folder fs.FS, fileName string, opt *dbtestify.SeedOpt) {
+	t.Helper()
+	dbConn, err := dbtestify.ConnectionStringFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to read DB connection setting: %v", err)
+		return
+	}
+	SeedDataSet(t, dbConn, folder, fileName, opt)
+}
+
+// SeedDataSets seeds the database with the data merged from the specified YAML files.
+//
+// Files are merged in order by dbtestify.DataSet.Merge, so later files can override operations and match strategies of earlier files.
+func SeedDataSets(t testing.TB, dbConn string, folder fs.FS, fileNames []string, opt *dbtestify.SeedOpt) {
+	t.Helper()
+	data, err := dbtestify.ParseYAMLFiles(folder, fileNames...)
+	if err != nil {
+		t.Fatalf("Failed to read dataset: %v", err)
+		return
+	}
+	seed(t, dbConn, data, strings.Join(fileNames, ", "), opt)
+}
+
+func seed(t testing.TB, dbConn string, data *dbtestify.DataSet, name string, opt *dbtestify.SeedOpt) {
+	t.Helper()
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
+	}
+	defer dbc.DB().Close()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	seedWith(t, ctx, dbc, data, name, opt)
+}
+
+// AssertDB asserts the database state against the data from the specified YAML file.
+func AssertDB(t testing.TB, dbConn string, folder fs.FS, fileName string, opt *dbtestify.AssertOpt) {
+	t.Helper()
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
+	}
+	defer dbc.DB().Close()
+	NewSession(dbc).AssertDB(t, folder, fileName, opt)
+}
+
+// AssertDBParallel asserts the database state against the data from the specified YAML files concurrently.
+//
+// Each file is asserted in its own goroutine with a shared DBConnector, and the failures of all files are reported
+// after all assertions finish. It doesn't call t.Parallel, so call it by yourself to run the test in parallel with other tests.
+func AssertDBParallel(t testing.TB, dbConn string, folder fs.FS, fileNames []string, opt *dbtestify.AssertOpt) {
+	t.Helper()
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
+	}
+	defer dbc.DB().Close()
+	NewSession(dbc).AssertDBParallel(t, folder, fileNames, opt)
+}
+
+// AssertDBWithRetry is the same as AssertDB, but it retries the assertion until the database matches the data set.
+//
+// It is for the systems that update the database asynchronously (e.g. event handlers or job queues).
+// It asserts up to maxAttempts times with delay between attempts and logs each failed attempt by t.Logf.
+// The test fails only after all attempts are failed. The whole retry is timed out after maxAttempts * delay * 2.
+func AssertDBWithRetry(t testing.TB, dbConn string, folder fs.FS, fileName string, maxAttempts int, delay time.Duration) {
+	t.Helper()
+	data := readDataSet(t, folder, fileName)
+	if data == nil {
+		return
+	}
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
+	}
+	defer dbc.DB().Close()
+	maxAttempts = max(maxAttempts, 1)
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout := time.Duration(maxAttempts) * delay * 2; timeout > 0 {
+		ctx, cancel = context.WithTimeout(t.Context(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(t.Context())
+	}
+	defer cancel()
+
+	var summary string
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		_, tables, err := dbtestify.Assert(ctx, dbc, data, dbtestify.AssertOpt{})
+		if err != nil && !errors.As(err, &dbtestify.ErrAssertFailed{}) {
+			t.Errorf("Failed to assert dataset %s at attempt %d/%d: %v", fileName, attempt, maxAttempts, err)
+			return
+		}
+		result := dbtestify.NewAssertResult(tables)
+		if result.IsMatch() {
+			return
+		}
+		summary = result.Summary()
+		t.Logf("Attempt %d/%d for dataset %s failed: %s", attempt, maxAttempts, fileName, summary)
+		if attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Errorf("Assertion for dataset %s timed out at attempt %d/%d: %s", fileName, attempt, maxAttempts, summary)
+			return
+		case <-time.After(delay):
+		}
+	}
+	t.Errorf("Assertion failed for dataset %s after %d attempts: %s", fileName, maxAttempts, summary)
+}
+
+// SeedAndAssert seeds the database with seedFile and asserts the database state against assertFile.
+//
+// Both operations share one DBConnector.
+func SeedAndAssert(t testing.TB, dbConn string, folder fs.FS, seedFile, assertFile string, seedOpt *dbtestify.SeedOpt, assertOpt *dbtestify.AssertOpt) {
+	t.Helper()
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
+	}
+	defer dbc.DB().Close()
+	NewSession(dbc).SeedAndAssert(t, folder, seedFile, assertFile, seedOpt, assertOpt)
+}
+
+// SeedAndAssertSame seeds the database with the file and asserts the database state against the same file.
+//
+// It is useful to check that the data set is consistent with the table definitions (e.g. default values or triggers).
+func SeedAndAssertSame(t testing.TB, dbConn string, folder fs.FS, fileName string, seedOpt *dbtestify.SeedOpt, assertOpt *dbtestify.AssertOpt) {
+	t.Helper()
+	SeedAndAssert(t, dbConn, folder, fileName, fileName, seedOpt, assertOpt)
+}
+
+func readDataSet(t testing.TB, folder fs.FS, fileName string) *dbtestify.DataSet {
 	t.Helper()
 	file, err := folder.Open(fileName)
 	if err != nil {
 		t.Fatalf("Failed to open dataset %s: %v", fileName, err)
-		return
+		return nil
 	}
 	defer file.Close()
 	data, err := dbtestify.ParseYAML(file)
 	if err != nil {
 		t.Fatalf("Failed to parse dataset %s: %v", fileName, err)
+		return nil
+	}
+	return data
+}
+
+func connect(t testing.TB, dbConn string) dbtestify.DBConnector {
+	t.Helper()
+	// the connector is closed when ctx is done, so it should live until the end of the test
+	ctx := t.Context()
+	// NewDBConnector pings the database, so unreachable databases are reported here
+	dbc, err := dbtestify.NewDBConnector(ctx, dbConn)
+	if err != nil {
+		t.Fatalf("Failed to create DB connector: %v", err)
+		return nil
+	}
+	return dbc
+}
+
+func seedWith(t testing.TB, ctx context.Context, dbc dbtestify.DBConnector, data *dbtestify.DataSet, name string, opt *dbtestify.SeedOpt) bool {
+	t.Helper()
+	if opt == nil {
+		opt = &dbtestify.SeedOpt{}
+	}
+	err := dbtestify.Seed(ctx, dbc, data, *opt)
+	if err != nil {
+		t.Fatalf("Failed to seed dataset %s: %v", name, err)
+		return false
+	}
+	return true
+}
+
+func assertWith(t testing.TB, ctx context.Context, dbc dbtestify.DBConnector, data *dbtestify.DataSet, name string, opt *dbtestify.AssertOpt) {
+	t.Helper()
+	var o dbtestify.AssertOpt
+	if opt != nil {
+		o = *opt
+	}
+	if c, ok := t.(*collector); ok {
+		// diffs of AssertDBParallel are reported after all data sets finish not to mix them
+		o.DiffCallback = dbtestify.DumpDiffTextCallback(&c.diff, true, true)
+	} else {
+		o.DiffCallback = dbtestify.DumpDiffCLICallback(true, true)
+	}
+	_, tables, err := dbtestify.Assert(ctx, dbc, data, o)
+	// FailFast returns ErrAssertFailed with the result until the first mismatch
+	if err != nil && !errors.As(err, &dbtestify.ErrAssertFailed{}) {
+		t.Fatalf("Failed to assert dataset %s: %v", name, err)
+		return
+	}
+	if result := dbtestify.NewAssertResult(tables); !result.IsMatch() {
+		t.Errorf("Assertion failed for dataset %s: %s", name, result.Summary())
+	}
+}
+
+// AssertCounts asserts only the number of rows of each table against the data from the specified YAML file.
+//
+// It is the helper of dbtestify.AssertCount.
+func AssertCounts(t testing.TB, dbConn string, folder fs.FS, fileName string, opt *dbtestify.AssertOpt) {
+	t.Helper()
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
+	}
+	defer dbc.DB().Close()
+	NewSession(dbc).AssertCounts(t, folder, fileName, opt)
+}
+
+// AssertDBColumns asserts the database state like AssertDB, but only the specified columns (and primary keys) are compared.
+//
+// Other columns are set to dbtestify.AssertOpt.IgnoreColumns. Each mismatch is reported with the table, row and column.
+func AssertDBColumns(t testing.TB, dbConn string, folder fs.FS, fileName string, columns []string) {
+	t.Helper()
+	data := readDataSet(t, folder, fileName)
+	if data == nil {
 		return
 	}
 	ctx, cancel := context.WithCancel(t.Context())
 	defer cancel()
-	dbc, err := dbtestify.NewDBConnector(ctx, dbConn)
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
+	}
+	defer dbc.DB().Close()
+	opt := dbtestify.AssertOpt{
+		IgnoreColumns: map[string][]string{},
+	}
+	for _, table := range data.Tables {
+		columnTypes, err := dbc.ColumnTypes(ctx, qualifyTable(data.DefaultSchema, table.Name))
+		if err != nil {
+			t.Fatalf("Failed to read column types of table %s: %v", table.Name, err)
+			return
+		}
+		for _, c := range columnTypes {
+			// some databases return the column names in upper case
+			if !slices.ContainsFunc(columns, func(column string) bool { return strings.EqualFold(column, c.Name) }) {
+				opt.IgnoreColumns[table.Name] = append(opt.IgnoreColumns[table.Name], c.Name)
+			}
+		}
+	}
+	_, result, err := dbtestify.Assert(ctx, dbc, data, opt)
 	if err != nil {
-		t.Fatalf("Failed to parse dataset %s: %v", fileName, err)
+		t.Fatalf("Failed to assert dataset %s: %v", fileName, err)
 		return
 	}
-	if opt == nil {
-		opt = &dbtestify.SeedOpt{}
+	for _, table := range result {
+		pkeyCount := len(table.PrimaryKeys)
+		for _, row := range table.Rows {
+			var keys []string
+			for _, f := range row.Fields[:min(pkeyCount, len(row.Fields))] {
+				v := f.Expect
+				if row.Status == dbtestify.OnlyOnActual {
+					v = f.Actual
+				}
+				keys = append(keys, fmt.Sprintf("%s=%v", f.Key, v))
+			}
+			rowName := strings.Join(keys, ", ")
+			switch row.Status {
+			case dbtestify.OnlyOnExpect:
+				t.Errorf("%s: table %s: row (%s) is not found", fileName, table.Name, rowName)
+			case dbtestify.OnlyOnActual:
+				t.Errorf("%s: table %s: row (%s) is not expected", fileName, table.Name, rowName)
+			case dbtestify.Truncated:
+				t.Errorf("%s: table %s: %s more rows are different", fileName, table.Name, row.Fields[0].Key)
+			case dbtestify.NotMatch:
+				for _, f := range row.Fields[pkeyCount:] {
+					switch f.Status {
+					case dbtestify.NotMatch:
+						t.Errorf("%s: table %s: row (%s): column %s is '%v', but expected '%v'", fileName, table.Name, rowName, f.Key, f.Actual, f.Expect)
+					case dbtestify.WrongDataSet:
+						t.Errorf("%s: table %s: row (%s): column %s is not found", fileName, table.Name, rowName, f.Key)
+					}
+				}
+			}
+		}
+	}
+}
+
+// qualifyTable adds the default schema of the data set to the table name as dbtestify.Assert does.
+func qualifyTable(schema, tableName string) string {
+	if schema == "" || strings.Contains(tableName, ".") {
+		return tableName
+	}
+	return schema + "." + tableName
+}
+
+// AssertRowCount asserts the number of rows in the table. If whereClause is not empty, it is used as WHERE clause verbatim.
+//
+//	assertdb.AssertRowCount(t, "sqlite://file:database.db", "user", 1, "name = 'Frank'")
+func AssertRowCount(t testing.TB, dbConn string, tableName string, expectedCount int, whereClause string) {
+	t.Helper()
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
+	}
+	defer dbc.DB().Close()
+	NewSession(dbc).AssertRowCount(t, tableName, expectedCount, whereClause)
+}
+
+// AssertOrder asserts the order of rows in the table sorted by orderBy (used as ORDER BY clause verbatim).
+//
+// The primary keys of the rows are compared with expectedIDs. If the table has multiple primary keys,
+// each item of expectedIDs is []any of the keys sorted by the column names.
+//
+//	assertdb.AssertOrder(t, "sqlite://file:database.db", "task", "created_at DESC", []any{3, 1, 2})
+//	assertdb.AssertOrder(t, "sqlite://file:database.db", "order_items", "price", []any{[]any{1, 100}, []any{1, 101}})
+func AssertOrder(t testing.TB, dbConn string, tableName, orderBy string, expectedIDs []any) {
+	t.Helper()
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
 	}
-	err = dbtestify.Seed(ctx, dbc, data, *opt)
+	defer dbc.DB().Close()
+	NewSession(dbc).AssertOrder(t, tableName, orderBy, expectedIDs)
+}
+
+// AssertDBEnv is the same as AssertDB, but the connection string is read from environment variables.
+//
+// See dbtestify.ConnectionStringFromEnv for the environment variables.
+func AssertDBEnv(t testing.TB, folder fs.FS, fileName string, opt *dbtestify.AssertOpt) {
+	t.Helper()
+	dbConn, err := dbtestify.ConnectionStringFromEnv()
 	if err != nil {
-		t.Fatalf("Failed to seed dataset %s: %v", fileName, err)
+		t.Fatalf("Failed to read DB connection setting: %v", err)
+		return
 	}
+	AssertDB(t, dbConn, folder, fileName, opt)
 }
 
-// AssertDB asserts the database state against the data from the specified YAML file.
-func AssertDB(t *testing.T, dbConn string, folder fs.FS, fileName string, opt *dbtestify.AssertOpt) {
+// AssertSchema asserts the column types of the database against the expected schema from the specified YAML file.
+//
+//	user:
+//	  id: { type: integer, nullable: false }
+//	  name: { type: varchar, nullable: false, max_length: 100 }
+func AssertSchema(t testing.TB, dbConn string, folder fs.FS, schemaFile string) {
 	t.Helper()
-	file, err := folder.Open(fileName)
+	file, err := folder.Open(schemaFile)
 	if err != nil {
-		t.Fatalf("Failed to open dataset %s: %v", fileName, err)
+		t.Fatalf("Failed to open schema %s: %v", schemaFile, err)
 		return
 	}
 	defer file.Close()
-	data, err := dbtestify.ParseYAML(file)
+	schema, err := dbtestify.ParseSchemaYAML(file)
 	if err != nil {
-		t.Fatalf("Failed to parse dataset %s: %v", fileName, err)
+		t.Fatalf("Failed to parse schema %s: %v", schemaFile, err)
 		return
 	}
 	ctx, cancel := context.WithCancel(t.Context())
 	defer cancel()
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
+	}
+	defer dbc.DB().Close()
+	diffs, err := dbtestify.AssertSchema(ctx, dbc, schema)
+	if err != nil {
+		t.Fatalf("Failed to assert schema %s: %v", schemaFile, err)
+		return
+	}
+	for _, d := range diffs {
+		t.Errorf("Schema mismatch in %s: %s", schemaFile, d)
+	}
+}
+
+var update = flag.Bool("update", false, "update snapshot files of assertdb.SnapshotAndAssert")
+
+// SnapshotAndAssert asserts the database state against the snapshot file in the folder.
+//
+// If the file doesn't exist or the test runs with `-update` flag, it takes a snapshot of the tables
+// and writes it to fileName (relative to the package directory) instead of asserting.
+//
+//	//go:embed testdata/*
+//	var snapshots embed.FS
+//
+//	assertdb.SnapshotAndAssert(t, "sqlite://file:database.db", snapshots, "testdata/expect.yaml", []string{"user"})
+func SnapshotAndAssert(t testing.TB, dbConn string, folder fs.FS, fileName string, tables []string) {
+	t.Helper()
+	dbc := connect(t, dbConn)
+	if dbc == nil {
+		return
+	}
+	defer dbc.DB().Close()
+	NewSession(dbc).SnapshotAndAssert(t, folder, fileName, tables)
+}
+
+// CleanUp truncates the tables when the test and all its sub tests finish.
+//
+// The tables are truncated by t.Cleanup, so it runs even if the test fails or panics.
+//
+//	assertdb.SeedDataSet(t, "sqlite://file:database.db", dataSet, "initial.yaml", nil)
+//	assertdb.CleanUp(t, "sqlite://file:database.db", []string{"user", "order"})
+func CleanUp(t testing.TB, dbConn string, tables []string) {
+	t.Helper()
+	t.Cleanup(func() {
+		truncateTables(t, dbConn, func(ctx context.Context, dbc dbtestify.DBConnector) ([]string, error) {
+			return tables, nil
+		})
+	})
+}
+
+// CleanUpAll is the same as CleanUp, but it truncates all tables returned by dbtestify.DBConnector.TableNames.
+func CleanUpAll(t testing.TB, dbConn string) {
+	t.Helper()
+	t.Cleanup(func() {
+		truncateTables(t, dbConn, func(ctx context.Context, dbc dbtestify.DBConnector) ([]string, error) {
+			return dbc.TableNames(ctx)
+		})
+	})
+}
+
+func truncateTables(t testing.TB, dbConn string, tableNames func(ctx context.Context, dbc dbtestify.DBConnector) ([]string, error)) {
+	t.Helper()
+	// t.Context() is already canceled when cleanup functions are called
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 	dbc, err := dbtestify.NewDBConnector(ctx, dbConn)
 	if err != nil {
 		t.Fatalf("Failed to create DB connector: %v", err)
 		return
 	}
-	if opt == nil {
-		opt = &dbtestify.AssertOpt{}
-	}
-	opt.DiffCallback = dbtestify.DumpDiffCLICallback(true, true)
-	ok, _, err := dbtestify.Assert(ctx, dbc, data, *opt)
+	defer dbc.DB().Close()
+	tables, err := tableNames(ctx, dbc)
 	if err != nil {
-		t.Fatalf("Failed to assert dataset %s: %v", fileName, err)
+		t.Fatalf("Failed to read table names: %v", err)
 		return
 	}
-	if !ok {
-		t.Errorf("Assertion failed for dataset %s", fileName)
+	// children are truncated before their parents. PostgreSQL truncates them in one statement without disabling foreign keys
+	if err := dbtestify.TruncateTables(ctx, dbc, tables); err != nil {
+		t.Fatalf("Failed to clean up tables %s: %v", strings.Join(tables, ", "), err)
 	}
 }
@@ -1,10 +1,20 @@
 package dbtestify
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/joho/godotenv"
 )
 
 var okC = color.New(color.FgGreen).SprintFunc()
@@ -15,109 +25,378 @@ var actualTC = color.New(color.BgRed, color.FgBlack).SprintfFunc()
 var expectLC = color.New(color.FgGreen).SprintfFunc()
 var expectTC = color.New(color.BgGreen, color.FgBlack).SprintfFunc()
 var nameC = color.New(color.FgBlue, color.Bold).SprintfFunc()
+var infoC = color.New(color.FgYellow).SprintfFunc()
+var errC = color.New(color.FgRed).SprintFunc()
+var deleteTaskC = color.New(color.FgHiRed).SprintFunc()
+var insertTaskC = color.New(color.FgHiBlue).SprintFunc()
+
+// ErrTimeout is returned by TimeoutError when the operation doesn't finish within Timeout.
+type ErrTimeout struct {
+	Timeout time.Duration
+}
+
+func (e ErrTimeout) Error() string {
+	return fmt.Sprintf("timed out after %s", e.Timeout)
+}
+
+// Unwrap returns context.DeadlineExceeded, so errors.Is(err, context.DeadlineExceeded) is true.
+func (e ErrTimeout) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// TimeoutError returns ErrTimeout instead of err if the deadline of ctx is exceeded, otherwise it returns err as is.
+//
+// Some database drivers don't wrap context.DeadlineExceeded in their errors, so ctx is checked too.
+func TimeoutError(ctx context.Context, err error, timeout time.Duration) error {
+	if err != nil && (errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+		return ErrTimeout{Timeout: timeout}
+	}
+	return err
+}
+
+// EnvFileArgs returns the values of `--env-file` flags in the command line arguments.
+//
+// Environment variables like DBTESTIFY_CONN are read while parsing the arguments, so the env files should be found before that.
+func EnvFileArgs(args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			break
+		}
+		if v, ok := strings.CutPrefix(args[i], "--env-file="); ok {
+			result = append(result, v)
+		} else if args[i] == "--env-file" && i+1 < len(args) {
+			result = append(result, args[i+1])
+			i++
+		}
+	}
+	return result
+}
+
+// LoadEnvFiles sets the environment variables from the env files. Later files override the variables of earlier files and the existing environment variables.
+//
+// If no file is given, `.env` is loaded if it exists and it doesn't override the existing environment variables.
+func LoadEnvFiles(files ...string) error {
+	if len(files) == 0 {
+		if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return godotenv.Overload(files...)
+}
 
 func DumpDiffCLICallback(showTableName, quiet bool) func(result AssertTableResult) {
+	return DumpDiffTextCallback(os.Stdout, showTableName, quiet)
+}
+
+// DumpDiffTextCallback is the same as DumpDiffCLICallback, but it writes the diff to w.
+func DumpDiffTextCallback(w io.Writer, showTableName, quiet bool) func(result AssertTableResult) {
 	return func(result AssertTableResult) {
-		if showTableName {
-			fmt.Print(nameC("Table: %s\n", result.Name))
+		dumpDiffText(w, result, showTableName, quiet)
+	}
+}
+
+// SeedProgressCLICallback returns a callback for SeedOpt.Callback that writes each task like "importing: 'user' ... OK (1.2ms)".
+//
+// If parallel is true, a task is written in one line when it finishes, because tasks of other tables run at the same time.
+// The callback can be called from multiple goroutines.
+func SeedProgressCLICallback(w io.Writer, parallel bool) func(targetTable, task string, start bool, err error) {
+	var m sync.Mutex
+	startTimes := map[string]time.Time{}
+	return func(targetTable, task string, start bool, err error) {
+		m.Lock()
+		defer m.Unlock()
+		var label string
+		switch task {
+		case "truncate":
+			label = deleteTaskC("truncating")
+		case "insert", "insert-ignore":
+			label = insertTaskC("importing")
+		case "upsert":
+			label = insertTaskC("upserting")
+		case "delete":
+			label = deleteTaskC("deleting")
+		default:
+			label = task
 		}
-		if result.Status == Match {
-			if !quiet {
-				fmt.Printf(" %s\n", okC("OK"))
+		key := task + " " + targetTable
+		if start {
+			startTimes[key] = time.Now()
+			if !parallel {
+				fmt.Fprintf(w, "%s: '%s' ...", label, nameC("%s", targetTable))
 			}
+			return
+		}
+		if parallel {
+			fmt.Fprintf(w, "%s: '%s' ...", label, nameC("%s", targetTable))
+		}
+		if err != nil {
+			fmt.Fprintf(w, " %s\n    %s\n", errC("NG"), errC(err.Error()))
 		} else {
-			fmt.Print(expectLC("- Expected\n"))
-			fmt.Print(actualLC("+ Actual\n"))
+			fmt.Fprintf(w, " %s (%s)\n", okC("OK"), infoC("%s", time.Since(startTimes[key])))
+		}
+		delete(startTimes, key)
+	}
+}
+
+// DumpDiffJSONCallback returns a callback that collects the results for AssertOpt.DiffCallback,
+// and a function that writes them to w as a JSON array. Call flush after Assert finishes.
+//
+// It contains the same information as DumpDiffCLICallback: table name, primary keys, status and different rows.
+func DumpDiffJSONCallback(w io.Writer) (callback func(result AssertTableResult), flush func() error) {
+	results := []AssertTableResult{}
+	callback = func(result AssertTableResult) {
+		results = append(results, result)
+	}
+	flush = func() error {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(results)
+	}
+	return callback, flush
+}
+
+var markdownCellEscaper = strings.NewReplacer("|", "\\|", "\n", "<br>")
 
+// DumpDiffMarkdownCallback returns a callback for AssertOpt.DiffCallback that writes each result as GitHub Flavored Markdown table.
+//
+// It is for job summaries of CI services (e.g. $GITHUB_STEP_SUMMARY). Each row starts with ✅ or ❌,
+// and mismatched expected values are shown with ~~strikethrough~~ followed by actual values.
+func DumpDiffMarkdownCallback(w io.Writer) func(result AssertTableResult) {
+	return func(result AssertTableResult) {
+		if result.Status == Match {
+			fmt.Fprintf(w, "### ✅ %s\n\n", result.Name)
+		} else {
+			fmt.Fprintf(w, "### ❌ %s\n\n", result.Name)
+		}
+		var header []Diff
+		truncated := ""
+		for _, r := range result.Rows {
+			if r.Status == Truncated {
+				truncated = r.Fields[0].Key
+			} else if header == nil {
+				header = r.Fields
+			}
+		}
+		if header != nil {
+			fmt.Fprint(w, "| status |")
+			for _, f := range header {
+				fmt.Fprintf(w, " %s |", markdownCell(f.Key))
+			}
+			fmt.Fprint(w, "\n|---|")
+			for range header {
+				fmt.Fprint(w, "---|")
+			}
+			fmt.Fprint(w, "\n")
+		}
+		for _, r := range result.Rows {
+			if r.Status == Truncated {
+				continue
+			}
+			switch r.Status {
+			case Match:
+				fmt.Fprint(w, "| ✅ |")
+			case NotMatch:
+				fmt.Fprint(w, "| ❌ |")
+			case OnlyOnExpect:
+				fmt.Fprint(w, "| ❌ expected only |")
+			case OnlyOnActual:
+				fmt.Fprint(w, "| ❌ actual only |")
+			}
+			for _, f := range r.Fields {
+				switch {
+				case r.Status == OnlyOnExpect:
+					fmt.Fprintf(w, " %s |", markdownCell(f.Expect))
+				case r.Status == OnlyOnActual:
+					fmt.Fprintf(w, " %s |", markdownCell(f.Actual))
+				case f.Status == NotMatch:
+					fmt.Fprintf(w, " ~~%s~~ %s |", markdownCell(f.Expect), markdownCell(f.Actual))
+				default:
+					fmt.Fprintf(w, " %s |", markdownCell(f.Expect))
+				}
+			}
+			fmt.Fprint(w, "\n")
+		}
+		if header != nil {
+			fmt.Fprint(w, "\n")
+		}
+		if truncated != "" {
+			fmt.Fprintf(w, "_... %s more different rows are omitted_\n\n", truncated)
+		}
+	}
+}
+
+func markdownCell(v any) string {
+	return markdownCellEscaper.Replace(fmt.Sprintf("%v", v))
+}
+
+var htmlRowClass = map[AssertStatus]string{
+	Match:        "dbtestify-match",
+	NotMatch:     "dbtestify-notmatch",
+	OnlyOnExpect: "dbtestify-only-expect",
+	OnlyOnActual: "dbtestify-only-actual",
+}
+
+// DumpDiffHTMLCallback returns a callback for AssertOpt.DiffCallback that writes each result as HTML <table> element.
+//
+// The output is a fragment to embed in HTML test reports. Each <tr> has a CSS class for its status
+// (dbtestify-match, dbtestify-notmatch, dbtestify-only-expect, dbtestify-only-actual), and
+// mismatched fields are highlighted inline: expected value in green and actual value in red.
+func DumpDiffHTMLCallback(w io.Writer) func(result AssertTableResult) {
+	return func(result AssertTableResult) {
+		fmt.Fprintf(w, "<table class=\"dbtestify\">\n<caption>%s (%s)</caption>\n", html.EscapeString(result.Name), result.Status)
+		var header []Diff
+		truncated := ""
+		for _, r := range result.Rows {
+			if r.Status == Truncated {
+				truncated = r.Fields[0].Key
+			} else if header == nil {
+				header = r.Fields
+			}
+		}
+		if header != nil {
+			fmt.Fprint(w, "<thead><tr><th>status</th>")
+			for _, f := range header {
+				fmt.Fprintf(w, "<th>%s</th>", html.EscapeString(f.Key))
+			}
+			fmt.Fprint(w, "</tr></thead>\n<tbody>\n")
 			for _, r := range result.Rows {
-				for i := range result.PrimaryKeys {
-					fmt.Print(pkeyL("%s", r.Fields[i].Key))
-					if r.Status == OnlyOnActual {
-						fmt.Print(pkeyV(": %v", r.Fields[i].Actual))
-					} else {
-						fmt.Print(pkeyV(": %v", r.Fields[i].Expect))
+				if r.Status == Truncated {
+					continue
+				}
+				fmt.Fprintf(w, "<tr class=\"%s\"><td>%s</td>", htmlRowClass[r.Status], r.Status)
+				for _, f := range r.Fields {
+					switch {
+					case r.Status == OnlyOnExpect:
+						fmt.Fprintf(w, "<td>%s</td>", htmlCell(f.Expect))
+					case r.Status == OnlyOnActual:
+						fmt.Fprintf(w, "<td>%s</td>", htmlCell(f.Actual))
+					case f.Status == NotMatch:
+						fmt.Fprintf(w, "<td><span style=\"background-color:#cfc\">%s</span> <span style=\"background-color:#fcc\">%s</span></td>", htmlCell(f.Expect), htmlCell(f.Actual))
+					default:
+						fmt.Fprintf(w, "<td>%s</td>", htmlCell(f.Expect))
+					}
+				}
+				fmt.Fprint(w, "</tr>\n")
+			}
+			fmt.Fprint(w, "</tbody>\n")
+			if truncated != "" {
+				fmt.Fprintf(w, "<tfoot><tr><td colspan=\"%d\">... %s more different rows are omitted</td></tr></tfoot>\n", len(header)+1, html.EscapeString(truncated))
+			}
+		}
+		fmt.Fprint(w, "</table>\n")
+	}
+}
+
+func htmlCell(v any) string {
+	return html.EscapeString(fmt.Sprintf("%v", v))
+}
+
+func dumpDiffText(w io.Writer, result AssertTableResult, showTableName, quiet bool) {
+	if showTableName {
+		fmt.Fprint(w, nameC("Table: %s\n", result.Name))
+	}
+	if result.Status == Match {
+		if !quiet {
+			fmt.Fprintf(w, " %s\n", okC("OK"))
+		}
+	} else {
+		fmt.Fprint(w, expectLC("- Expected\n"))
+		fmt.Fprint(w, actualLC("+ Actual\n"))
+
+		for _, r := range result.Rows {
+			if r.Status == Truncated {
+				fmt.Fprint(w, infoC("... %s more different rows are omitted\n", r.Fields[0].Key))
+				continue
+			}
+			for i := range result.PrimaryKeys {
+				fmt.Fprint(w, pkeyL("%s", r.Fields[i].Key))
+				if r.Status == OnlyOnActual {
+					fmt.Fprint(w, pkeyV(": %v", r.Fields[i].Actual))
+				} else {
+					fmt.Fprint(w, pkeyV(": %v", r.Fields[i].Expect))
+				}
+				if i+1 == len(result.PrimaryKeys) {
+					fmt.Fprint(w, "\n")
+				} else {
+					fmt.Fprint(w, pkeyV(", "))
+				}
+			}
+			switch r.Status {
+			case Match:
+				fmt.Fprintf(w, "  ")
+				for i, f := range r.Fields {
+					if i < len(result.PrimaryKeys) {
+						continue
+					}
+					if i != len(result.PrimaryKeys) {
+						fmt.Fprintf(w, ", ")
+					}
+					fmt.Fprintf(w, "%s: %v", f.Key, f.Expect)
+				}
+			case NotMatch:
+				fmt.Fprint(w, expectTC("+")+" ")
+				for i, f := range r.Fields {
+					if i < len(result.PrimaryKeys) {
+						continue
+					}
+					if i != len(result.PrimaryKeys) {
+						fmt.Fprint(w, expectLC(", "))
 					}
-					if i+1 == len(result.PrimaryKeys) {
-						fmt.Print("\n")
+					if f.Status == Match {
+						fmt.Fprint(w, expectLC("%s: %v", f.Key, f.Expect))
 					} else {
-						fmt.Print(pkeyV(", "))
+						fmt.Fprint(w, expectLC("%s: ", f.Key))
+						e := fmt.Sprintf("%v", f.Expect)
+						a := fmt.Sprintf("%v", f.Actual)
+						fmt.Fprint(w, expectTC(e))
+						fmt.Fprint(w, strings.Repeat(" ", max(len(a)-len(e), 0)))
 					}
 				}
-				switch r.Status {
-				case Match:
-					fmt.Printf("  ")
-					for i, f := range r.Fields {
-						if i < len(result.PrimaryKeys) {
-							continue
-						}
-						if i != len(result.PrimaryKeys) {
-							fmt.Printf(", ")
-						}
-						fmt.Printf("%s: %v", f.Key, f.Expect)
+				fmt.Fprint(w, "\n"+actualTC("-")+" ")
+				for i, f := range r.Fields {
+					if i < len(result.PrimaryKeys) {
+						continue
 					}
-				case NotMatch:
-					fmt.Print(expectTC("+") + " ")
-					for i, f := range r.Fields {
-						if i < len(result.PrimaryKeys) {
-							continue
-						}
-						if i != len(result.PrimaryKeys) {
-							fmt.Print(expectLC(", "))
-						}
-						if f.Status == Match {
-							fmt.Print(expectLC("%s: %v", f.Key, f.Expect))
-						} else {
-							fmt.Print(expectLC("%s: ", f.Key))
-							e := fmt.Sprintf("%v", f.Expect)
-							a := fmt.Sprintf("%v", f.Actual)
-							fmt.Print(expectTC(e))
-							fmt.Print(strings.Repeat(" ", max(len(a)-len(e), 0)))
-						}
+					if i != len(result.PrimaryKeys) {
+						fmt.Fprint(w, actualLC(", "))
 					}
-					fmt.Print("\n" + actualTC("-") + " ")
-					for i, f := range r.Fields {
-						if i < len(result.PrimaryKeys) {
-							continue
-						}
-						if i != len(result.PrimaryKeys) {
-							fmt.Print(actualLC(", "))
-						}
-						if f.Status == Match {
-							fmt.Print(actualLC("%s: %v", f.Key, f.Actual))
-						} else {
-							fmt.Print(actualLC("%s: ", f.Key))
-							e := fmt.Sprintf("%v", f.Expect)
-							a := fmt.Sprintf("%v", f.Actual)
-							fmt.Print(actualTC("%v", f.Actual))
-							fmt.Print(strings.Repeat(" ", max(len(e)-len(a), 0)))
-						}
+					if f.Status == Match {
+						fmt.Fprint(w, actualLC("%s: %v", f.Key, f.Actual))
+					} else {
+						fmt.Fprint(w, actualLC("%s: ", f.Key))
+						e := fmt.Sprintf("%v", f.Expect)
+						a := fmt.Sprintf("%v", f.Actual)
+						fmt.Fprint(w, actualTC("%v", f.Actual))
+						fmt.Fprint(w, strings.Repeat(" ", max(len(e)-len(a), 0)))
+					}
+				}
+			case OnlyOnExpect:
+				fmt.Fprint(w, expectTC("+")+" ")
+				for i, f := range r.Fields {
+					if i < len(result.PrimaryKeys) {
+						continue
 					}
-				case OnlyOnExpect:
-					fmt.Print(expectTC("+") + " ")
-					for i, f := range r.Fields {
-						if i < len(result.PrimaryKeys) {
-							continue
-						}
-						if i != len(result.PrimaryKeys) {
-							fmt.Print(expectLC(", "))
-						}
-						fmt.Print(expectTC("%s: %v", f.Key, f.Expect))
+					if i != len(result.PrimaryKeys) {
+						fmt.Fprint(w, expectLC(", "))
+					}
+					fmt.Fprint(w, expectTC("%s: %v", f.Key, f.Expect))
+				}
+			case OnlyOnActual:
+				fmt.Fprint(w, actualTC("-")+" ")
+				for i, f := range r.Fields {
+					if i < len(result.PrimaryKeys) {
+						continue
 					}
-				case OnlyOnActual:
-					fmt.Print(actualTC("-") + " ")
-					for i, f := range r.Fields {
-						if i < len(result.PrimaryKeys) {
-							continue
-						}
-						if i != len(result.PrimaryKeys) {
-							fmt.Print(actualLC(", "))
-						}
-						fmt.Print(actualTC("%s: %v", f.Key, f.Actual))
+					if i != len(result.PrimaryKeys) {
+						fmt.Fprint(w, actualLC(", "))
 					}
+					fmt.Fprint(w, actualTC("%s: %v", f.Key, f.Actual))
 				}
-				fmt.Print("\n")
 			}
-			fmt.Print("\n")
+			fmt.Fprint(w, "\n")
 		}
+		fmt.Fprint(w, "\n")
 	}
 }
@@ -0,0 +1,384 @@
+package dbtestify
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/fatih/color"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// diffResultForDump covers all row statuses.
+var diffResultForDump = []AssertTableResult{
+	{
+		Name:        "user",
+		PrimaryKeys: []string{"id"},
+		Status:      NotMatch,
+		Rows: []RowDiff{
+			{Status: Match, Fields: []Diff{
+				{Key: "id", Expect: 1, Actual: 1, Status: Match},
+				{Key: "name", Expect: "Alice", Actual: "Alice", Status: Match},
+			}},
+			{Status: NotMatch, Fields: []Diff{
+				{Key: "id", Expect: 2, Actual: 2, Status: Match},
+				{Key: "name", Expect: "Bob", Actual: "Robert", Status: NotMatch},
+			}},
+			{Status: OnlyOnExpect, Fields: []Diff{
+				{Key: "id", Expect: 3, Status: OnlyOnExpect},
+				{Key: "name", Expect: "Carol", Status: OnlyOnExpect},
+			}},
+			{Status: OnlyOnActual, Fields: []Diff{
+				{Key: "id", Actual: 4, Status: OnlyOnActual},
+				{Key: "name", Actual: "Dave", Status: OnlyOnActual},
+			}},
+			{Status: Truncated, Fields: []Diff{
+				{Key: "5"},
+			}},
+		},
+	},
+	{
+		Name:        "tag",
+		PrimaryKeys: []string{"name"},
+		Status:      Match,
+	},
+}
+
+func TestDumpDiffText(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	var buf bytes.Buffer
+	for _, r := range diffResultForDump {
+		dumpDiffText(&buf, r, true, false)
+	}
+	// expected value of not-matched field is padded to the width of actual value
+	assert.Equal(t, strings.Join([]string{
+		"Table: user",
+		"- Expected",
+		"+ Actual",
+		"id: 1",
+		"  name: Alice",
+		"id: 2",
+		"+ name: Bob   ",
+		"- name: Robert",
+		"id: 3",
+		"+ name: Carol",
+		"id: 4",
+		"- name: Dave",
+		"... 5 more different rows are omitted",
+		"",
+		"Table: tag",
+		" OK",
+		"",
+	}, "\n"), buf.String())
+}
+
+func TestDumpDiffJSON(t *testing.T) {
+	var buf bytes.Buffer
+	callback, flush := DumpDiffJSONCallback(&buf)
+	for _, r := range diffResultForDump {
+		callback(r)
+	}
+	assert.NoError(t, flush())
+
+	var got []map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, 2, len(got))
+	assert.Equal(t, map[string]any{
+		"name":         "tag",
+		"primary_keys": []any{"name"},
+		"rows":         nil,
+		"status":       "match",
+	}, got[1])
+
+	user := got[0]
+	assert.Equal(t, "user", user["name"])
+	assert.Equal(t, any([]any{"id"}), user["primary_keys"])
+	assert.Equal(t, "not-match", user["status"])
+	assert.Equal(t, any([]any{
+		map[string]any{"status": "match", "fields": []any{
+			map[string]any{"key": "id", "expect": 1.0, "actual": 1.0, "status": "match"},
+			map[string]any{"key": "name", "expect": "Alice", "actual": "Alice", "status": "match"},
+		}},
+		map[string]any{"status": "not-match", "fields": []any{
+			map[string]any{"key": "id", "expect": 2.0, "actual": 2.0, "status": "match"},
+			map[string]any{"key": "name", "expect": "Bob", "actual": "Robert", "status": "not-match"},
+		}},
+		map[string]any{"status": "only-e", "fields": []any{
+			map[string]any{"key": "id", "expect": 3.0, "actual": nil, "status": "only-e"},
+			map[string]any{"key": "name", "expect": "Carol", "actual": nil, "status": "only-e"},
+		}},
+		map[string]any{"status": "only-a", "fields": []any{
+			map[string]any{"key": "id", "expect": nil, "actual": 4.0, "status": "only-a"},
+			map[string]any{"key": "name", "expect": nil, "actual": "Dave", "status": "only-a"},
+		}},
+		map[string]any{"status": "truncated", "fields": []any{
+			map[string]any{"key": "5", "expect": nil, "actual": nil, "status": ""},
+		}},
+	}), user["rows"])
+
+	t.Run("empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, flush := DumpDiffJSONCallback(&buf)
+		assert.NoError(t, flush())
+		assert.Equal(t, "[]\n", buf.String())
+	})
+}
+
+func TestDumpDiffMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	callback := DumpDiffMarkdownCallback(&buf)
+	for _, r := range diffResultForDump {
+		callback(r)
+	}
+	assert.Equal(t, TrimIndent(t, `
+		### ❌ user
+
+		| status | id | name |
+		|---|---|---|
+		| ✅ | 1 | Alice |
+		| ❌ | 2 | ~~Bob~~ Robert |
+		| ❌ expected only | 3 | Carol |
+		| ❌ actual only | 4 | Dave |
+
+		_... 5 more different rows are omitted_
+
+		### ✅ tag
+		`)+"\n\n", buf.String())
+
+	t.Run("escape", func(t *testing.T) {
+		var buf bytes.Buffer
+		DumpDiffMarkdownCallback(&buf)(AssertTableResult{
+			Name:        "memo",
+			PrimaryKeys: []string{"id"},
+			Status:      NotMatch,
+			Rows: []RowDiff{
+				{Status: NotMatch, Fields: []Diff{
+					{Key: "id", Expect: 1, Actual: 1, Status: Match},
+					{Key: "body", Expect: "a|b", Actual: "a\nb", Status: NotMatch},
+				}},
+			},
+		})
+		assert.Contains(t, buf.String(), "| ❌ | 1 | ~~a\\|b~~ a<br>b |\n")
+	})
+}
+
+func TestDumpDiffHTML(t *testing.T) {
+	var buf bytes.Buffer
+	callback := DumpDiffHTMLCallback(&buf)
+	for _, r := range diffResultForDump {
+		callback(r)
+	}
+
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(bytes.NewReader(buf.Bytes()), body)
+	assert.NoError(t, err)
+
+	var tables int
+	var rowClasses []string
+	var highlighted []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Table:
+				tables++
+			case atom.Tr:
+				for _, a := range n.Attr {
+					if a.Key == "class" {
+						rowClasses = append(rowClasses, a.Val)
+					}
+				}
+			case atom.Span:
+				highlighted = append(highlighted, n.Attr[0].Val+":"+n.FirstChild.Data)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	assert.Equal(t, 2, tables)
+	assert.Equal(t, []string{"dbtestify-match", "dbtestify-notmatch", "dbtestify-only-expect", "dbtestify-only-actual"}, rowClasses)
+	assert.Equal(t, []string{"background-color:#cfc:Bob", "background-color:#fcc:Robert"}, highlighted)
+	assert.Contains(t, buf.String(), "5 more different rows are omitted")
+
+	t.Run("escape", func(t *testing.T) {
+		var buf bytes.Buffer
+		DumpDiffHTMLCallback(&buf)(AssertTableResult{
+			Name:        "memo",
+			PrimaryKeys: []string{"id"},
+			Status:      Match,
+			Rows: []RowDiff{
+				{Status: Match, Fields: []Diff{
+					{Key: "id", Expect: 1, Actual: 1, Status: Match},
+					{Key: "body", Expect: "<b>", Actual: "<b>", Status: Match},
+				}},
+			},
+		})
+		assert.Contains(t, buf.String(), "<td>&lt;b&gt;</td>")
+	})
+}
+
+func TestSeedProgressCLICallback(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	t.Run("sequential", func(t *testing.T) {
+		var buf bytes.Buffer
+		callback := SeedProgressCLICallback(&buf, false)
+		callback("user", "truncate", true, nil)
+		callback("user", "truncate", false, nil)
+		callback("user", "insert", true, nil)
+		callback("user", "insert", false, errors.New("failed"))
+		lines := strings.Split(buf.String(), "\n")
+		assert.True(t, strings.HasPrefix(lines[0], "truncating: 'user' ... OK ("))
+		assert.Equal(t, []string{"importing: 'user' ... NG", "    failed", ""}, lines[1:])
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		skipWithoutDuckDB(t)
+		path := filepath.Join(t.TempDir(), "progress.duckdb")
+		dbc, err := NewDBConnector(t.Context(), "duckdb://"+path)
+		assert.NoError(t, err)
+		defer dbc.DB().Close()
+
+		var src strings.Builder
+		var names []string
+		for i := range 8 {
+			name := fmt.Sprintf("t%d", i)
+			names = append(names, name)
+			_, err = dbc.DB().ExecContext(t.Context(), fmt.Sprintf("CREATE TABLE %s (id INTEGER PRIMARY KEY)", name))
+			assert.NoError(t, err)
+			fmt.Fprintf(&src, "%s:\n- { id: 1 }\n- { id: 2 }\n", name)
+		}
+		data, err := ParseYAML(strings.NewReader(src.String()))
+		assert.NoError(t, err)
+
+		var buf bytes.Buffer
+		err = Seed(t.Context(), dbc, data, SeedOpt{
+			Parallel: true,
+			Workers:  4,
+			Callback: SeedProgressCLICallback(&buf, true),
+		})
+		assert.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		assert.Equal(t, 16, len(lines))
+		for _, name := range names {
+			assertLinePrefix(t, lines, "truncating: '"+name+"' ... OK (")
+			assertLinePrefix(t, lines, "importing: '"+name+"' ... OK (")
+			var count int
+			assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM "+name).Scan(&count))
+			assert.Equal(t, 2, count)
+		}
+	})
+}
+
+// assertLinePrefix checks that one of lines starts with prefix.
+func assertLinePrefix(t *testing.T, lines []string, prefix string) {
+	t.Helper()
+	for _, l := range lines {
+		if strings.HasPrefix(l, prefix) {
+			return
+		}
+	}
+	t.Fatalf("line starting with %q is not found in %q", prefix, lines)
+}
+
+// sleepingConnector simulates an unresponsive database. Insert and PrimaryKeys block until ctx is canceled.
+type sleepingConnector struct {
+	DBConnector
+}
+
+func (s *sleepingConnector) Insert(ctx context.Context, tx *sql.Tx, tableName string, columns []string, values []any) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *sleepingConnector) PrimaryKeys(ctx context.Context, table string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestTimeoutError(t *testing.T) {
+	dbc := &sleepingConnector{DBConnector: newDuckDBTestConnector(t)}
+	data, err := ParseYAML(strings.NewReader("users:\n- { id: 1, name: Frank }\n"))
+	assert.NoError(t, err)
+	timeout := 50 * time.Millisecond
+
+	t.Run("seed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), timeout)
+		defer cancel()
+		err := TimeoutError(ctx, Seed(ctx, dbc, data, SeedOpt{}), timeout)
+		assert.EqualError(t, err, "timed out after 50ms")
+		assert.IsError(t, err, context.DeadlineExceeded)
+		var e ErrTimeout
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, timeout, e.Timeout)
+	})
+
+	t.Run("assert", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), timeout)
+		defer cancel()
+		_, _, err := Assert(ctx, dbc, data, AssertOpt{})
+		assert.EqualError(t, TimeoutError(ctx, err, timeout), "timed out after 50ms")
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		err := errors.New("connection refused")
+		assert.Equal(t, err, TimeoutError(t.Context(), err, timeout))
+		assert.NoError(t, TimeoutError(t.Context(), nil, timeout))
+	})
+}
+
+func TestEnvFileArgs(t *testing.T) {
+	assert.Equal(t, []string{".env", ".env.ci", ".env.local"}, EnvFileArgs([]string{"--env-file", ".env", "seed", "--env-file=.env.ci", "-q", "--env-file", ".env.local", "data.yaml"}))
+	assert.Equal(t, []string(nil), EnvFileArgs([]string{"seed", "data.yaml", "--", "--env-file", ".env"}))
+	assert.Equal(t, []string(nil), EnvFileArgs([]string{"seed", "--env-file"}))
+}
+
+func TestLoadEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, ".env")
+	ci := filepath.Join(dir, ".env.ci")
+	assert.NoError(t, os.WriteFile(base, []byte("DBTESTIFY_CONN=sqlite://file:base.db\nDBTESTIFY_TEST_BASE_ONLY=base\n"), 0o644))
+	assert.NoError(t, os.WriteFile(ci, []byte("DBTESTIFY_CONN=postgres://ci:5432/db\n"), 0o644))
+	t.Setenv("DBTESTIFY_CONN", "sqlite://file:original.db")
+	t.Setenv("DBTESTIFY_TEST_BASE_ONLY", "")
+
+	assert.NoError(t, LoadEnvFiles(base, ci))
+	assert.Equal(t, "postgres://ci:5432/db", os.Getenv("DBTESTIFY_CONN"))
+	assert.Equal(t, "base", os.Getenv("DBTESTIFY_TEST_BASE_ONLY"))
+
+	t.Run("missing file", func(t *testing.T) {
+		assert.IsError(t, LoadEnvFiles(filepath.Join(dir, ".env.missing")), fs.ErrNotExist)
+	})
+
+	t.Run("default .env", func(t *testing.T) {
+		t.Chdir(dir)
+		t.Setenv("DBTESTIFY_CONN", "sqlite://file:original.db")
+		assert.NoError(t, LoadEnvFiles())
+		// existing variables are not overridden
+		assert.Equal(t, "sqlite://file:original.db", os.Getenv("DBTESTIFY_CONN"))
+
+		t.Chdir(t.TempDir())
+		assert.NoError(t, LoadEnvFiles())
+	})
+}
@@ -3,51 +3,102 @@ package main
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/fatih/color"
-	"github.com/joho/godotenv"
 
 	"github.com/shibukawa/dbtestify"
 	"github.com/shibukawa/dbtestify/httpapi"
 )
 
 var deleteTaskC = color.New(color.FgHiRed).SprintFunc()
-var insertTaskC = color.New(color.FgHiBlue).SprintFunc()
 var nameC = color.New(color.FgBlue, color.Bold).SprintFunc()
 var okC = color.New(color.FgGreen).SprintFunc()
 var errC = color.New(color.FgRed).SprintFunc()
 var infoC = color.New(color.FgYellow).SprintFunc()
 
 var cli struct {
-	DB      string `flag:"" env:"DBTESTIFY_CONN" help:"Database connection setting"`
-	Quiet   bool   `flag:"" short:"q"`
-	Verbose bool   `flag:"" short:"v"`
+	DB      string   `flag:"" env:"DBTESTIFY_CONN" help:"Database connection setting"`
+	EnvFile []string `flag:"" name:"env-file" sep:"none" placeholder:"FILE" help:"Environment variable file to load. It can be repeated and later files override earlier ones (default: .env)."`
+	Quiet   bool     `flag:"" short:"q"`
+	Verbose bool     `flag:"" short:"v"`
 
 	Seed struct {
-		//Gen         string   `short:"g" enum:"playwright,cypress,go," default:""`
-		IncludeTag []string `flag:"" short:"i" optional:"Tag name that is used for filtering data (for include)."`
-		ExcludeTag []string `flag:"" short:"e" optional:"Tag name that is used for filtering data (for exclude)."`
-		BatchSize  int      `flag:"" short:"b" default:"50"`
-		Truncates  []string `flag:"" short:"t" help:"Truncate table target before seeding."`
-		SourceFile string   `arg:"" type:"existingfile" help:"Data set file to import"`
-		Targets    []string `arg:"" optional:"" help:"Target table (default: all tables in source file)"`
+		IncludeTag []string      `flag:"" short:"i" optional:"Tag name that is used for filtering data (for include)."`
+		ExcludeTag []string      `flag:"" short:"e" optional:"Tag name that is used for filtering data (for exclude)."`
+		BatchSize  int           `flag:"" short:"b" default:"50"`
+		Truncates  []string      `flag:"" short:"t" help:"Truncate table target before seeding."`
+		Parallel   bool          `flag:"" short:"p" help:"Seed tables that don't depend on each other concurrently."`
+		Workers    int           `flag:"" help:"Number of concurrent workers for --parallel (default: number of CPUs)."`
+		Watch      bool          `flag:"" short:"w" help:"Watch the source file and seed again when it is changed."`
+		Timeout    time.Duration `flag:"" default:"30s" help:"Timeout of connecting and seeding (0 means no timeout)."`
+		SourceFile string        `arg:"" type:"existingfile" help:"Data set file to import"`
+		Targets    []string      `arg:"" optional:"" help:"Target table. Glob patterns like 'audit_*' are allowed (default: all tables in source file)"`
 	} `cmd:"" help:"Seeding database content for testing"`
 
 	Assert struct {
-		//Gen         string   `short:"g" enum:"playwright,cypress,go," default:""`
+		IncludeTag []string      `flag:"" short:"i" optional:"Tag name that is used for filtering data (for include)."`
+		ExcludeTag []string      `flag:"" short:"e" optional:"Tag name that is used for filtering data (for exclude)."`
+		Format     string        `flag:"" enum:"text,json,markdown" default:"text" help:"Output format of the result (text, json, markdown)."`
+		Timeout    time.Duration `flag:"" default:"30s" help:"Timeout of connecting and asserting (0 means no timeout)."`
+		SourceFile string        `arg:"" type:"existingfile"`
+		Targets    []string      `arg:"" optional:"" help:"Target table. Glob patterns like 'audit_*' are allowed (default: all tables in source file)"`
+	} `cmd:""`
+
+	AssertCount struct {
 		IncludeTag []string `flag:"" short:"i" optional:"Tag name that is used for filtering data (for include)."`
 		ExcludeTag []string `flag:"" short:"e" optional:"Tag name that is used for filtering data (for exclude)."`
 		SourceFile string   `arg:"" type:"existingfile"`
-		Targets    []string `arg:"" optional:"" help:"Target table (default: all tables in source file)"`
-	} `cmd:""`
+		Targets    []string `arg:"" optional:"" help:"Target table. Glob patterns like 'audit_*' are allowed (default: all tables in source file)"`
+	} `cmd:"" help:"Checking only the number of rows of each table"`
+
+	Validate struct {
+		SourceFile string `arg:"" type:"existingfile" help:"Data set file to check"`
+	} `cmd:"" help:"Checking column names of the data set against the database schema"`
+
+	Diff struct {
+		FirstFile  string `arg:"" type:"existingfile" help:"Data set file before the change"`
+		SecondFile string `arg:"" type:"existingfile" help:"Data set file after the change"`
+	} `cmd:"" help:"Comparing rows of two data set files without database"`
+
+	Generate struct {
+		Go struct {
+			Pkg string `flag:"" help:"Package name of the generated test file (default: name of the output folder)."`
+			Out string `flag:"" short:"o" default:"." help:"Output folder."`
+		} `cmd:"" help:"Generating Go test file and data set stubs from the database schema"`
+
+		Playwright struct {
+			Out     string `flag:"" short:"o" default:"." help:"Output folder."`
+			BaseURL string `flag:"" name:"base-url" default:"http://localhost:8000" help:"URL of the API server started by http subcommand."`
+			Dir     string `arg:"" type:"existingdir" help:"Data set folder"`
+		} `cmd:"" help:"Generating Playwright spec files that seed each data set via HTTP API"`
+
+		Cypress struct {
+			Out     string `flag:"" short:"o" default:"." help:"Output folder."`
+			BaseURL string `flag:"" name:"base-url" default:"http://localhost:8000" help:"URL of the API server started by http subcommand."`
+			Dir     string `arg:"" type:"existingdir" help:"Data set folder"`
+		} `cmd:"" help:"Generating Cypress spec files that seed each data set via HTTP API"`
+	} `cmd:"" help:"Generating test boilerplate"`
 
 	Http struct {
-		Port uint16 `flag:"" short:"p" default:"8000"`
-		Dir  string `arg:"" type:"existingdir"`
+		Port       uint16   `flag:"" short:"p" default:"8000"`
+		Token      string   `flag:"" env:"DBTESTIFY_TOKEN" help:"Token required for API requests (Authorization: Bearer <token> or ?token=<token>)."`
+		BasicAuth  string   `flag:"" name:"basic-auth" env:"DBTESTIFY_BASIC_AUTH" placeholder:"USER:PASSWORD" help:"Basic authentication required for seed and assert API requests."`
+		CORSOrigin []string `flag:"" name:"cors-origin" help:"Origin allowed for cross-origin requests (default: all origins)."`
+		RateLimit  int      `flag:"" name:"rate-limit" default:"10" help:"Maximum seed requests per second of each client (0: unlimited)."`
+		Cert       string   `flag:"" type:"existingfile" help:"Certificate file for HTTPS."`
+		Key        string   `flag:"" type:"existingfile" help:"Private key file for HTTPS."`
+		AllowExec  bool     `flag:"" name:"allow-exec" help:"Enable POST /api/exec that executes arbitrary SQL (disabled by default). It requires --token."`
+		Watch      bool     `flag:"" help:"Watch the data set folder and refresh the data set list when files are added or removed."`
+		NoMetrics  bool     `flag:"" name:"no-metrics" help:"Disable GET /metrics that exposes Prometheus metrics."`
+		Dir        string   `arg:"" type:"existingdir"`
 	} `cmd:""`
 }
 
@@ -55,8 +106,8 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
-	err := godotenv.Load()
-	if err != nil && !os.IsNotExist(err) {
+	err := dbtestify.LoadEnvFiles(dbtestify.EnvFileArgs(os.Args[1:])...)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, errC(".env load error: %s\n"), errC(err.Error()))
 		os.Exit(1)
 	}
@@ -68,90 +119,44 @@ func main() {
 			fmt.Fprintln(os.Stderr, errC("--db=<src> or DBTESTIFY_CONN envvar is required to specify database location."))
 			os.Exit(1)
 		}
-		dbc, err := dbtestify.NewDBConnector(ctx, cli.DB)
+		// the connector is closed when its context is done, so only the first ping is limited by --timeout for --watch
+		dbc, err := dbtestify.NewDBConnectorWithOpts(ctx, cli.DB, dbtestify.WithConnectTimeout(cli.Seed.Timeout))
+		err = dbtestify.TimeoutError(ctx, err, cli.Seed.Timeout)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, errC("database location is invalid: %s\n"), errC(err.Error()))
 			os.Exit(1)
 		}
-		f, err := os.Open(cli.Seed.SourceFile)
+		err = seedFile(ctx, dbc)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, errC("can't read source file: %s\n"), errC(err.Error()))
-			os.Exit(1)
-		}
-		defer f.Close()
-		data, err := dbtestify.ParseYAML(f)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, errC("data set file load error: %s\n"), errC(err.Error()))
-			os.Exit(1)
+			fmt.Fprintln(os.Stderr, errC(err.Error()))
+			if !cli.Seed.Watch {
+				os.Exit(1)
+			}
 		}
-
-		var startTime time.Time
-		opt := dbtestify.SeedOpt{
-			BatchSize:    cli.Seed.BatchSize,
-			Operations:   data.Operation,
-			IncludeTags:  cli.Seed.IncludeTag,
-			ExcludeTags:  cli.Seed.ExcludeTag,
-			TargetTables: cli.Seed.Targets,
-			Callback: func(targetTable, task string, start bool, err error) {
-				if cli.Quiet {
-					return
-				}
-				switch task {
-				case "truncate":
-					if start {
-						startTime = time.Now()
-						fmt.Printf("%s: '%s' ...", deleteTaskC("truncating"), nameC(targetTable))
-					} else if err != nil {
-						fmt.Printf(" %s\n    %s\n", errC("NG"), errC(err.Error()))
-					} else {
-						fmt.Printf(" %s (%s)\n", okC("OK"), infoC(time.Since(startTime)))
-					}
-				case "insert":
-					if start {
-						startTime = time.Now()
-						fmt.Printf("%s: '%s' ...", insertTaskC("importing"), nameC(targetTable))
-					} else if err != nil {
-						fmt.Printf(" %s\n    %s\n", errC("NG"), errC(err.Error()))
-					} else {
-						fmt.Printf(" %s (%s)\n", okC("OK"), infoC(time.Since(startTime)))
-					}
-				case "upsert":
-					if start {
-						startTime = time.Now()
-						fmt.Printf("%s: '%s' ...", insertTaskC("upserting"), nameC(targetTable))
-					} else if err != nil {
-						fmt.Printf(" %s\n    %s\n", errC("NG"), errC(err.Error()))
-					} else {
-						fmt.Printf(" %s (%s)\n", okC("OK"), infoC(time.Since(startTime)))
-					}
-				case "delete":
-					if start {
-						startTime = time.Now()
-						fmt.Printf("%s: '%s' ...", deleteTaskC("deleting"), nameC(targetTable))
-					} else if err != nil {
-						fmt.Printf(" %s\n    %s\n", errC("NG"), errC(err.Error()))
-					} else {
-						fmt.Printf(" %s (%s)\n", okC("OK"), infoC(time.Since(startTime)))
-					}
-				default:
-					panic(task)
+		if cli.Seed.Watch {
+			fmt.Printf("watching %s\n", cli.Seed.SourceFile)
+			err = dbtestify.WatchFile(ctx, cli.Seed.SourceFile, 200*time.Millisecond, func() {
+				fmt.Printf("%s re-seeding %s\n", infoC(time.Now().Format("2006-01-02 15:04:05")), cli.Seed.SourceFile)
+				if err := seedFile(ctx, dbc); err != nil {
+					fmt.Fprintln(os.Stderr, errC(err.Error()))
+				} else {
+					fmt.Println(okC("done"))
 				}
-			},
-		}
-		for _, t := range cli.Seed.Truncates {
-			opt.Operations[t] = dbtestify.TruncateOperation
-		}
-		err = dbtestify.Seed(ctx, dbc, data, opt)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, errC("seed error: %s\n"), errC(err.Error()))
-			os.Exit(1)
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, errC("watch error: %s\n"), errC(err.Error()))
+				os.Exit(1)
+			}
 		}
 	case "assert <source-file>":
 		if cli.DB == "" {
 			fmt.Fprintln(os.Stderr, errC("--db=<src> or dbtestify_CONN envvar is required to specify database location."))
 			os.Exit(1)
 		}
+		ctx, cancel := withTimeout(ctx, cli.Assert.Timeout)
+		defer cancel()
 		dbc, err := dbtestify.NewDBConnector(ctx, cli.DB)
+		err = dbtestify.TimeoutError(ctx, err, cli.Assert.Timeout)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, errC("database location is invalid: %s\n"), errC(err.Error()))
 			os.Exit(1)
@@ -168,12 +173,22 @@ func main() {
 			os.Exit(1)
 		}
 		var startTime time.Time
-		ok, _, err := dbtestify.Assert(ctx, dbc, data, dbtestify.AssertOpt{
+		diffCallback := dbtestify.DumpDiffCLICallback(false, cli.Quiet)
+		var flush func() error
+		switch cli.Assert.Format {
+		case "json":
+			diffCallback, flush = dbtestify.DumpDiffJSONCallback(os.Stdout)
+		case "markdown":
+			diffCallback = dbtestify.DumpDiffMarkdownCallback(os.Stdout)
+			flush = func() error { return nil }
+		}
+		ok, tables, err := dbtestify.Assert(ctx, dbc, data, dbtestify.AssertOpt{
 			IncludeTags:  cli.Assert.IncludeTag,
 			ExcludeTags:  cli.Assert.ExcludeTag,
 			TargetTables: cli.Assert.Targets,
 			Callback: func(targetTable string, s dbtestify.MatchStrategy, start bool, err error) {
-				if cli.Quiet {
+				// progress is not printed to keep the JSON/Markdown output valid
+				if cli.Quiet || flush != nil {
 					return
 				}
 				if start {
@@ -186,28 +201,287 @@ func main() {
 				}
 				startTime = time.Now()
 			},
-			DiffCallback: dbtestify.DumpDiffCLICallback(false, cli.Quiet),
+			DiffCallback: diffCallback,
 		})
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "seed error: %s\n", err.Error())
+			fmt.Fprintf(os.Stderr, "assert error: %s\n", dbtestify.TimeoutError(ctx, err, cli.Assert.Timeout).Error())
 			os.Exit(1)
 		}
 
+		if flush != nil {
+			if err := flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "output error: %s\n", err.Error())
+				os.Exit(1)
+			}
+			if !ok {
+				os.Exit(1)
+			}
+		} else if !ok {
+			fmt.Print(errC("Not Match: " + dbtestify.NewAssertResult(tables).Summary() + "\n"))
+			os.Exit(1)
+		} else {
+			fmt.Print(okC("Match\n"))
+		}
+	case "assert-count <source-file>":
+		if cli.DB == "" {
+			fmt.Fprintln(os.Stderr, errC("--db=<src> or DBTESTIFY_CONN envvar is required to specify database location."))
+			os.Exit(1)
+		}
+		dbc, err := dbtestify.NewDBConnector(ctx, cli.DB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, errC("database location is invalid: %s\n"), errC(err.Error()))
+			os.Exit(1)
+		}
+		f, err := os.Open(cli.AssertCount.SourceFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, errC("can't read source file: %s\n"), errC(err.Error()))
+			os.Exit(1)
+		}
+		defer f.Close()
+		data, err := dbtestify.ParseYAML(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, errC("data set file load error: %s\n"), err.Error())
+			os.Exit(1)
+		}
+		ok, result, err := dbtestify.AssertCount(ctx, dbc, data, dbtestify.AssertOpt{
+			IncludeTags:  cli.AssertCount.IncludeTag,
+			ExcludeTags:  cli.AssertCount.ExcludeTag,
+			TargetTables: cli.AssertCount.Targets,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "assert error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		for _, r := range result {
+			if r.Expected == r.Actual {
+				if !cli.Quiet {
+					fmt.Printf("'%s': %d rows %s\n", nameC(r.TableName), r.Actual, okC("OK"))
+				}
+			} else {
+				fmt.Printf("'%s': %d rows, but expected %d %s\n", nameC(r.TableName), r.Actual, r.Expected, errC("NG"))
+			}
+		}
 		if !ok {
-			fmt.Printf(errC("Not Match\n"))
+			fmt.Print(errC("Not Match\n"))
 			os.Exit(1)
 		} else {
-			fmt.Printf(okC("Match\n"))
+			fmt.Print(okC("Match\n"))
+		}
+	case "validate <source-file>":
+		if cli.DB == "" {
+			fmt.Fprintln(os.Stderr, errC("--db=<src> or DBTESTIFY_CONN envvar is required to specify database location."))
+			os.Exit(1)
+		}
+		dbc, err := dbtestify.NewDBConnector(ctx, cli.DB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, errC("database location is invalid: %s\n"), errC(err.Error()))
+			os.Exit(1)
 		}
+		f, err := os.Open(cli.Validate.SourceFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, errC("can't read source file: %s\n"), errC(err.Error()))
+			os.Exit(1)
+		}
+		defer f.Close()
+		data, err := dbtestify.ParseYAML(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, errC("data set file load error: %s\n"), err.Error())
+			os.Exit(1)
+		}
+		problems, err := dbtestify.ValidateDataSet(ctx, dbc, data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "validate error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		for _, p := range problems {
+			fmt.Printf("%s %s\n", errC("NG"), p.String())
+		}
+		if len(problems) > 0 {
+			fmt.Print(errC(fmt.Sprintf("%d problems found\n", len(problems))))
+			os.Exit(1)
+		} else {
+			fmt.Print(okC("OK\n"))
+		}
+	case "diff <first-file> <second-file>":
+		var datasets []*dbtestify.DataSet
+		for _, path := range []string{cli.Diff.FirstFile, cli.Diff.SecondFile} {
+			f, err := os.Open(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, errC("can't read source file: %s\n"), errC(err.Error()))
+				os.Exit(1)
+			}
+			data, err := dbtestify.ParseYAML(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, errC("data set file load error: %s\n"), err.Error())
+				os.Exit(1)
+			}
+			datasets = append(datasets, data)
+		}
+		diff := dbtestify.DiffDataSets(datasets[0], datasets[1])
+		diffCallback := dbtestify.DumpDiffCLICallback(true, cli.Quiet)
+		for _, t := range diff.Tables {
+			diffCallback(t)
+		}
+		for _, t := range diff.OnlyInFirst {
+			fmt.Printf("'%s': %s\n", nameC(t), errC("only in "+cli.Diff.FirstFile))
+		}
+		for _, t := range diff.OnlyInSecond {
+			fmt.Printf("'%s': %s\n", nameC(t), errC("only in "+cli.Diff.SecondFile))
+		}
+		if !diff.IsMatch() {
+			fmt.Print(errC("Not Match\n"))
+			os.Exit(1)
+		} else {
+			fmt.Print(okC("Match\n"))
+		}
+	case "generate go":
+		if cli.DB == "" {
+			fmt.Fprintln(os.Stderr, errC("--db=<src> or DBTESTIFY_CONN envvar is required to specify database location."))
+			os.Exit(1)
+		}
+		dbc, err := dbtestify.NewDBConnector(ctx, cli.DB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, errC("database location is invalid: %s\n"), errC(err.Error()))
+			os.Exit(1)
+		}
+		pkg := cli.Generate.Go.Pkg
+		if pkg == "" {
+			abs, err := filepath.Abs(cli.Generate.Go.Out)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, errC("output folder is invalid: %s\n"), errC(err.Error()))
+				os.Exit(1)
+			}
+			pkg = filepath.Base(abs)
+		}
+		files, err := dbtestify.GenerateGoTest(ctx, dbc, dbtestify.GoTestOpt{Package: pkg, DBConn: cli.DB})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "generate error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		writeGeneratedFiles(cli.Generate.Go.Out, files)
+	case "generate playwright <dir>":
+		root, err := os.OpenRoot(cli.Generate.Playwright.Dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "generate error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		defer root.Close()
+		files, err := dbtestify.GeneratePlaywrightHooks(root.FS(), cli.Generate.Playwright.BaseURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "generate error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		writeGeneratedFiles(cli.Generate.Playwright.Out, files)
+	case "generate cypress <dir>":
+		root, err := os.OpenRoot(cli.Generate.Cypress.Dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "generate error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		defer root.Close()
+		files, err := dbtestify.GenerateCypressHooks(root.FS(), cli.Generate.Cypress.BaseURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "generate error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		writeGeneratedFiles(cli.Generate.Cypress.Out, files)
 	case "http <dir>":
 		if cli.DB == "" {
 			fmt.Fprintln(os.Stderr, errC("--db=<src> or DBTESTIFY_CONN envvar is required to specify database location."))
 			os.Exit(1)
 		}
-		err := httpapi.Start(ctx, cli.Http.Dir, cli.DB, cli.Http.Port)
+		opts := []httpapi.ServerOpt{httpapi.WithDataDir(cli.Http.Dir), httpapi.WithToken(cli.Http.Token), httpapi.WithCORSOrigins(cli.Http.CORSOrigin...), httpapi.WithRateLimit(cli.Http.RateLimit)}
+		if cli.Http.BasicAuth != "" {
+			user, password, ok := strings.Cut(cli.Http.BasicAuth, ":")
+			if !ok {
+				fmt.Fprintln(os.Stderr, errC("--basic-auth should be <user>:<password> format."))
+				os.Exit(1)
+			}
+			opts = append(opts, httpapi.WithBasicAuth(user, password))
+		}
+		if cli.Http.AllowExec {
+			opts = append(opts, httpapi.WithExec())
+		}
+		if cli.Http.Watch {
+			opts = append(opts, httpapi.WithWatch())
+		}
+		if cli.Http.NoMetrics {
+			opts = append(opts, httpapi.WithoutMetrics())
+		}
+		root, err := os.OpenRoot(cli.Http.Dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "server start error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		defer root.Close()
+		err = httpapi.StartTLS(ctx, root.FS(), cli.DB, cli.Http.Port, cli.Http.Cert, cli.Http.Key, opts...)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "server start error: %s\n", err.Error())
 			os.Exit(1)
 		}
 	}
 }
+
+// seedFile reads the data set file of seed subcommand and seeds the database within --timeout.
+func seedFile(ctx context.Context, dbc dbtestify.DBConnector) error {
+	ctx, cancel := withTimeout(ctx, cli.Seed.Timeout)
+	defer cancel()
+	f, err := os.Open(cli.Seed.SourceFile)
+	if err != nil {
+		return fmt.Errorf("can't read source file: %w", err)
+	}
+	defer f.Close()
+	data, err := dbtestify.ParseYAML(f)
+	if err != nil {
+		return fmt.Errorf("data set file load error: %w", err)
+	}
+
+	opt := dbtestify.SeedOpt{
+		BatchSize:    cli.Seed.BatchSize,
+		Operations:   data.Operation,
+		IncludeTags:  cli.Seed.IncludeTag,
+		ExcludeTags:  cli.Seed.ExcludeTag,
+		TargetTables: cli.Seed.Targets,
+		Parallel:     cli.Seed.Parallel,
+		Workers:      cli.Seed.Workers,
+	}
+	if !cli.Quiet {
+		opt.Callback = dbtestify.SeedProgressCLICallback(os.Stdout, cli.Seed.Parallel)
+	}
+	for _, t := range cli.Seed.Truncates {
+		opt.Operations[t] = dbtestify.TruncateOperation
+	}
+	if err := dbtestify.Seed(ctx, dbc, data, opt); err != nil {
+		return fmt.Errorf("seed error: %w", dbtestify.TimeoutError(ctx, err, cli.Seed.Timeout))
+	}
+	return nil
+}
+
+// withTimeout is context.WithTimeout, but timeout 0 means no deadline.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// writeGeneratedFiles writes the files generated by `generate` subcommands into out folder. Existing files are not overwritten.
+func writeGeneratedFiles(out string, files map[string][]byte) {
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		p := filepath.Join(out, filepath.FromSlash(name))
+		if _, err := os.Stat(p); err == nil {
+			fmt.Printf("%s %s (already exists)\n", infoC("skip"), p)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "generate error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		if err := os.WriteFile(p, files[name], 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "generate error: %s\n", err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("%s %s\n", okC("create"), p)
+	}
+}
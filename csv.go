@@ -0,0 +1,81 @@
+package dbtestify
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ParseCSV reads a CSV formatted table from the provided reader.
+//
+// The first row is a header. Each field is a number if it looks like a number, nil if it is `null`, or a string otherwise.
+func ParseCSV(tableName string, r io.Reader) (*Table, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("parse error: CSV of table %s doesn't have header", tableName)
+	} else if err != nil {
+		return nil, fmt.Errorf("parse error: CSV of table %s: %w", tableName, err)
+	}
+	t := &Table{
+		Name: tableName,
+	}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parse error: CSV of table %s: %w", tableName, err)
+		}
+		row := map[string]any{}
+		for i, column := range header {
+			row[column] = csvValue(record[i])
+		}
+		t.Rows = append(t.Rows, row)
+		t.Tags = append(t.Tags, nil)
+	}
+	return t, nil
+}
+
+func csvValue(field string) any {
+	if field == "null" {
+		return nil
+	}
+	// zip codes and phone numbers like "00123" and "0901234" lose the zeros as numbers
+	if digits := strings.TrimLeft(field, "+-"); len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return field
+	}
+	if i, err := strconv.Atoi(field); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(field, 64); err == nil && !strings.ContainsAny(field, "xXnN") {
+		return f
+	}
+	return field
+}
+
+// ParseCSVDir reads all `*.csv` files in the directory and returns a DataSet. The file name without extension is used as the table name.
+func ParseCSVDir(dir fs.FS) (*DataSet, error) {
+	files, err := fs.Glob(dir, "*.csv")
+	if err != nil {
+		return nil, err
+	}
+	result := &DataSet{}
+	for _, file := range files {
+		f, err := dir.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		t, err := ParseCSV(strings.TrimSuffix(file, path.Ext(file)), f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		result.Tables = append(result.Tables, t)
+	}
+	return result, nil
+}
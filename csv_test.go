@@ -0,0 +1,90 @@
+package dbtestify
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestParseCSV(t *testing.T) {
+	source := TrimIndent(t, `
+		id,name,note,score
+		1,Frank,null,1.5
+		2,"Grace, Jr.","say ""hello""",-3
+		3,Heidi,"multi
+		line",NaN
+		`)
+	table, err := ParseCSV("user", strings.NewReader(source))
+	assert.NoError(t, err)
+	assert.Equal(t, &Table{
+		Name: "user",
+		Rows: []map[string]any{
+			{"id": 1, "name": "Frank", "note": nil, "score": 1.5},
+			{"id": 2, "name": "Grace, Jr.", "note": `say "hello"`, "score": -3},
+			{"id": 3, "name": "Heidi", "note": "multi\nline", "score": "NaN"},
+		},
+		Tags: [][]string{nil, nil, nil},
+	}, table)
+
+	t.Run("leading zeros", func(t *testing.T) {
+		table, err := ParseCSV("address", strings.NewReader("zip,phone,zero,ratio,negative,code\n00123,0901234,0,0.5,-0.25,-007\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"zip":      "00123",
+			"phone":    "0901234",
+			"zero":     0,
+			"ratio":    0.5,
+			"negative": -0.25,
+			"code":     "-007",
+		}, table.Rows[0])
+	})
+
+	_, err = ParseCSV("user", strings.NewReader(""))
+	assert.Error(t, err)
+	_, err = ParseCSV("user", strings.NewReader("id,name\n1\n"))
+	assert.Error(t, err)
+}
+
+func TestParseCSVRoundTrip(t *testing.T) {
+	records := [][]string{
+		{"id", "name"},
+		{"1", "comma, inside"},
+		{"2", `"quoted"`},
+		{"3", "new\nline"},
+		{"4", "日本語"},
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	assert.NoError(t, w.WriteAll(records))
+
+	table, err := ParseCSV("memo", &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, len(records)-1, len(table.Rows))
+	for i, r := range records[1:] {
+		assert.Equal[any](t, r[1], table.Rows[i]["name"])
+	}
+}
+
+func TestParseCSVDir(t *testing.T) {
+	dir := fstest.MapFS{
+		"user.csv":   {Data: []byte("id,name\n1,Frank\n2,Grace\n")},
+		"group.csv":  {Data: []byte("id,name\n1,admin\n")},
+		"readme.txt": {Data: []byte("not a table")},
+	}
+	data, err := ParseCSVDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(data.Tables))
+	assert.Equal(t, "group", data.Tables[0].Name)
+	assert.Equal(t, "user", data.Tables[1].Name)
+
+	normalized, err := data.Tables[1].SortAndFilter([]string{"id"}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]Value{
+		{{"id", 1}, {"name", "Frank"}},
+		{{"id", 2}, {"name", "Grace"}},
+	}, normalized.Rows)
+}
@@ -1,13 +1,16 @@
 package dbtestify
 
 import (
-	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"maps"
+	"os"
+	"path"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/goccy/go-yaml"
@@ -17,12 +20,13 @@ import (
 type Operation string
 
 const (
-	ClearInsertOperation Operation = "clear-insert"
-	InsertOperation      Operation = "insert"
-	UpsertOperation      Operation = "upsert"
-	DeleteOperation      Operation = "delete"
-	TruncateOperation    Operation = "truncate"
-	InvalidOperator      Operation = "invalid"
+	ClearInsertOperation  Operation = "clear-insert"
+	InsertOperation       Operation = "insert"
+	UpsertOperation       Operation = "upsert"
+	InsertIgnoreOperation Operation = "insert-ignore"
+	DeleteOperation       Operation = "delete"
+	TruncateOperation     Operation = "truncate"
+	InvalidOperator       Operation = "invalid"
 )
 
 func (o Operation) String() string {
@@ -44,46 +48,304 @@ func (s MatchStrategy) String() string {
 
 // DataSet represents a collection of tables and their associated operations and match strategies.
 type DataSet struct {
-	Operation map[string]Operation
-	Match     map[string]MatchStrategy
-	Tables    []*Table
+	Operation     map[string]Operation
+	Match         map[string]MatchStrategy
+	PKOverride    map[string][]string // Primary keys used for assertion instead of the keys registered in database
+	Order         map[string][]string // Columns to sort rows fetched for assertion. They are also used to pair rows if PKOverride is not specified.
+	Where         map[string]string   // SQL expression to filter rows fetched for assertion. It is passed to the WHERE clause verbatim, so use it only with trusted data sets.
+	DependsOn     map[string][]string // Tables that should be seeded before the key table.
+	DefaultSchema string              // Schema prepended to the table names without schema when Seed and Assert access the database. Set by `_schema`.
+	Tables        []*Table
 }
 
 // Table represents a single table in the dataset, including its name, rows, and tags.
 type Table struct {
-	Name string
-	Rows []map[string]any
-	Tags [][]string
+	Name       string
+	Rows       []map[string]any
+	Tags       [][]string
+	SeedOnly   []bool // Rows marked by `_seed_only: true`. They are not used for assertion.
+	AssertOnly []bool // Rows marked by `_assert_only: true`. They are not used for seeding.
+}
+
+func (t Table) isSeedOnly(i int) bool {
+	return i < len(t.SeedOnly) && t.SeedOnly[i]
+}
+
+func (t Table) isAssertOnly(i int) bool {
+	return i < len(t.AssertOnly) && t.AssertOnly[i]
+}
+
+// DataSet.GetTable returns the table that has the specified name.
+func (d *DataSet) GetTable(name string) (*Table, bool) {
+	i := slices.IndexFunc(d.Tables, func(t *Table) bool {
+		return t.Name == name
+	})
+	if i == -1 {
+		return nil, false
+	}
+	return d.Tables[i], true
+}
+
+// DataSet.TableNames returns the sorted names of the tables in the dataset.
+func (d *DataSet) TableNames() []string {
+	result := make([]string, len(d.Tables))
+	for i, t := range d.Tables {
+		result[i] = t.Name
+	}
+	slices.Sort(result)
+	return result
 }
 
 // ParseYAML reads a YAML formatted dataset from the provided reader and returns a DataSet object.
-func ParseYAML(r io.Reader) (*DataSet, error) {
+//
+// `${VAR}` in string values of rows and `_where` directive is replaced with the environment variable. See ParseYAMLWithEnv.
+func ParseYAML(r io.Reader, opts ...ParseOpt) (*DataSet, error) {
+	return parseYAML(r, newEnvLookup(os.LookupEnv, opts))
+}
+
+func decodeYAML(r io.Reader) (*DataSet, error) {
 	temp := dataSet{}
 	d := yaml.NewDecoder(r, yaml.AllowDuplicateMapKey())
 	if err := d.Decode(&temp); err != nil && !errors.Is(err, io.EOF) {
 		return nil, err
 	}
 	return &DataSet{
-		Operation: temp.Operation,
-		Match:     temp.Match,
-		Tables:    temp.Tables,
+		Operation:     temp.Operation,
+		Match:         temp.Match,
+		PKOverride:    temp.PKOverride,
+		Order:         temp.Order,
+		Where:         temp.Where,
+		DependsOn:     temp.DependsOn,
+		DefaultSchema: temp.DefaultSchema,
+		Tables:        temp.Tables,
 	}, nil
 }
 
+// ParseYAMLFiles reads YAML formatted dataset files in root and merges them in order.
+//
+// e.g. a base fixture and environment-specific overrides. See DataSet.Merge for the merge rule.
+func ParseYAMLFiles(root fs.FS, paths ...string) (*DataSet, error) {
+	result := &DataSet{}
+	for _, path := range paths {
+		d, err := parseYAMLFile(root, path)
+		if err != nil {
+			return nil, err
+		}
+		result = result.Merge(d)
+	}
+	return result, nil
+}
+
+func parseYAMLFile(root fs.FS, path string) (*DataSet, error) {
+	f, err := root.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset %s: %w", path, err)
+	}
+	defer f.Close()
+	d, err := ParseYAML(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dataset %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// ParseYAMLReaders reads YAML formatted datasets from the provided readers and merges them in order.
+//
+// See DataSet.Merge for the merge rule.
+func ParseYAMLReaders(readers ...io.Reader) (*DataSet, error) {
+	result := &DataSet{}
+	for i, r := range readers {
+		d, err := ParseYAML(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dataset #%d: %w", i, err)
+		}
+		result = result.Merge(d)
+	}
+	return result, nil
+}
+
+// DataSet.Merge returns a new DataSet that combines the receiver and other.
+//
+// Rows of the table that exists in both datasets are appended. For Operation, Match, PKOverride, Order, Where, DependsOn and DefaultSchema, the setting in other wins on conflict.
+// The receiver and other are not modified.
+func (d *DataSet) Merge(other *DataSet) *DataSet {
+	result := &DataSet{}
+	for _, src := range []*DataSet{d, other} {
+		if src == nil {
+			continue
+		}
+		result.Operation = mergeMap(result.Operation, src.Operation)
+		result.Match = mergeMap(result.Match, src.Match)
+		result.PKOverride = mergeMap(result.PKOverride, src.PKOverride)
+		result.Order = mergeMap(result.Order, src.Order)
+		result.Where = mergeMap(result.Where, src.Where)
+		result.DependsOn = mergeMap(result.DependsOn, src.DependsOn)
+		if src.DefaultSchema != "" {
+			result.DefaultSchema = src.DefaultSchema
+		}
+		for _, t := range src.Tables {
+			rt, ok := result.GetTable(t.Name)
+			if !ok {
+				result.Tables = append(result.Tables, &Table{
+					Name:       t.Name,
+					Rows:       slices.Clone(t.Rows),
+					Tags:       slices.Clone(t.Tags),
+					SeedOnly:   slices.Clone(t.SeedOnly),
+					AssertOnly: slices.Clone(t.AssertOnly),
+				})
+			} else {
+				if len(rt.SeedOnly) > 0 || len(t.SeedOnly) > 0 {
+					rt.SeedOnly = append(padFlags(rt.SeedOnly, len(rt.Rows)), padFlags(t.SeedOnly, len(t.Rows))...)
+				}
+				if len(rt.AssertOnly) > 0 || len(t.AssertOnly) > 0 {
+					rt.AssertOnly = append(padFlags(rt.AssertOnly, len(rt.Rows)), padFlags(t.AssertOnly, len(t.Rows))...)
+				}
+				rt.Rows = append(rt.Rows, t.Rows...)
+				rt.Tags = append(rt.Tags, t.Tags...)
+			}
+		}
+	}
+	return result
+}
+
+// padFlags returns a copy of flags that has exactly n elements.
+func padFlags(flags []bool, n int) []bool {
+	result := make([]bool, n)
+	copy(result, flags)
+	return result
+}
+
+func mergeMap[V any](dest, src map[string]V) map[string]V {
+	if len(src) == 0 {
+		return dest
+	}
+	if dest == nil {
+		dest = map[string]V{}
+	}
+	maps.Copy(dest, src)
+	return dest
+}
+
 // ErrMissingPrimaryKey is an error type that indicates that some primary keys are missing from a row.
 type ErrMissingPrimaryKey struct {
 	MissingKeys []string
 	Dump        string
 }
 
+// ErrMissingPrimaryKeyType matches any ErrMissingPrimaryKey with errors.Is.
+//
+//	if errors.Is(err, dbtestify.ErrMissingPrimaryKeyType) { ... }
+//
+// To read MissingKeys, use errors.As with your own *ErrMissingPrimaryKey variable.
+var ErrMissingPrimaryKeyType = &ErrMissingPrimaryKey{}
+
 func (e ErrMissingPrimaryKey) Error() string {
 	return fmt.Sprintf("missing primary keys: [%s]", strings.Join(e.MissingKeys, ", "))
 }
 
+// Is reports whether target is an ErrMissingPrimaryKey regardless of MissingKeys and Dump.
+func (e ErrMissingPrimaryKey) Is(target error) bool {
+	switch target.(type) {
+	case ErrMissingPrimaryKey, *ErrMissingPrimaryKey:
+		return true
+	}
+	return false
+}
+
+// Table.FilterByTag returns a new Table that has only the rows matched with include and exclude tags.
+//
+// It is the same filter as Seed and Assert use. Rows are not copied, and the receiver is not modified.
+func (t Table) FilterByTag(includeTags, excludeTags []string) *Table {
+	result := &Table{
+		Name: t.Name,
+	}
+	for i, row := range t.Rows {
+		if !filter(t.Tags[i], includeTags, excludeTags) {
+			continue
+		}
+		result.Rows = append(result.Rows, row)
+		result.Tags = append(result.Tags, t.Tags[i])
+		if len(t.SeedOnly) > 0 {
+			result.SeedOnly = append(result.SeedOnly, t.isSeedOnly(i))
+		}
+		if len(t.AssertOnly) > 0 {
+			result.AssertOnly = append(result.AssertOnly, t.isAssertOnly(i))
+		}
+	}
+	return result
+}
+
+// DataSet.FilterByTag returns a new DataSet whose tables are filtered by Table.FilterByTag.
+//
+// Other settings like Operation and Match are shared with the receiver.
+func (d *DataSet) FilterByTag(includeTags, excludeTags []string) *DataSet {
+	result := *d
+	result.Tables = make([]*Table, len(d.Tables))
+	for i, t := range d.Tables {
+		result.Tables[i] = t.FilterByTag(includeTags, excludeTags)
+	}
+	return &result
+}
+
 // Table.SortAndFilter sorts the rows of the table based on the provided primary keys and filters them based on include and exclude tags.
 func (t Table) SortAndFilter(primaryKeys, includeTags, excludeTags []string) (*NormalizedTable, error) {
-	slices.Sort(primaryKeys)
+	primaryKeys = slices.Sorted(slices.Values(primaryKeys))
+	result, err := t.normalize(primaryKeys, includeTags, excludeTags)
+	if err != nil {
+		return nil, err
+	}
+	sortRow(result.Rows, primaryKeys)
+	return result, nil
+}
 
+// Table.SortAndFilterBatch is the same as SortAndFilter, but it returns only limit rows from offset of the sorted rows.
+//
+// It is for processing large tables in chunks. The returned rows don't share memory with other chunks.
+// If limit is 0, it returns all rows from offset. If offset is past the last row, the result has no rows.
+// It sorts the whole table in each call, so use SortAndFilterBatches to read all chunks.
+func (t Table) SortAndFilterBatch(primaryKeys, includeTags, excludeTags []string, offset, limit int) (*NormalizedTable, error) {
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("table '%s': offset and limit should not be negative: offset=%d, limit=%d", t.Name, offset, limit)
+	}
+	result, err := t.SortAndFilter(primaryKeys, includeTags, excludeTags)
+	if err != nil {
+		return nil, err
+	}
+	start := min(offset, len(result.Rows))
+	end := len(result.Rows)
+	if limit > 0 {
+		end = min(start+limit, end)
+	}
+	result.Rows = slices.Clone(result.Rows[start:end])
+	return result, nil
+}
+
+// Table.SortAndFilterBatches sorts and filters the rows once, and calls fn with every limit rows in order.
+//
+// The chunk shares memory with the sorted rows, so fn shouldn't keep it after returning. The error of fn stops the iteration and is returned.
+// If limit is 0, fn is called once with all rows. fn is not called if no rows remain after filtering.
+func (t Table) SortAndFilterBatches(primaryKeys, includeTags, excludeTags []string, limit int, fn func(chunk *NormalizedTable) error) error {
+	if limit < 0 {
+		return fmt.Errorf("table '%s': limit should not be negative: limit=%d", t.Name, limit)
+	}
+	sorted, err := t.SortAndFilter(primaryKeys, includeTags, excludeTags)
+	if err != nil {
+		return err
+	}
+	if limit == 0 {
+		limit = max(len(sorted.Rows), 1)
+	}
+	for start := 0; start < len(sorted.Rows); start += limit {
+		end := min(start+limit, len(sorted.Rows))
+		if err := fn(&NormalizedTable{Name: sorted.Name, Rows: sorted.Rows[start:end]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalize is the same as SortAndFilter, but it keeps the order of rows in the dataset and the order of primary keys.
+func (t Table) normalize(primaryKeys, includeTags, excludeTags []string) (*NormalizedTable, error) {
 	var errs []error
 
 	result := &NormalizedTable{
@@ -92,10 +354,13 @@ func (t Table) SortAndFilter(primaryKeys, includeTags, excludeTags []string) (*N
 
 	result.Rows = make([][]Value, 0, len(t.Rows))
 	for i, rawRow := range t.Rows {
+		if t.isSeedOnly(i) {
+			continue
+		}
 		if filter(t.Tags[i], includeTags, excludeTags) {
 			row, err := mapToValues(rawRow, primaryKeys)
 			if err != nil {
-				errs = append(errs, err)
+				errs = append(errs, fmt.Errorf("table '%s' row %d: %w", t.Name, i, err))
 			} else {
 				result.Rows = append(result.Rows, row)
 			}
@@ -104,7 +369,6 @@ func (t Table) SortAndFilter(primaryKeys, includeTags, excludeTags []string) (*N
 	if len(errs) > 0 {
 		return nil, errors.Join(errs...)
 	}
-	sortRow(result.Rows, primaryKeys)
 	return result, nil
 }
 
@@ -121,17 +385,147 @@ type Value struct {
 }
 
 type dataSet struct {
-	Operation map[string]Operation
-	Match     map[string]MatchStrategy
-	Tables    []*Table
+	Operation     map[string]Operation
+	Match         map[string]MatchStrategy
+	PKOverride    map[string][]string
+	Order         map[string][]string
+	Where         map[string]string
+	DependsOn     map[string][]string
+	DefaultSchema string
+	Tables        []*Table
 }
 
-func (d *dataSet) UnmarshalYAML(b []byte) error {
+// rowsOf converts the decoded table value into rows.
+// It doesn't re-encode the value like directives, because go-yaml can't read its own output of some strings.
+func rowsOf(val any) ([]map[string]any, error) {
+	if val == nil {
+		return nil, nil
+	}
+	items, ok := val.([]any)
+	if !ok {
+		return nil, fmt.Errorf("rows should be a sequence, but: '%v'", val)
+	}
+	rows := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		row, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("row should be a mapping, but: '%v'", item)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// templatesOf converts the value of `_template` directive into default values per table.
+func templatesOf(val any) (map[string]map[string]any, error) {
+	if val == nil {
+		return nil, nil
+	}
+	tables, ok := val.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("_template should be a mapping of tables, but: '%v'", val)
+	}
+	result := make(map[string]map[string]any, len(tables))
+	for name, t := range tables {
+		tmpl, ok := t.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("_template of table %s should be a mapping, but: '%v'", name, t)
+		}
+		result[name] = tmpl
+	}
+	return result, nil
+}
+
+// generatedRowsOf expands the value of `_generate` directive into rows per table.
+//
+// Each table has `count` and the column values. "sequence" is replaced with the row number starting from 1,
+// and `${n}` in other strings is replaced with the row number too.
+func generatedRowsOf(val any) (map[string][]map[string]any, error) {
+	if val == nil {
+		return nil, nil
+	}
+	tables, ok := val.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("_generate should be a mapping of tables, but: '%v'", val)
+	}
+	result := make(map[string][]map[string]any, len(tables))
+	for name, t := range tables {
+		columns, ok := t.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("_generate of table %s should be a mapping, but: '%v'", name, t)
+		}
+		var count int
+		switch c := columns["count"].(type) {
+		case uint64:
+			count = int(c)
+		case int64:
+			count = int(c)
+		}
+		if count <= 0 {
+			return nil, fmt.Errorf("_generate of table %s should have positive count, but: '%v'", name, columns["count"])
+		}
+		rows := make([]map[string]any, count)
+		for i := range count {
+			n := strconv.Itoa(i + 1)
+			row := make(map[string]any, len(columns)-1)
+			for k, v := range columns {
+				if k == "count" {
+					continue
+				}
+				switch vv := v.(type) {
+				case string:
+					if vv == "sequence" {
+						v = uint64(i + 1)
+					} else {
+						v = strings.ReplaceAll(vv, "${n}", n)
+					}
+				case []any:
+					v = slices.Clone(vv)
+				}
+				row[k] = v
+			}
+			rows[i] = row
+		}
+		result[name] = rows
+	}
+	return result, nil
+}
+
+// withTemplate returns a new row that has the template values for the fields the row doesn't have.
+func withTemplate(tmpl, row map[string]any) map[string]any {
+	result := make(map[string]any, len(tmpl)+len(row))
+	for k, v := range tmpl {
+		if s, ok := v.([]any); ok {
+			// not to share the sequence between rows
+			v = slices.Clone(s)
+		}
+		result[k] = v
+	}
+	maps.Copy(result, row)
+	return result
+}
+
+func (d *dataSet) UnmarshalYAML(unmarshal func(any) error) error {
 	var rawData map[string]any
-	if err := yaml.Unmarshal(b, &rawData); err != nil {
+	if err := unmarshal(&rawData); err != nil {
 		return err
 	}
 
+	// templates are read first to apply them to the rows regardless of the key order
+	templates, err := templatesOf(rawData["_template"])
+	if err != nil {
+		return err
+	}
+	generated, err := generatedRowsOf(rawData["_generate"])
+	if err != nil {
+		return err
+	}
+	for name := range generated {
+		if _, ok := rawData[name]; !ok {
+			rawData[name] = nil
+		}
+	}
+
 	for key, val := range rawData {
 		valueBytes, err := yaml.Marshal(val)
 		if err != nil {
@@ -139,6 +533,8 @@ func (d *dataSet) UnmarshalYAML(b []byte) error {
 		}
 
 		switch key {
+		case "_template", "_generate":
+			// already read
 		case "_operation":
 			operations := map[string]Operation{}
 			if err := yaml.Unmarshal(valueBytes, &operations); err != nil {
@@ -151,56 +547,185 @@ func (d *dataSet) UnmarshalYAML(b []byte) error {
 				return fmt.Errorf("failed to unmarshal _strategy: %w", err)
 			}
 			d.Match = matches
+		case "_pkey":
+			pkeys := map[string][]string{}
+			if err := yaml.Unmarshal(valueBytes, &pkeys); err != nil {
+				return fmt.Errorf("failed to unmarshal _pkey: %w", err)
+			}
+			d.PKOverride = pkeys
+		case "_where":
+			where := map[string]string{}
+			if err := yaml.Unmarshal(valueBytes, &where); err != nil {
+				return fmt.Errorf("failed to unmarshal _where: %w", err)
+			}
+			d.Where = where
+		case "_order":
+			order := map[string][]string{}
+			if err := yaml.Unmarshal(valueBytes, &order); err != nil {
+				return fmt.Errorf("failed to unmarshal _order: %w", err)
+			}
+			d.Order = order
+		case "_depends_on":
+			dependsOn := map[string][]string{}
+			if err := yaml.Unmarshal(valueBytes, &dependsOn); err != nil {
+				return fmt.Errorf("failed to unmarshal _depends_on: %w", err)
+			}
+			d.DependsOn = dependsOn
+		case "_schema":
+			schema, ok := val.(string)
+			if !ok || schema == "" || strings.Contains(schema, ".") {
+				return fmt.Errorf("_schema should be a schema name, but: %v", val)
+			}
+			d.DefaultSchema = schema
 		default:
-			var rows []map[string]any
-			if err := yaml.Unmarshal(valueBytes, &rows); err != nil {
+			if err := checkTableName(key); err != nil {
+				return err
+			}
+			rows, err := rowsOf(val)
+			if err != nil {
 				return fmt.Errorf("failed to unmarshal key %s: %w", key, err)
 			}
+			rows = append(rows, generated[key]...)
 			t := &Table{
 				Name: key,
 			}
 			d.Tables = append(d.Tables, t)
 			for _, rowSrc := range rows {
-				rowMap := map[string]any{}
-				var tags []string
+				if tmpl, ok := templates[key]; ok {
+					rowSrc = withTemplate(tmpl, rowSrc)
+				}
+				rowMap, tags, seedOnly, assertOnly, err := parseRow(rowSrc)
+				if err != nil {
+					return err
+				}
 				t.Rows = append(t.Rows, rowMap)
-				for k, v := range rowSrc {
-					if k == "_tag" {
-						switch val := v.(type) {
-						case string:
-							for _, t := range strings.Split(val, ",") {
-								if tt := strings.TrimSpace(t); tt != "" {
-									tags = append(tags, tt)
-								}
-							}
-						case []any:
-							for _, t := range val {
-								if ts, ok := t.(string); ok {
-									tags = append(tags, ts)
-								} else {
-									tags = append(tags, fmt.Sprintf("%v", t))
-								}
-							}
-						default:
-							return fmt.Errorf("parse error: tag should be string or [string...], but: '%v'", v)
-						}
+				t.Tags = append(t.Tags, tags)
+				t.SeedOnly = append(t.SeedOnly, seedOnly)
+				t.AssertOnly = append(t.AssertOnly, assertOnly)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkTableName accepts "table" or "schema.table". "schema.table" is passed to DBConnector as is.
+func checkTableName(name string) error {
+	if strings.Count(name, ".") > 1 || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+		return fmt.Errorf("invalid table name %s: it should be 'table' or 'schema.table'", name)
+	}
+	return nil
+}
+
+// parseRow splits the row of the dataset into column values and row options like `_tag`.
+func parseRow(rowSrc map[string]any) (rowMap map[string]any, tags []string, seedOnly, assertOnly bool, err error) {
+	rowMap = map[string]any{}
+	for k, v := range rowSrc {
+		if strings.HasPrefix(k, "_comment") {
+			// note for fixture authors like _comment or _comment_ja
+			continue
+		} else if k == "_seed_only" || k == "_assert_only" {
+			flag, ok := v.(bool)
+			if !ok {
+				return nil, nil, false, false, fmt.Errorf("parse error: %s should be bool, but: '%v'", k, v)
+			}
+			if k == "_seed_only" {
+				seedOnly = flag
+			} else {
+				assertOnly = flag
+			}
+		} else if k == "_tag" {
+			switch val := v.(type) {
+			case string:
+				for _, t := range strings.Split(val, ",") {
+					if tt := strings.TrimSpace(t); tt != "" {
+						tags = append(tags, tt)
+					}
+				}
+			case []any:
+				for _, t := range val {
+					if ts, ok := t.(string); ok {
+						tags = append(tags, ts)
 					} else {
-						switch vv := v.(type) {
-						case uint64:
-							rowMap[k] = int(vv)
-						case int64:
-							rowMap[k] = int(vv)
-						default:
-							rowMap[k] = v
-						}
+						tags = append(tags, fmt.Sprintf("%v", t))
 					}
 				}
-				t.Tags = append(t.Tags, tags)
+			default:
+				return nil, nil, false, false, fmt.Errorf("parse error: tag should be string or [string...], but: '%v'", v)
+			}
+		} else {
+			switch vv := v.(type) {
+			case uint64:
+				rowMap[k] = int(vv)
+			case int64:
+				rowMap[k] = int(vv)
+			default:
+				rowMap[k] = v
 			}
 		}
 	}
+	return rowMap, tags, seedOnly, assertOnly, nil
+}
 
-	return nil
+// MatchTargetTables reports whether the table is selected by SeedOpt.TargetTables or AssertOpt.TargetTables.
+//
+// Empty targets select all tables. Targets that have `*` or `?` are glob patterns of path.Match like `audit_*`.
+// Other targets should be the same as the table name.
+func MatchTargetTables(targets []string, tableName string) bool {
+	if len(targets) == 0 {
+		return true
+	}
+	for _, t := range targets {
+		if strings.ContainsAny(t, "*?") {
+			if ok, _ := path.Match(t, tableName); ok {
+				return true
+			}
+		} else if t == tableName {
+			return true
+		}
+	}
+	return false
+}
+
+// TopologicalSort returns tables ordered so that every table comes after the tables that fkFn returns for it.
+//
+// The original order is kept as much as possible. Dependencies to the tables that are not in tables aren't included in the result,
+// but their dependencies are still followed. ErrCyclicDependency is returned if the dependencies have a cycle.
+func TopologicalSort(tables []string, fkFn func(t string) []string) ([]string, error) {
+	inTables := map[string]bool{}
+	for _, t := range tables {
+		inTables[t] = true
+	}
+	result := make([]string, 0, len(tables))
+	done := map[string]bool{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		if done[name] {
+			return nil
+		}
+		if i := slices.Index(path, name); i != -1 {
+			return fmt.Errorf("%w: %s", ErrCyclicDependency, strings.Join(append(path[i:], name), " -> "))
+		}
+		path = append(path, name)
+		for _, dep := range fkFn(name) {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		done[name] = true
+		if inTables[name] {
+			result = append(result, name)
+		}
+		return nil
+	}
+	for _, t := range tables {
+		if err := visit(t); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
 func filter(src, includes, excludes []string) bool {
@@ -253,33 +778,7 @@ func mapToValues(m map[string]any, primaryKeys []string) ([]Value, error) {
 func sortRow(rows [][]Value, sortKeys []string) {
 	slices.SortFunc(rows, func(ri, rj []Value) int {
 		for i := range sortKeys {
-			c := -1
-			vi := ri[i].Value
-			vj := rj[i].Value
-			switch vit := vi.(type) { // todo other types
-			case int:
-				if vjt, ok := vj.(int); !ok {
-					break
-				} else {
-					c = cmp.Compare(vit, vjt)
-				}
-			case float64:
-				if vjt, ok := vj.(float64); !ok {
-					break
-				} else {
-					c = cmp.Compare(vit, vjt)
-				}
-			case string:
-				if vjt, ok := vj.(string); !ok {
-					break
-				} else {
-					c = cmp.Compare(vit, vjt)
-				}
-			}
-			if c == -1 { // can't convert to primitive
-				c = cmp.Compare(fmt.Sprint(vi), fmt.Sprint(vj))
-			}
-			if c != 0 { // check next primary key
+			if c := compareKeyValue(ri[i].Value, rj[i].Value); c != 0 { // check next primary key
 				return c
 			}
 		}
@@ -0,0 +1,77 @@
+package dbtestify
+
+import (
+	"maps"
+	"slices"
+)
+
+// DataSetDiff is the result of DiffDataSets.
+type DataSetDiff struct {
+	Tables       []AssertTableResult // Tables in both data sets. Expect of each field is the value in the first data set, and Actual is the one in the second.
+	OnlyInFirst  []string            // Tables only in the first data set.
+	OnlyInSecond []string            // Tables only in the second data set.
+}
+
+// DataSetDiff.IsMatch returns true if both data sets have the same tables and rows.
+func (d DataSetDiff) IsMatch() bool {
+	if len(d.OnlyInFirst) > 0 || len(d.OnlyInSecond) > 0 {
+		return false
+	}
+	for _, t := range d.Tables {
+		if t.Status != Match {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffDataSets compares rows of two data sets without database. It is for reviewing changes of data set files.
+//
+// Rows are paired by all of their columns, so a modified row is reported as a row only in the first data set and a row only in the second.
+// Placeholders like `[null]` are compared as they are. Tags, `_seed_only` and `_assert_only` are ignored and all rows are compared.
+// Tables are ordered by name.
+func DiffDataSets(first, second *DataSet) DataSetDiff {
+	var result DataSetDiff
+	names := map[string]bool{}
+	for _, t := range first.Tables {
+		names[t.Name] = true
+	}
+	for _, t := range second.Tables {
+		names[t.Name] = true
+	}
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		ft, inFirst := first.GetTable(name)
+		st, inSecond := second.GetTable(name)
+		switch {
+		case !inSecond:
+			result.OnlyInFirst = append(result.OnlyInFirst, name)
+		case !inFirst:
+			result.OnlyInSecond = append(result.OnlyInSecond, name)
+		default:
+			// all columns of both tables are used as a synthetic primary key
+			columns := map[string]bool{}
+			for _, row := range slices.Concat(ft.Rows, st.Rows) {
+				for k := range row {
+					columns[k] = true
+				}
+			}
+			pKeys := slices.Sorted(maps.Keys(columns))
+			result.Tables = append(result.Tables, compareTable(name, ExactMatchStrategy, pKeys, diffRows(ft, pKeys), diffRows(st, pKeys), 0))
+		}
+	}
+	return result
+}
+
+// diffRows converts all rows of the table into sorted rows that have all columns. Missing columns are nil.
+func diffRows(t *Table, columns []string) [][]Value {
+	result := make([][]Value, 0, len(t.Rows))
+	for _, src := range t.Rows {
+		row := make([]Value, len(columns))
+		for i, c := range columns {
+			row[i] = Value{Key: c, Value: src[c]}
+		}
+		result = append(result, row)
+	}
+	sortRow(result, columns)
+	return result
+}
@@ -0,0 +1,64 @@
+package dbtestify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestDiffDataSets(t *testing.T) {
+	parse := func(src string) *DataSet {
+		t.Helper()
+		data, err := ParseYAML(strings.NewReader(TrimIndent(t, src)))
+		assert.NoError(t, err)
+		return data
+	}
+	first := parse(`
+		user:
+		- { id: 1, name: Frank }
+		- { id: 2, name: Grace }
+		- { id: 3, name: Heidi, _tag: [admin] }
+		group:
+		- { id: 1, name: admin }
+		history:
+		- { id: 1 }
+		`)
+	second := parse(`
+		user:
+		- { id: 3, name: Heidi }
+		- { id: 1, name: Frank }
+		- { id: 2, name: Ivy, email: ivy@example.com }
+		group:
+		- { id: 1, name: admin }
+		item:
+		- { id: 1 }
+		`)
+
+	diff := DiffDataSets(first, second)
+	assert.False(t, diff.IsMatch())
+	assert.Equal(t, []string{"history"}, diff.OnlyInFirst)
+	assert.Equal(t, []string{"item"}, diff.OnlyInSecond)
+	assert.Equal(t, 2, len(diff.Tables))
+
+	group := diff.Tables[0]
+	assert.Equal(t, "group", group.Name)
+	assert.Equal(t, Match, group.Status)
+
+	user := diff.Tables[1]
+	assert.Equal(t, "user", user.Name)
+	assert.Equal(t, NotMatch, user.Status)
+	assert.Equal(t, []string{"email", "id", "name"}, user.PrimaryKeys)
+	var statuses []AssertStatus
+	for _, r := range user.Rows {
+		statuses = append(statuses, r.Status)
+	}
+	// rows without email come first because nil is sorted as "<nil>"
+	assert.Equal(t, []AssertStatus{Match, OnlyOnExpect, Match, OnlyOnActual}, statuses)
+	assert.Equal(t, Diff{Key: "name", Expect: "Grace"}, user.Rows[1].Fields[2])
+	assert.Equal(t, Diff{Key: "email", Actual: "ivy@example.com"}, user.Rows[3].Fields[0])
+
+	t.Run("same data sets", func(t *testing.T) {
+		assert.True(t, DiffDataSets(first, first).IsMatch())
+	})
+}
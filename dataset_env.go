@@ -0,0 +1,164 @@
+package dbtestify
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrUnresolvedEnv is returned by ParseYAML when `${VAR}` in the dataset refers to an undefined variable.
+var ErrUnresolvedEnv = errors.New("unresolved environment variable")
+
+// ParseOpt is an option of ParseYAML, ParseYAMLWithEnv and ParseYAMLStreaming.
+type ParseOpt func(e *envLookup)
+
+// KeepUnresolvedEnv keeps `${VAR}` of undefined variables as is instead of returning ErrUnresolvedEnv.
+func KeepUnresolvedEnv() ParseOpt {
+	return func(e *envLookup) {
+		e.keepUnresolved = true
+	}
+}
+
+// envLookup resolves `${VAR}` in datasets.
+type envLookup struct {
+	lookup         func(string) (string, bool)
+	keepUnresolved bool
+}
+
+func newEnvLookup(lookup func(string) (string, bool), opts []ParseOpt) envLookup {
+	e := envLookup{lookup: lookup}
+	for _, opt := range opts {
+		opt(&e)
+	}
+	return e
+}
+
+// ParseYAMLWithEnv is the same as ParseYAML, but `${VAR}` in string values is replaced with env instead of environment variables.
+func ParseYAMLWithEnv(r io.Reader, env map[string]string, opts ...ParseOpt) (*DataSet, error) {
+	return parseYAML(r, newEnvLookup(func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}, opts))
+}
+
+func parseYAML(r io.Reader, env envLookup) (*DataSet, error) {
+	d, err := decodeYAML(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.expandEnv(env); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// expandEnv replaces `${VAR}` in string values of rows and `_where` directive.
+func (d *DataSet) expandEnv(env envLookup) error {
+	for _, t := range d.Tables {
+		for i, row := range t.Rows {
+			for k, v := range row {
+				ev, err := expandEnvValue(v, env)
+				if err != nil {
+					return fmt.Errorf("table %s row %d field %s: %w", t.Name, i, k, err)
+				}
+				row[k] = ev
+			}
+		}
+	}
+	for t, w := range d.Where {
+		ew, err := expandEnv(w, env)
+		if err != nil {
+			return fmt.Errorf("_where of table %s: %w", t, err)
+		}
+		d.Where[t] = ew
+	}
+	return nil
+}
+
+func expandEnvValue(v any, env envLookup) (any, error) {
+	switch vv := v.(type) {
+	case string:
+		return expandEnv(vv, env)
+	case []any:
+		for i, item := range vv {
+			ev, err := expandEnvValue(item, env)
+			if err != nil {
+				return nil, err
+			}
+			vv[i] = ev
+		}
+	case map[string]any:
+		for k, item := range vv {
+			ev, err := expandEnvValue(item, env)
+			if err != nil {
+				return nil, err
+			}
+			vv[k] = ev
+		}
+	}
+	return v, nil
+}
+
+// expandEnv replaces `${VAR}` in s. The variable name can have nested references like `${DB_${STAGE}}`.
+//
+// `$${` is an escape of literal `${`. Replaced values are not expanded again.
+func expandEnv(s string, env envLookup) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start == -1 {
+			break
+		}
+		if start > 0 && s[start-1] == '$' {
+			b.WriteString(s[:start] + "{")
+			s = s[start+2:]
+			continue
+		}
+		end := closingBrace(s, start+2)
+		if end == -1 { // not terminated
+			break
+		}
+		name, err := expandEnv(s[start+2:end], env)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:start])
+		if v, ok := env.lookup(name); ok {
+			b.WriteString(v)
+		} else if env.keepUnresolved {
+			b.WriteString(s[start : end+1])
+		} else {
+			return "", fmt.Errorf("%w: %s", ErrUnresolvedEnv, name)
+		}
+		s = s[end+1:]
+	}
+	b.WriteString(s)
+	return b.String(), nil
+}
+
+// closingBrace returns the index of `}` that closes `${` before from. It returns -1 if it is not found.
+func closingBrace(s string, from int) int {
+	depth := 0
+	for i := from; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// escapeEnv escapes `${` so that ParseYAML reads s as is.
+func escapeEnv(s string) string {
+	return strings.ReplaceAll(s, "${", "$${")
+}
@@ -0,0 +1,96 @@
+package dbtestify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestParseYAMLWithEnv(t *testing.T) {
+	env := map[string]string{
+		"ADMIN_NAME":    "Frank",
+		"STAGE":         "CI",
+		"DOMAIN_CI":     "ci.example.com",
+		"MIN_ID":        "10",
+		"WITH_TEMPLATE": "${ADMIN_NAME}",
+	}
+	tests := []struct {
+		name    string
+		src     string
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name: "replace",
+			src:  `user: [{ id: 1, name: "${ADMIN_NAME}", email: "${ADMIN_NAME}@${DOMAIN_CI}" }]`,
+			want: map[string]any{"id": 1, "name": "Frank", "email": "Frank@ci.example.com"},
+		},
+		{
+			name: "nested variable name",
+			src:  `user: [{ id: 1, email: "admin@${DOMAIN_${STAGE}}" }]`,
+			want: map[string]any{"id": 1, "email": "admin@ci.example.com"},
+		},
+		{
+			name: "replaced value is not expanded again",
+			src:  `user: [{ id: 1, name: "${WITH_TEMPLATE}" }]`,
+			want: map[string]any{"id": 1, "name": "${ADMIN_NAME}"},
+		},
+		{
+			name: "non-string and unterminated values are kept",
+			src:  `user: [{ id: 1, name: "${ADMIN_NAME", deleted_at: [null] }]`,
+			want: map[string]any{"id": 1, "name": "${ADMIN_NAME", "deleted_at": []any{nil}},
+		},
+		{
+			name: "escape",
+			src:  `user: [{ id: 1, name: "$${ADMIN_NAME} ${ADMIN_NAME}", email: "$$${ADMIN_NAME}" }]`,
+			want: map[string]any{"id": 1, "name": "${ADMIN_NAME} Frank", "email": "$${ADMIN_NAME}"},
+		},
+		{
+			name:    "missing variable",
+			src:     `user: [{ id: 1, name: "${UNKNOWN}" }]`,
+			wantErr: "table user row 0 field name: unresolved environment variable: UNKNOWN",
+		},
+		{
+			name:    "missing nested variable",
+			src:     `user: [{ id: 1, email: "admin@${DOMAIN_${UNKNOWN}}" }]`,
+			wantErr: "unresolved environment variable: UNKNOWN",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ParseYAMLWithEnv(strings.NewReader(tt.src), env)
+			if tt.wantErr != "" {
+				assert.IsError(t, err, ErrUnresolvedEnv)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []map[string]any{tt.want}, data.Tables[0].Rows)
+		})
+	}
+
+	t.Run("_where", func(t *testing.T) {
+		data, err := ParseYAMLWithEnv(strings.NewReader(TrimIndent(t, `
+			_where:
+			  user: id >= ${MIN_ID}
+			user:
+			- { id: 10 }
+			`)), env)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"user": "id >= 10"}, data.Where)
+	})
+
+	t.Run("keep unresolved", func(t *testing.T) {
+		data, err := ParseYAMLWithEnv(strings.NewReader(`user: [{ id: 1, name: "${UNKNOWN} ${ADMIN_NAME}" }]`), env, KeepUnresolvedEnv())
+		assert.NoError(t, err)
+		assert.Equal(t, "${UNKNOWN} Frank", data.Tables[0].Rows[0]["name"])
+	})
+}
+
+func TestParseYAMLEnv(t *testing.T) {
+	t.Setenv("DBTESTIFY_TEST_NAME", "Grace")
+	data, err := ParseYAML(strings.NewReader(`user: [{ id: 1, name: "${DBTESTIFY_TEST_NAME}" }]`))
+	assert.NoError(t, err)
+	assert.Equal(t, "Grace", data.Tables[0].Rows[0]["name"])
+}
@@ -0,0 +1,207 @@
+package dbtestify
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// TableRow is a row of the dataset emitted by ParseYAMLStreaming.
+type TableRow struct {
+	Table      string
+	Index      int // Index of the row in the table
+	Row        map[string]any
+	Tags       []string
+	SeedOnly   bool
+	AssertOnly bool
+}
+
+// ParseYAMLStreaming reads a YAML formatted dataset and emits the rows one by one, so the whole dataset is not kept in memory.
+//
+// It reads top level tables that have block sequences of rows like:
+//
+//	user:
+//	- { id: 1, name: Frank }
+//	- id: 2
+//	  name: Grace
+//
+// Directives like `_operation` are not supported. Use SeedOpt instead.
+// `_tag`, `_seed_only`, `_assert_only`, `_comment` and `${VAR}` in rows work as ParseYAML.
+//
+// Read rows until the channel is closed, and then read the error channel. It has at most one error.
+func ParseYAMLStreaming(r io.Reader, opts ...ParseOpt) (<-chan *TableRow, <-chan error) {
+	rows := make(chan *TableRow, 100)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(rows)
+		err := streamYAML(r, newEnvLookup(os.LookupEnv, opts), func(row *TableRow) {
+			rows <- row
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return rows, errs
+}
+
+// streamYAML splits the input into tables and rows by indentation, and decodes each row separately.
+func streamYAML(r io.Reader, env envLookup, emit func(row *TableRow)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var table string
+	var index int
+	var item []string
+	var itemLine int
+	itemIndent := -1
+	started := false
+	inline := false // rows are written in the line of the table name
+
+	emitRow := func(rowSrc map[string]any) error {
+		rowMap, tags, seedOnly, assertOnly, err := parseRow(rowSrc)
+		if err != nil {
+			return err
+		}
+		for k, v := range rowMap {
+			ev, err := expandEnvValue(v, env)
+			if err != nil {
+				return fmt.Errorf("table %s row %d field %s: %w", table, index, k, err)
+			}
+			rowMap[k] = ev
+		}
+		emit(&TableRow{
+			Table:      table,
+			Index:      index,
+			Row:        rowMap,
+			Tags:       tags,
+			SeedOnly:   seedOnly,
+			AssertOnly: assertOnly,
+		})
+		index++
+		return nil
+	}
+	flush := func() error {
+		if len(item) == 0 {
+			return nil
+		}
+		var items []any
+		err := yaml.Unmarshal([]byte(strings.Join(item, "\n")), &items)
+		item = item[:0]
+		if err != nil {
+			return fmt.Errorf("line %d: %w", itemLine, err)
+		}
+		rows, err := rowsOf(items)
+		if err != nil {
+			return fmt.Errorf("line %d: failed to unmarshal key %s: %w", itemLine, table, err)
+		}
+		for _, row := range rows {
+			if err := emitRow(row); err != nil {
+				return fmt.Errorf("line %d: %w", itemLine, err)
+			}
+		}
+		return nil
+	}
+
+	var lineNo int
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNo++
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		// keep blank lines and comments in the row for block scalars
+		if trimmed == "" {
+			if len(item) > 0 {
+				item = append(item, "")
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			if len(item) > 0 && indent > itemIndent {
+				item = append(item, line[itemIndent:])
+			}
+			continue
+		}
+		if indent == 0 && (line == "---" || line == "...") {
+			if started {
+				// only the first document is read like ParseYAML
+				break
+			}
+			continue
+		}
+		started = true
+		if indent == 0 && !strings.HasPrefix(line, "-") {
+			// table name
+			if err := flush(); err != nil {
+				return err
+			}
+			key, rest, ok := strings.Cut(line, ":")
+			if !ok {
+				return fmt.Errorf("line %d: table name should end with ':', but: '%s'", lineNo, line)
+			}
+			key = strings.Trim(strings.TrimSpace(key), `"'`)
+			if strings.HasPrefix(key, "_") {
+				return fmt.Errorf("line %d: directive %s is not supported by ParseYAMLStreaming", lineNo, key)
+			}
+			if err := checkTableName(key); err != nil {
+				return fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			table = key
+			index = 0
+			itemIndent = -1
+			inline = false
+			if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+				inline = true
+				// flow sequence like `user: [{ id: 1 }]`
+				var val any
+				if err := yaml.Unmarshal([]byte(rest), &val); err != nil {
+					return fmt.Errorf("line %d: %w", lineNo, err)
+				}
+				rows, err := rowsOf(val)
+				if err != nil {
+					return fmt.Errorf("line %d: failed to unmarshal key %s: %w", lineNo, key, err)
+				}
+				for _, row := range rows {
+					if err := emitRow(row); err != nil {
+						return fmt.Errorf("line %d: %w", lineNo, err)
+					}
+				}
+			}
+			continue
+		}
+		if table == "" {
+			return fmt.Errorf("line %d: row should be in a table", lineNo)
+		}
+		if inline {
+			return fmt.Errorf("line %d: unexpected indentation in table %s", lineNo, table)
+		}
+		if itemIndent == -1 || indent == itemIndent {
+			if trimmed != "-" && !strings.HasPrefix(trimmed, "- ") {
+				return fmt.Errorf("line %d: row of table %s should be a sequence item", lineNo, table)
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			itemIndent = indent
+			itemLine = lineNo
+			item = append(item, line[indent:])
+			continue
+		}
+		if indent < itemIndent || len(item) == 0 {
+			return fmt.Errorf("line %d: unexpected indentation in table %s", lineNo, table)
+		}
+		item = append(item, line[itemIndent:])
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("line %d: %w", lineNo+1, err)
+		}
+		return err
+	}
+	return flush()
+}
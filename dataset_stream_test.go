@@ -0,0 +1,120 @@
+package dbtestify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func collectRows(t *testing.T, src string) ([]*TableRow, error) {
+	t.Helper()
+	rows, errs := ParseYAMLStreaming(strings.NewReader(src))
+	var result []*TableRow
+	for row := range rows {
+		result = append(result, row)
+	}
+	return result, <-errs
+}
+
+func TestParseYAMLStreaming(t *testing.T) {
+	t.Setenv("DBTESTIFY_TEST_NAME", "Grace")
+	src := TrimIndent(t, `
+		---
+		# users
+		user:
+		- { id: 1, name: Frank, _tag: [admin] }
+		- id: 2
+		  name: ${DBTESTIFY_TEST_NAME}
+		  # comment in a row
+		  profile: |
+		    line 1
+
+		    line 2
+		  _comment: second user
+		-   id: 3
+		    name: Ivy
+		    _assert_only: true
+		item: [{ id: 10 }, { id: 11, _seed_only: true }]
+		empty: []
+		`)
+	rows, err := collectRows(t, src)
+	assert.NoError(t, err)
+	assert.Equal(t, []*TableRow{
+		{Table: "user", Index: 0, Row: map[string]any{"id": 1, "name": "Frank"}, Tags: []string{"admin"}},
+		{Table: "user", Index: 1, Row: map[string]any{"id": 2, "name": "Grace", "profile": "line 1\n\nline 2\n"}},
+		{Table: "user", Index: 2, Row: map[string]any{"id": 3, "name": "Ivy"}, AssertOnly: true},
+		{Table: "item", Index: 0, Row: map[string]any{"id": 10}},
+		{Table: "item", Index: 1, Row: map[string]any{"id": 11}, SeedOnly: true},
+	}, rows)
+
+	// the same rows as ParseYAML
+	data, err := ParseYAML(strings.NewReader(src))
+	assert.NoError(t, err)
+	for _, row := range rows {
+		table := findTable(t, data, row.Table)
+		assert.Equal(t, table.Rows[row.Index], row.Row)
+	}
+
+	// a row of "empty" that has an unexpected indentation
+	_, err = collectRows(t, src+"\n  - { id: 20 }\n")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected indentation in table empty")
+}
+
+func TestParseYAMLStreamingError(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name:    "directive",
+			src:     "_operation:\n  user: upsert\nuser:\n- { id: 1 }\n",
+			wantErr: "line 1: directive _operation is not supported by ParseYAMLStreaming",
+		},
+		{
+			name:    "invalid table name",
+			src:     "a.b.c:\n- { id: 1 }\n",
+			wantErr: "line 1: invalid table name a.b.c",
+		},
+		{
+			name:    "row without table",
+			src:     "- { id: 1 }\n",
+			wantErr: "line 1: row should be in a table",
+		},
+		{
+			name:    "not a sequence",
+			src:     "user:\n  id: 1\n",
+			wantErr: "line 2: row of table user should be a sequence item",
+		},
+		{
+			name:    "not a mapping",
+			src:     "user:\n- { id: 1 }\n- 2\n",
+			wantErr: "line 3: failed to unmarshal key user: row should be a mapping",
+		},
+		{
+			name:    "invalid tag",
+			src:     "user:\n- { id: 1, _tag: { a: b } }\n",
+			wantErr: "line 2: parse error: tag should be string",
+		},
+		{
+			name:    "unresolved environment variable",
+			src:     "user:\n- { id: 1, name: '${DBTESTIFY_UNKNOWN}' }\n",
+			wantErr: "line 2: table user row 0 field name: unresolved environment variable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := collectRows(t, tt.src)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("only the first document", func(t *testing.T) {
+		rows, err := collectRows(t, "user:\n- { id: 1 }\n---\nitem:\n- { id: 2 }\n")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(rows))
+	})
+}
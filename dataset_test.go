@@ -1,9 +1,13 @@
 package dbtestify
 
 import (
+	"errors"
+	"io/fs"
 	"log"
+	"slices"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/alecthomas/assert/v2"
 )
@@ -47,7 +51,7 @@ user:
 	assert.NoError(t, err)
 	// wrong primary key: email is not exists
 	_, err = data.Tables[0].SortAndFilter([]string{"email"}, []string{"b"}, []string{"a"})
-	assert.Error(t, err)
+	assert.IsError(t, err, ErrMissingPrimaryKeyType)
 	normalizedTable, err := data.Tables[0].SortAndFilter([]string{"name"}, []string{"b"}, []string{"a"})
 	assert.NoError(t, err)
 	assert.Equal(t, &NormalizedTable{
@@ -58,6 +62,271 @@ user:
 	}, normalizedTable)
 }
 
+func TestSortAndFilterBatch(t *testing.T) {
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		user:
+		- { id: 4, _tag: a }
+		- { id: 2 }
+		- { id: 5 }
+		- { id: 1 }
+		- { id: 3, _tag: a }
+		`)))
+	assert.NoError(t, err)
+	ids := func(table *NormalizedTable) []any {
+		var result []any
+		for _, row := range table.Rows {
+			result = append(result, row[0].Value)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		excludeTags   []string
+		want          []any
+	}{
+		{name: "first chunk", offset: 0, limit: 2, want: []any{1, 2}},
+		{name: "middle chunk", offset: 2, limit: 2, want: []any{3, 4}},
+		{name: "last partial chunk", offset: 4, limit: 2, want: []any{5}},
+		{name: "exact end", offset: 3, limit: 2, want: []any{4, 5}},
+		{name: "offset at end", offset: 5, limit: 2, want: nil},
+		{name: "offset past end", offset: 10, limit: 2, want: nil},
+		{name: "limit 0 returns all", offset: 0, limit: 0, want: []any{1, 2, 3, 4, 5}},
+		{name: "limit 0 from offset", offset: 3, limit: 0, want: []any{4, 5}},
+		{name: "limit larger than rows", offset: 0, limit: 100, want: []any{1, 2, 3, 4, 5}},
+		{name: "offset after filter", offset: 1, limit: 2, excludeTags: []string{"a"}, want: []any{2, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := data.Tables[0].SortAndFilterBatch([]string{"id"}, nil, tt.excludeTags, tt.offset, tt.limit)
+			assert.NoError(t, err)
+			assert.Equal(t, "user", table.Name)
+			assert.Equal(t, tt.want, ids(table))
+		})
+	}
+
+	t.Run("same as SortAndFilter", func(t *testing.T) {
+		all, err := data.Tables[0].SortAndFilter([]string{"id"}, nil, nil)
+		assert.NoError(t, err)
+		batch, err := data.Tables[0].SortAndFilterBatch([]string{"id"}, nil, nil, 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, all, batch)
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		_, err := data.Tables[0].SortAndFilterBatch([]string{"id"}, nil, nil, -1, 2)
+		assert.Error(t, err)
+		_, err = data.Tables[0].SortAndFilterBatch([]string{"id"}, nil, nil, 0, -1)
+		assert.Error(t, err)
+	})
+
+	t.Run("batches", func(t *testing.T) {
+		for _, limit := range []int{0, 1, 2, 5, 10} {
+			var chunks [][]any
+			err := data.Tables[0].SortAndFilterBatches([]string{"id"}, nil, nil, limit, func(chunk *NormalizedTable) error {
+				assert.Equal(t, "user", chunk.Name)
+				want, err := data.Tables[0].SortAndFilterBatch([]string{"id"}, nil, nil, len(chunks)*limit, limit)
+				assert.NoError(t, err)
+				assert.Equal(t, ids(want), ids(chunk))
+				chunks = append(chunks, ids(chunk))
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, []any{1, 2, 3, 4, 5}, slices.Concat(chunks...), "limit: %d", limit)
+			if limit > 0 {
+				assert.Equal(t, (5+limit-1)/limit, len(chunks), "limit: %d", limit)
+			}
+		}
+
+		stop := errors.New("stop")
+		calls := 0
+		err := data.Tables[0].SortAndFilterBatches([]string{"id"}, nil, []string{"a"}, 1, func(chunk *NormalizedTable) error {
+			calls++
+			return stop
+		})
+		assert.IsError(t, err, stop)
+		assert.Equal(t, 1, calls)
+
+		err = data.Tables[0].SortAndFilterBatches([]string{"id"}, []string{"unknown"}, nil, 2, func(chunk *NormalizedTable) error {
+			t.Fatal("fn should not be called without rows")
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Error(t, data.Tables[0].SortAndFilterBatches([]string{"id"}, nil, nil, -1, nil))
+	})
+}
+
+func TestErrMissingPrimaryKey(t *testing.T) {
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		user:
+		- { id: 1, name: Frank }
+		- { name: Grace }
+		`)))
+	assert.NoError(t, err)
+	_, err = data.Tables[0].SortAndFilter([]string{"id"}, nil, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "table 'user' row 1: missing primary keys: [id]")
+
+	assert.True(t, errors.Is(err, ErrMissingPrimaryKeyType))
+	assert.True(t, errors.Is(err, &ErrMissingPrimaryKey{MissingKeys: []string{"other"}}))
+	assert.True(t, errors.Is(err, ErrMissingPrimaryKey{}))
+	assert.False(t, errors.Is(err, ErrCyclicDependency))
+
+	var e *ErrMissingPrimaryKey
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, []string{"id"}, e.MissingKeys)
+	assert.Equal(t, `{"name":"Grace"}`, e.Dump)
+	// the sentinel is not modified
+	assert.Equal(t, &ErrMissingPrimaryKey{}, ErrMissingPrimaryKeyType)
+}
+
+func TestParseYAMLSchemaQualifiedTable(t *testing.T) {
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		analytics.events:
+		- { id: 1 }
+		`)))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"analytics.events"}, data.TableNames())
+
+	for _, name := range []string{"a.b.c", ".events", "analytics."} {
+		_, err := ParseYAML(strings.NewReader(name + ":\n- { id: 1 }\n"))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestMatchTargetTables(t *testing.T) {
+	tables := []string{"audit_access", "audit_query", "payment_log", "log_a", "log_ab"}
+	tests := []struct {
+		name    string
+		targets []string
+		want    []string
+	}{
+		{name: "empty", targets: nil, want: tables},
+		{name: "exact", targets: []string{"audit_access", "log"}, want: []string{"audit_access"}},
+		{name: "star", targets: []string{"audit_*"}, want: []string{"audit_access", "audit_query"}},
+		{name: "question", targets: []string{"log_?"}, want: []string{"log_a"}},
+		{name: "mixed", targets: []string{"payment_log", "log_*"}, want: []string{"payment_log", "log_a", "log_ab"}},
+		{name: "invalid pattern", targets: []string{"audit_[*"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, name := range tables {
+				if MatchTargetTables(tt.targets, name) {
+					got = append(got, name)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseYAMLComment(t *testing.T) {
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		_operation:
+		  user: upsert
+		user:
+		- { id: 1, name: Frank, _comment: "admin user for permission tests" }
+		- { id: 2, name: Grace, _tag: [a], _comment_ja: "一般ユーザー" }
+		- { id: 3, name: Heidi, _comments: [first, second] }
+		`)))
+	assert.NoError(t, err)
+	user := findTable(t, data, "user")
+	assert.Equal(t, []map[string]any{
+		{"id": 1, "name": "Frank"},
+		{"id": 2, "name": "Grace"},
+		{"id": 3, "name": "Heidi"},
+	}, user.Rows)
+	assert.Equal(t, [][]string{nil, {"a"}, nil}, user.Tags)
+	assert.Equal(t, map[string]Operation{"user": UpsertOperation}, data.Operation)
+}
+
+func TestParseYAMLTemplate(t *testing.T) {
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		user:
+		- { id: 1, name: Frank }
+		- { id: 2, name: Grace, tier: premium }
+		- { id: 3, name: Heidi, tier: null }
+		- { id: 4, name: Ivan, _tag: [b] }
+		_template:
+		  user: { status: active, tier: free, login_count: 0, _tag: [a] }
+		group:
+		- { id: 1 }
+		`)))
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{
+		{"id": 1, "name": "Frank", "status": "active", "tier": "free", "login_count": 0},
+		{"id": 2, "name": "Grace", "status": "active", "tier": "premium", "login_count": 0},
+		{"id": 3, "name": "Heidi", "status": "active", "tier": nil, "login_count": 0},
+		{"id": 4, "name": "Ivan", "status": "active", "tier": "free", "login_count": 0},
+	}, findTable(t, data, "user").Rows)
+	assert.Equal(t, [][]string{{"a"}, {"a"}, {"a"}, {"b"}}, findTable(t, data, "user").Tags)
+	// template of other table is not applied
+	assert.Equal(t, []map[string]any{{"id": 1}}, findTable(t, data, "group").Rows)
+	assert.Equal(t, []string{"group", "user"}, data.TableNames())
+
+	_, err = ParseYAML(strings.NewReader("_template:\n  user: [status]\nuser:\n- { id: 1 }\n"))
+	assert.Error(t, err)
+}
+
+func TestParseYAMLGenerate(t *testing.T) {
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		_generate:
+		  user: { count: 1000, id: sequence, name: "User ${n}", email: "user${n}@test.com", active: true, deleted_at: [null] }
+		  group: { count: 2, id: sequence }
+		group:
+		- { id: 0 }
+		`)))
+	assert.NoError(t, err)
+
+	user := findTable(t, data, "user")
+	assert.Equal(t, 1000, len(user.Rows))
+	assert.Equal(t, 1000, len(user.Tags))
+	assert.Equal(t, map[string]any{"id": 1, "name": "User 1", "email": "user1@test.com", "active": true, "deleted_at": []any{nil}}, user.Rows[0])
+	assert.Equal(t, map[string]any{"id": 1000, "name": "User 1000", "email": "user1000@test.com", "active": true, "deleted_at": []any{nil}}, user.Rows[999])
+
+	// generated rows are appended to the rows in the table
+	assert.Equal(t, []map[string]any{{"id": 0}, {"id": 1}, {"id": 2}}, findTable(t, data, "group").Rows)
+
+	for _, src := range []string{
+		"_generate:\n  user: { id: sequence }\n",
+		"_generate:\n  user: { count: 0, id: sequence }\n",
+		"_generate:\n  user: [id]\n",
+	} {
+		_, err := ParseYAML(strings.NewReader(src))
+		assert.Error(t, err, src)
+	}
+}
+
+func TestLoadYAMLWithSeedOnlyAndAssertOnly(t *testing.T) {
+	source := `
+user:
+- { name: Frank, luckyNumber: 10, _seed_only: true }
+- { name: Grace, luckyNumber: 12, _assert_only: true }
+- { name: Heidi, luckyNumber: 14 }
+`
+	data, err := ParseYAML(strings.NewReader(source))
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false, false}, data.Tables[0].SeedOnly)
+	assert.Equal(t, []bool{false, true, false}, data.Tables[0].AssertOnly)
+	normalizedTable, err := data.Tables[0].SortAndFilter([]string{"name"}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &NormalizedTable{
+		Name: "user",
+		Rows: [][]Value{
+			{Value{"name", "Grace"}, Value{"luckyNumber", 12}},
+			{Value{"name", "Heidi"}, Value{"luckyNumber", 14}},
+		},
+	}, normalizedTable)
+
+	_, err = ParseYAML(strings.NewReader(`
+user:
+- { name: Frank, _seed_only: yes please }
+`))
+	assert.Error(t, err)
+}
+
 func Test_filter(t *testing.T) {
 	type args struct {
 		src      []string
@@ -160,3 +429,358 @@ user:
 		"accesslog": SubMatchStrategy,
 	}, data.Match)
 }
+
+func TestDataSetMerge(t *testing.T) {
+	base, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		_operation:
+		    user: clear-insert
+		    group: upsert
+		_match:
+		    user: exact
+		    group: sub
+		user:
+		- { id: 1, name: Frank }
+		- { id: 2, name: Grace, _tag: a }
+		group:
+		- { id: 1, name: admin }
+		`)))
+	assert.NoError(t, err)
+	override, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		_operation:
+		    user: insert
+		    history: truncate
+		_match:
+		    group: exact
+		    history: sub
+		user:
+		- { id: 3, name: Heidi, _tag: b }
+		history:
+		- { id: 1, action: login }
+		`)))
+	assert.NoError(t, err)
+
+	merged := base.Merge(override)
+
+	t.Run("operation: other wins on conflict", func(t *testing.T) {
+		assert.Equal(t, map[string]Operation{
+			"user":    InsertOperation,
+			"group":   UpsertOperation,
+			"history": TruncateOperation,
+		}, merged.Operation)
+	})
+	t.Run("match: other wins on conflict", func(t *testing.T) {
+		assert.Equal(t, map[string]MatchStrategy{
+			"user":    ExactMatchStrategy,
+			"group":   ExactMatchStrategy,
+			"history": SubMatchStrategy,
+		}, merged.Match)
+	})
+	t.Run("tables: rows are appended", func(t *testing.T) {
+		names := map[string]int{}
+		for _, table := range merged.Tables {
+			names[table.Name] = len(table.Rows)
+			assert.Equal(t, len(table.Rows), len(table.Tags))
+		}
+		assert.Equal(t, map[string]int{"user": 3, "group": 1, "history": 1}, names)
+
+		i := slices.IndexFunc(merged.Tables, func(t *Table) bool { return t.Name == "user" })
+		normalizedTable, err := merged.Tables[i].SortAndFilter([]string{"id"}, []string{"b"}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, [][]Value{
+			{Value{"id", 3}, Value{"name", "Heidi"}},
+		}, normalizedTable.Rows)
+	})
+	t.Run("source datasets are not modified", func(t *testing.T) {
+		i := slices.IndexFunc(base.Tables, func(t *Table) bool { return t.Name == "user" })
+		assert.Equal(t, 2, len(base.Tables[i].Rows))
+		assert.Equal(t, ClearInsertOperation, base.Operation["user"])
+		assert.Equal(t, SubMatchStrategy, base.Match["group"])
+		assert.Equal(t, 2, len(override.Operation))
+	})
+	t.Run("merge with nil", func(t *testing.T) {
+		m := base.Merge(nil)
+		assert.Equal(t, base.Operation, m.Operation)
+		assert.Equal(t, base.Match, m.Match)
+		assert.Equal(t, len(base.Tables), len(m.Tables))
+	})
+}
+
+func TestParseYAMLReaders(t *testing.T) {
+	data, err := ParseYAMLReaders(
+		strings.NewReader(TrimIndent(t, `
+			_operation:
+			    user: clear-insert
+			user:
+			- { id: 1, name: Frank }
+			`)),
+		strings.NewReader(TrimIndent(t, `
+			_operation:
+			    user: upsert
+			user:
+			- { id: 2, name: Grace }
+			`)),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Operation{"user": UpsertOperation}, data.Operation)
+	assert.Equal(t, 1, len(data.Tables))
+	assert.Equal(t, 2, len(data.Tables[0].Rows))
+
+	_, err = ParseYAMLReaders(
+		strings.NewReader("user:\n- { id: 1 }\n"),
+		strings.NewReader("user: [ broken\n"),
+	)
+	assert.Error(t, err)
+}
+
+func TestParseYAMLFiles(t *testing.T) {
+	root := fstest.MapFS{
+		"base.yaml": {Data: []byte(TrimIndent(t, `
+			_operation:
+			    user: clear-insert
+			_match:
+			    user: exact
+			user:
+			- { id: 1, name: Frank }
+			group:
+			- { id: 1, name: admin }
+			`))},
+		"override.yaml": {Data: []byte(TrimIndent(t, `
+			_operation:
+			    user: upsert
+			_match:
+			    user: sub
+			user:
+			- { id: 2, name: Grace }
+			`))},
+	}
+
+	t.Run("operation and match override", func(t *testing.T) {
+		data, err := ParseYAMLFiles(root, "base.yaml", "override.yaml")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]Operation{"user": UpsertOperation}, data.Operation)
+		assert.Equal(t, map[string]MatchStrategy{"user": SubMatchStrategy}, data.Match)
+	})
+	t.Run("row append", func(t *testing.T) {
+		data, err := ParseYAMLFiles(root, "base.yaml", "override.yaml")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(data.Tables))
+		user := findTable(t, data, "user")
+		assert.Equal(t, []map[string]any{
+			{"id": 1, "name": "Frank"},
+			{"id": 2, "name": "Grace"},
+		}, user.Rows)
+		assert.Equal(t, 2, len(user.Tags))
+		assert.Equal(t, 1, len(findTable(t, data, "group").Rows))
+	})
+	t.Run("missing file", func(t *testing.T) {
+		_, err := ParseYAMLFiles(root, "base.yaml", "missing.yaml")
+		assert.IsError(t, err, fs.ErrNotExist)
+		assert.Contains(t, err.Error(), "missing.yaml")
+	})
+}
+
+// findTable returns the table by name because the order of tables in YAML is not kept.
+func findTable(t *testing.T, data *DataSet, name string) *Table {
+	t.Helper()
+	table, ok := data.GetTable(name)
+	if !ok {
+		t.Fatalf("table %s is not found", name)
+	}
+	return table
+}
+
+func TestGetTable(t *testing.T) {
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		user:
+		- { id: 1, name: Frank }
+		group: []
+		access_log:
+		- { id: 1 }
+		- { id: 2 }
+		`)))
+	assert.NoError(t, err)
+
+	user, ok := data.GetTable("user")
+	assert.True(t, ok)
+	assert.Equal(t, "user", user.Name)
+	assert.Equal(t, 1, len(user.Rows))
+
+	log, ok := data.GetTable("access_log")
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(log.Rows))
+
+	_, ok = data.GetTable("missing")
+	assert.False(t, ok)
+	_, ok = (&DataSet{}).GetTable("user")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"access_log", "group", "user"}, data.TableNames())
+	assert.Equal(t, []string{}, (&DataSet{}).TableNames())
+}
+
+func TestFilterByTag(t *testing.T) {
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		_operation:
+		    user: upsert
+		user:
+		- { name: Frank }
+		- { name: Grace, _tag: [a, b] }
+		- { name: Heidi, _tag: a, _seed_only: true }
+		- { name: Ivan, _tag: b }
+		group:
+		- { name: admin, _tag: a }
+		`)))
+	assert.NoError(t, err)
+
+	names := func(table *Table) []string {
+		var result []string
+		for _, r := range table.Rows {
+			result = append(result, r["name"].(string))
+		}
+		return result
+	}
+
+	tests := []struct {
+		name        string
+		includeTags []string
+		excludeTags []string
+		want        []string
+	}{
+		{name: "no filter", want: []string{"Frank", "Grace", "Heidi", "Ivan"}},
+		{name: "include", includeTags: []string{"a"}, want: []string{"Grace", "Heidi"}},
+		{name: "include all of the tags", includeTags: []string{"a", "b"}, want: []string{"Grace"}},
+		{name: "exclude", excludeTags: []string{"a"}, want: []string{"Frank", "Ivan"}},
+		{name: "include and exclude", includeTags: []string{"b"}, excludeTags: []string{"a"}, want: []string{"Ivan"}},
+		{name: "no match", includeTags: []string{"c"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findTable(t, data, "user").FilterByTag(tt.includeTags, tt.excludeTags)
+			assert.Equal(t, "user", got.Name)
+			assert.Equal(t, tt.want, names(got))
+			assert.Equal(t, len(got.Rows), len(got.Tags))
+		})
+	}
+
+	t.Run("tags and flags follow the rows", func(t *testing.T) {
+		got := findTable(t, data, "user").FilterByTag([]string{"a"}, nil)
+		assert.Equal(t, [][]string{{"a", "b"}, {"a"}}, got.Tags)
+		assert.Equal(t, []bool{false, true}, got.SeedOnly)
+	})
+
+	t.Run("dataset", func(t *testing.T) {
+		got := data.FilterByTag(nil, []string{"a"})
+		assert.Equal(t, 2, len(got.Tables))
+		assert.Equal(t, []string{"Frank", "Ivan"}, names(findTable(t, got, "user")))
+		assert.Equal(t, 0, len(findTable(t, got, "group").Rows))
+		assert.Equal(t, data.Operation, got.Operation)
+	})
+
+	t.Run("original is not modified", func(t *testing.T) {
+		data.FilterByTag([]string{"b"}, nil)
+		assert.Equal(t, []string{"Frank", "Grace", "Heidi", "Ivan"}, names(findTable(t, data, "user")))
+		assert.Equal(t, 4, len(findTable(t, data, "user").Tags))
+		assert.Equal(t, 1, len(findTable(t, data, "group").Rows))
+	})
+}
+
+func TestLoadWithPKOverride(t *testing.T) {
+	source := `
+_pkey:
+    event_log: [occurred_at, event_id]
+event_log:
+- { event_id: 1, occurred_at: "2024-01-01", message: start }
+`
+	data, err := ParseYAML(strings.NewReader(source))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"event_log": {"occurred_at", "event_id"},
+	}, data.PKOverride)
+	assert.Equal(t, 1, len(data.Tables))
+}
+
+func TestLoadWithWhere(t *testing.T) {
+	source := `
+_where:
+    events: "tenant_id = 42"
+events:
+- { id: 1, tenant_id: 42 }
+`
+	data, err := ParseYAML(strings.NewReader(source))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"events": "tenant_id = 42",
+	}, data.Where)
+}
+
+func TestLoadWithOrder(t *testing.T) {
+	source := `
+_order:
+    event_log: [occurred_at, event_id]
+event_log:
+- { event_id: 1, occurred_at: "2024-01-01" }
+`
+	data, err := ParseYAML(strings.NewReader(source))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"event_log": {"occurred_at", "event_id"},
+	}, data.Order)
+}
+
+func TestLoadWithDependsOn(t *testing.T) {
+	source := `
+_depends_on:
+    orders: [customers, products]
+orders:
+- { id: 1, customer_id: 1, product_id: 1 }
+`
+	data, err := ParseYAML(strings.NewReader(source))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"orders": {"customers", "products"},
+	}, data.DependsOn)
+}
+
+func TestLoadWithSchema(t *testing.T) {
+	source := `
+_schema: analytics
+events:
+- { id: 1 }
+public.users:
+- { id: 1 }
+`
+	data, err := ParseYAML(strings.NewReader(source))
+	assert.NoError(t, err)
+	assert.Equal(t, "analytics", data.DefaultSchema)
+
+	for _, src := range []string{"_schema: a.b\n", "_schema: [a]\n", "_schema: \"\"\n"} {
+		_, err := ParseYAML(strings.NewReader(src))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "_schema should be a schema name")
+	}
+
+	merged := data.Merge(&DataSet{})
+	assert.Equal(t, "analytics", merged.DefaultSchema)
+	merged = data.Merge(&DataSet{DefaultSchema: "audit"})
+	assert.Equal(t, "audit", merged.DefaultSchema)
+}
+
+func TestTopologicalSort(t *testing.T) {
+	deps := map[string][]string{
+		"order_items": {"orders", "products"},
+		"orders":      {"customers"},
+		"customers":   {"accounts"},
+		"accounts":    {},
+	}
+	fkFn := func(t string) []string { return deps[t] }
+
+	sorted, err := TopologicalSort([]string{"order_items", "orders", "products", "customers"}, fkFn)
+	assert.NoError(t, err)
+	// accounts is not in tables
+	assert.Equal(t, []string{"customers", "orders", "products", "order_items"}, sorted)
+
+	deps["accounts"] = []string{"order_items"}
+	_, err = TopologicalSort([]string{"order_items", "orders", "products", "customers"}, fkFn)
+	assert.IsError(t, err, ErrCyclicDependency)
+	assert.Contains(t, err.Error(), "order_items -> orders -> customers -> accounts -> order_items")
+}
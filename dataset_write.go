@@ -0,0 +1,203 @@
+package dbtestify
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteYAML writes the dataset in YAML format that can be read by ParseYAML.
+//
+// Directives come first, then tables in alphabetical order. Each row is written in flow style.
+// Columns in PKOverride come first in each row, and the rest are sorted alphabetically.
+func (d *DataSet) WriteYAML(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if d.DefaultSchema != "" {
+		fmt.Fprintf(bw, "_schema: %s\n", yamlString(d.DefaultSchema))
+	}
+	writeDirective(bw, "_operation", d.Operation)
+	writeDirective(bw, "_match", d.Match)
+	writeDirective(bw, "_pkey", d.PKOverride)
+	writeDirective(bw, "_where", d.Where)
+	writeDirective(bw, "_order", d.Order)
+	writeDirective(bw, "_depends_on", d.DependsOn)
+
+	tables := slices.Clone(d.Tables)
+	slices.SortStableFunc(tables, func(a, b *Table) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, t := range tables {
+		if len(t.Rows) == 0 {
+			fmt.Fprintf(bw, "%s: []\n", yamlString(t.Name))
+			continue
+		}
+		fmt.Fprintf(bw, "%s:\n", yamlString(t.Name))
+		pKeys := d.PKOverride[t.Name]
+		for i, row := range t.Rows {
+			columns := slices.Sorted(maps.Keys(row))
+			columns = slices.DeleteFunc(columns, func(c string) bool {
+				return slices.Contains(pKeys, c)
+			})
+			for j := len(pKeys) - 1; j >= 0; j-- {
+				if _, ok := row[pKeys[j]]; ok {
+					columns = slices.Insert(columns, 0, pKeys[j])
+				}
+			}
+			var fields []string
+			for _, c := range columns {
+				v, err := yamlValue(row[c])
+				if err != nil {
+					return fmt.Errorf("table %s, column %s: %w", t.Name, c, err)
+				}
+				fields = append(fields, yamlString(c)+": "+v)
+			}
+			if i < len(t.Tags) && len(t.Tags[i]) > 0 {
+				var tags []string
+				for _, tag := range t.Tags[i] {
+					tags = append(tags, yamlString(tag))
+				}
+				fields = append(fields, "_tag: ["+strings.Join(tags, ", ")+"]")
+			}
+			if t.isSeedOnly(i) {
+				fields = append(fields, "_seed_only: true")
+			}
+			if t.isAssertOnly(i) {
+				fields = append(fields, "_assert_only: true")
+			}
+			fmt.Fprintf(bw, "- { %s }\n", strings.Join(fields, ", "))
+		}
+	}
+	return bw.Flush()
+}
+
+func writeDirective[V any](w io.Writer, name string, values map[string]V) {
+	if len(values) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", name)
+	for _, k := range slices.Sorted(maps.Keys(values)) {
+		v, _ := yamlValue(values[k])
+		fmt.Fprintf(w, "  %s: %s\n", yamlString(k), v)
+	}
+}
+
+var plainYAMLString = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+
+// yamlString returns plain string if it is not confusing, otherwise returns double-quoted string.
+func yamlString(s string) string {
+	if plainYAMLString.MatchString(s) {
+		switch strings.ToLower(s) {
+		case "null", "true", "false", "yes", "no", "on", "off", "y", "n":
+		default:
+			return s
+		}
+	}
+	return yamlQuote(s)
+}
+
+// yamlQuote returns double-quoted string. Unlike strconv.Quote, it escapes only control characters.
+func yamlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f || r == 0x85 || r == 0x2028 || r == 0x2029 || r == 0xfeff:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func yamlValue(v any) (string, error) {
+	switch vv := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return yamlString(escapeEnv(vv)), nil
+	case Operation:
+		return yamlString(string(vv)), nil
+	case MatchStrategy:
+		return yamlString(string(vv)), nil
+	case bool:
+		return strconv.FormatBool(vv), nil
+	case int:
+		return strconv.Itoa(vv), nil
+	case int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", vv), nil
+	case float32:
+		return yamlFloat(float64(vv)), nil
+	case float64:
+		return yamlFloat(vv), nil
+	case time.Time:
+		return yamlQuote(vv.Format(time.RFC3339Nano)), nil
+	case []byte:
+		// quoted not to be an empty scalar
+		return "!!binary " + yamlQuote(base64.StdEncoding.EncodeToString(vv)), nil
+	case []string:
+		var items []string
+		for _, item := range vv {
+			items = append(items, yamlString(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case []any:
+		var items []string
+		for _, item := range vv {
+			s, err := yamlValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]any:
+		var items []string
+		for _, k := range slices.Sorted(maps.Keys(vv)) {
+			s, err := yamlValue(vv[k])
+			if err != nil {
+				return "", err
+			}
+			items = append(items, yamlString(k)+": "+s)
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// yamlFloat keeps the decimal point so that the value is read as float again.
+func yamlFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
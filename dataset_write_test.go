@@ -0,0 +1,149 @@
+package dbtestify
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestDataSetWriteYAML(t *testing.T) {
+	data := &DataSet{
+		Operation:  map[string]Operation{"user": InsertOperation},
+		Match:      map[string]MatchStrategy{"user": SubMatchStrategy},
+		PKOverride: map[string][]string{"user": {"name"}},
+		Tables: []*Table{
+			{
+				Name: "user",
+				Rows: []map[string]any{
+					{"id": 1, "name": "Frank", "email": nil, "score": 1.0},
+					{"id": 2, "name": "Grace Hopper", "email": "grace@example.com", "score": 2.5},
+				},
+				Tags:     [][]string{{"admin"}, nil},
+				SeedOnly: []bool{false, true},
+			},
+			{
+				Name: "group",
+				Rows: []map[string]any{
+					{"id": 1, "members": []any{1, 2}, "note": "multi\nline"},
+				},
+				Tags: [][]string{nil},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, data.WriteYAML(&buf))
+	assert.Equal(t, TrimIndent(t, `
+		_operation:
+		  user: insert
+		_match:
+		  user: sub
+		_pkey:
+		  user: [name]
+		group:
+		- { id: 1, members: [1, 2], note: "multi\nline" }
+		user:
+		- { name: Frank, email: null, id: 1, score: 1.0, _tag: [admin] }
+		- { name: "Grace Hopper", email: "grace@example.com", id: 2, score: 2.5, _seed_only: true }
+		`)+"\n", buf.String())
+
+	parsed, err := ParseYAML(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, data.Operation, parsed.Operation)
+	assert.Equal(t, data.Match, parsed.Match)
+	assert.Equal(t, data.PKOverride, parsed.PKOverride)
+	i := 0
+	if parsed.Tables[0].Name != "user" {
+		i = 1
+	}
+	assert.Equal(t, data.Tables[0].Rows, parsed.Tables[i].Rows)
+	assert.Equal(t, [][]string{{"admin"}, nil}, parsed.Tables[i].Tags)
+	assert.Equal(t, []bool{false, true}, parsed.Tables[i].SeedOnly)
+	// nested numbers are not normalized by ParseYAML
+	assert.Equal(t, []map[string]any{
+		{"id": 1, "members": []any{uint64(1), uint64(2)}, "note": "multi\nline"},
+	}, parsed.Tables[1-i].Rows)
+}
+
+func TestDataSetWriteYAMLSchema(t *testing.T) {
+	data := &DataSet{
+		DefaultSchema: "analytics",
+		Tables:        []*Table{{Name: "events", Rows: []map[string]any{{"id": 1}}, Tags: [][]string{nil}}},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, data.WriteYAML(&buf))
+	assert.Equal(t, "_schema: analytics\nevents:\n- { id: 1 }\n", buf.String())
+
+	parsed, err := ParseYAML(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "analytics", parsed.DefaultSchema)
+}
+
+func TestDataSetWriteYAMLRoundTrip(t *testing.T) {
+	f := func(ints []int64, floats []float64, texts []string, flags []bool) bool {
+		n := max(len(ints), len(floats), len(texts), len(flags))
+		table := &Table{Name: "random"}
+		for i := range n {
+			row := map[string]any{"id": i}
+			if i < len(ints) {
+				row["int"] = int(ints[i])
+			}
+			if i < len(floats) && !math.IsNaN(floats[i]) {
+				row["float"] = floats[i]
+			}
+			if i < len(texts) {
+				row["text"] = texts[i]
+			}
+			if i < len(flags) {
+				row["flag"] = flags[i]
+			} else {
+				row["flag"] = nil
+			}
+			table.Rows = append(table.Rows, row)
+			table.Tags = append(table.Tags, nil)
+		}
+		var buf bytes.Buffer
+		if err := (&DataSet{Tables: []*Table{table}}).WriteYAML(&buf); err != nil {
+			t.Log(err)
+			return false
+		}
+		parsed, err := ParseYAML(strings.NewReader(buf.String()))
+		if err != nil {
+			t.Log(err, buf.String())
+			return false
+		}
+		if n == 0 {
+			return len(parsed.Tables) == 1 && len(parsed.Tables[0].Rows) == 0
+		}
+		for i, row := range table.Rows {
+			for k, v := range row {
+				if parsed.Tables[0].Rows[i][k] != v {
+					t.Logf("%s: %#v != %#v\n%s", k, v, parsed.Tables[0].Rows[i][k], buf.String())
+					return false
+				}
+			}
+		}
+		return true
+	}
+	assert.NoError(t, quick.Check(f, nil))
+}
+
+func TestDataSetWriteYAMLEnvEscape(t *testing.T) {
+	for _, s := range []string{"Hello ${name}", "${a}${b}", "$${escaped}", "${unterminated", "price: $100"} {
+		t.Run(s, func(t *testing.T) {
+			data := &DataSet{
+				Where:  map[string]string{"user": "name <> '" + s + "'"},
+				Tables: []*Table{{Name: "user", Rows: []map[string]any{{"id": 1, "name": s}}, Tags: [][]string{nil}}},
+			}
+			var buf bytes.Buffer
+			assert.NoError(t, data.WriteYAML(&buf))
+			parsed, err := ParseYAML(strings.NewReader(buf.String()))
+			assert.NoError(t, err)
+			assert.Equal[any](t, s, parsed.Tables[0].Rows[0]["name"])
+			assert.Equal(t, data.Where, parsed.Where)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package dbtestify
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// cockroachDBConnector is a DBConnector for CockroachDB.
+// CockroachDB is wire-compatible with PostgreSQL, so it reuses psqlDBConnector except for the several differences.
+type cockroachDBConnector struct {
+	psqlDBConnector
+}
+
+// PrimaryKeys implements DBConnector.
+//
+// CockroachDB adds hidden rowid column as a primary key for the table without primary key. It is excluded from the result.
+func (c *cockroachDBConnector) PrimaryKeys(ctx context.Context, table string) ([]string, error) {
+	var schema, tname string
+	f := strings.SplitN(table, ".", 2)
+	if len(f) == 2 {
+		schema = f[0]
+		tname = f[1]
+	} else {
+		err := c.db.QueryRowContext(ctx, `SELECT current_schema();`).Scan(&schema)
+		if err != nil {
+			return nil, err
+		}
+		tname = table
+	}
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT
+			kcu.column_name
+		FROM
+			information_schema.table_constraints AS tc
+		JOIN
+			information_schema.key_column_usage AS kcu
+		ON
+			tc.constraint_name = kcu.constraint_name
+		AND
+			tc.table_schema = kcu.table_schema
+		AND
+			tc.table_name = kcu.table_name
+		JOIN
+			information_schema.columns AS c
+		ON
+			c.table_schema = kcu.table_schema
+		AND
+			c.table_name = kcu.table_name
+		AND
+			c.column_name = kcu.column_name
+		WHERE
+			tc.constraint_type = 'PRIMARY KEY'
+		AND
+			c.is_hidden = 'NO'
+		AND
+			tc.table_schema = $1
+		AND
+			tc.table_name = $2
+		ORDER BY
+			kcu.column_name;
+	`, schema, tname)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+// ColumnTypes implements DBConnector.
+//
+// Hidden rowid column is excluded like PrimaryKeys.
+func (c *cockroachDBConnector) ColumnTypes(ctx context.Context, tableName string) ([]ColumnTypeMeta, error) {
+	var schema, tname string
+	f := strings.SplitN(tableName, ".", 2)
+	if len(f) == 2 {
+		schema = f[0]
+		tname = f[1]
+	} else {
+		err := c.db.QueryRowContext(ctx, `SELECT current_schema();`).Scan(&schema)
+		if err != nil {
+			return nil, err
+		}
+		tname = tableName
+	}
+	return queryColumnTypes(ctx, c.db, `
+		SELECT
+			c.column_name,
+			c.data_type,
+			c.is_nullable,
+			c.character_maximum_length,
+			CASE WHEN c.column_default IS NOT NULL OR c.is_identity = 'YES' THEN 'YES' ELSE 'NO' END
+		FROM
+			information_schema.columns AS c
+		WHERE
+			c.table_schema = $1
+		AND
+			c.table_name = $2
+		AND
+			c.is_hidden = 'NO'
+		ORDER BY
+			c.ordinal_position;
+	`, schema, tname)
+}
+
+// InsertIgnore implements DBConnector.
+//
+// CockroachDB requires the explicit conflict target, so it uses primary keys.
+func (c *cockroachDBConnector) InsertIgnore(ctx context.Context, tx *sql.Tx, tableName string, columns []string, values []any) error {
+	pKeys, err := c.PrimaryKeys(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	insertStmt := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES %s ON CONFLICT(%s) DO NOTHING",
+		tableName,
+		strings.Join(columns, ", "),
+		pgPlaceholders(len(columns), len(values)/len(columns)),
+		strings.Join(pKeys, ", "),
+	)
+	_, err = tx.ExecContext(ctx, insertStmt, values...)
+	return err
+}
+
+// Truncate implements DBConnector.
+//
+// CockroachDB rejects truncating the table referenced by other tables without CASCADE.
+func (c *cockroachDBConnector) Truncate(ctx context.Context, tx *sql.Tx, tableName string) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("TRUNCATE TABLE %s CASCADE;", tableName))
+	return err
+}
+
+// truncateTables implements multiTableTruncator with CASCADE as Truncate.
+func (c *cockroachDBConnector) truncateTables(ctx context.Context, tx *sql.Tx, tableNames []string) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("TRUNCATE TABLE %s CASCADE;", strings.Join(tableNames, ", ")))
+	return err
+}
+
+// DisableForeignKeys implements ForeignKeyController.
+//
+// CockroachDB doesn't support session_replication_role, so foreign keys are still checked.
+func (c *cockroachDBConnector) DisableForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+
+// EnableForeignKeys implements ForeignKeyController.
+func (c *cockroachDBConnector) EnableForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+
+var _ DBConnector = (*cockroachDBConnector)(nil)
+var _ ForeignKeyController = (*cockroachDBConnector)(nil)
@@ -0,0 +1,91 @@
+package dbtestify
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/cockroachdb"
+)
+
+func TestDBConnectorCockroachDB(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := context.Background()
+
+	crdbContainer, err := cockroachdb.Run(ctx, "cockroachdb/cockroach:latest-v23.1",
+		cockroachdb.WithInsecure(),
+		cockroachdb.WithInitScripts(filepath.Join("testdata", "db-connector-test-init.sql")),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		if err := testcontainers.TerminateContainer(crdbContainer); err != nil {
+			t.Fatalf("failed to terminate crdbContainer: %s", err)
+		}
+	})
+	connStr, err := crdbContainer.ConnectionString(ctx)
+	assert.NoError(t, err)
+
+	ctx2, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := NewDBConnector(ctx2, "cockroachdb://"+strings.TrimPrefix(connStr, "postgres://"))
+	assert.NoError(t, err)
+
+	_, err = db.DB().ExecContext(ctx2, TrimIndent(t, `
+		CREATE TABLE customers (id INT PRIMARY KEY, name STRING NOT NULL);
+		CREATE TABLE purchases (id INT PRIMARY KEY, customer_id INT NOT NULL REFERENCES customers(id));
+		CREATE TABLE access_log (path STRING NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	tnames, err := db.TableNames(ctx2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"access_log", "book_authors", "customers", "orders", "purchases", "student_course_enrollments"}, tnames)
+
+	pkeys, err := db.PrimaryKeys(ctx2, "public.orders")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"order_id", "product_id"}, pkeys)
+
+	// hidden rowid is not a primary key
+	pkeys, err = db.PrimaryKeys(ctx2, "access_log")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(pkeys))
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		_depends_on:
+		  purchases: [customers]
+		customers:
+		- { id: 1, name: Frank }
+		purchases:
+		- { id: 1, customer_id: 1 }
+		`)))
+	assert.NoError(t, err)
+	// truncate of referenced table needs CASCADE
+	for range 2 {
+		err = Seed(ctx2, db, data, SeedOpt{})
+		assert.NoError(t, err)
+	}
+
+	tx, err := db.DB().Begin()
+	assert.NoError(t, err)
+	defer tx.Rollback()
+	err = db.InsertIgnore(ctx2, tx, "customers", []string{"id", "name"}, []any{1, "Frankie", 2, "Grace"})
+	assert.NoError(t, err)
+	err = db.Upsert(ctx2, tx, "customers", []string{"id", "name"}, []string{"id"}, []any{2, "Gracie"})
+	assert.NoError(t, err)
+	var names []string
+	rows, err := tx.QueryContext(ctx2, "SELECT name FROM customers ORDER BY id")
+	assert.NoError(t, err)
+	for rows.Next() {
+		var name string
+		assert.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"Frank", "Gracie"}, names)
+}
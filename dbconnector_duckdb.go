@@ -0,0 +1,261 @@
+//go:build duckdb
+
+package dbtestify
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	_ "github.com/marcboeker/go-duckdb/v2"
+)
+
+type duckDBConnector struct {
+	db *sql.DB
+}
+
+func newDuckDBConnector(db *sql.DB) (DBConnector, error) {
+	return &duckDBConnector{db: db}, nil
+}
+
+// openDuckDB opens the DuckDB database file. An empty source is an in-memory database.
+func openDuckDB(ctx context.Context, source string) (DBConnector, error) {
+	db, err := sql.Open("duckdb", source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't open DuckDB database file '%s': %s", ErrInvalidDBDriver, err.Error(), source)
+	}
+	go func() {
+		<-ctx.Done()
+		db.Close()
+	}()
+	return &duckDBConnector{db: db}, nil
+}
+
+// duckDBSchema splits "schema.table" notation. The schema is "main" if it is omitted.
+func duckDBSchema(table string) (schema, tname string) {
+	if f := strings.SplitN(table, ".", 2); len(f) == 2 {
+		return f[0], f[1]
+	}
+	return "main", table
+}
+
+func (d *duckDBConnector) TableNames(ctx context.Context, schema ...string) ([]string, error) {
+	s := "main"
+	if len(schema) > 0 {
+		s = schema[0]
+	}
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT
+			t.table_name
+		FROM
+			information_schema.tables AS t
+		WHERE
+			t.table_schema = ?
+		AND
+			t.table_type = 'BASE TABLE'
+		ORDER BY
+			t.table_name;`, s)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []string
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, name)
+	}
+	return result, rows.Err()
+}
+
+func (d *duckDBConnector) PrimaryKeys(ctx context.Context, table string) ([]string, error) {
+	schema, tname := duckDBSchema(table)
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT
+			kcu.column_name
+		FROM
+			information_schema.table_constraints AS tc
+		JOIN
+			information_schema.key_column_usage AS kcu
+		ON
+			tc.constraint_name = kcu.constraint_name
+		AND
+			tc.table_schema = kcu.table_schema
+		AND
+			tc.table_name = kcu.table_name
+		WHERE
+			tc.constraint_type = 'PRIMARY KEY'
+		AND
+			tc.table_schema = ?
+		AND
+			tc.table_name = ?
+		ORDER BY
+			kcu.column_name;`, schema, tname)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []string
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, name)
+	}
+	return result, rows.Err()
+}
+
+// UniqueKeys implements DBConnector.
+func (d *duckDBConnector) UniqueKeys(ctx context.Context, table string) ([][]string, error) {
+	schema, tname := duckDBSchema(table)
+	return queryUniqueKeys(ctx, d.db, `
+		SELECT
+			tc.constraint_name,
+			kcu.column_name
+		FROM
+			information_schema.table_constraints AS tc
+		JOIN
+			information_schema.key_column_usage AS kcu
+		ON
+			tc.constraint_name = kcu.constraint_name
+		AND
+			tc.table_schema = kcu.table_schema
+		AND
+			tc.table_name = kcu.table_name
+		WHERE
+			tc.constraint_type = 'UNIQUE'
+		AND
+			tc.table_schema = ?
+		AND
+			tc.table_name = ?
+		ORDER BY
+			tc.constraint_name,
+			kcu.column_name;`, schema, tname)
+}
+
+// ForeignKeys implements DBConnector.
+//
+// information_schema of DuckDB doesn't report the positions of composite foreign keys correctly, so duckdb_constraints() is used.
+func (d *duckDBConnector) ForeignKeys(ctx context.Context, table string) ([]ForeignKey, error) {
+	schema, tname := duckDBSchema(table)
+	return queryForeignKeys(ctx, d.db, table, schema, `
+		SELECT
+			UNNEST(c.constraint_column_names),
+			c.schema_name,
+			c.referenced_table,
+			UNNEST(c.referenced_column_names)
+		FROM
+			duckdb_constraints() AS c
+		WHERE
+			c.constraint_type = 'FOREIGN KEY'
+		AND
+			c.schema_name = ?
+		AND
+			c.table_name = ?
+		ORDER BY
+			c.constraint_index;`, schema, tname)
+}
+
+// ColumnTypes implements DBConnector.
+func (d *duckDBConnector) ColumnTypes(ctx context.Context, tableName string) ([]ColumnTypeMeta, error) {
+	schema, tname := duckDBSchema(tableName)
+	return queryColumnTypes(ctx, d.db, `
+		SELECT
+			c.column_name,
+			c.data_type,
+			c.is_nullable,
+			c.character_maximum_length,
+			CASE WHEN c.column_default IS NOT NULL THEN 'YES' ELSE 'NO' END
+		FROM
+			information_schema.columns AS c
+		WHERE
+			c.table_schema = ?
+		AND
+			c.table_name = ?
+		ORDER BY
+			c.ordinal_position;`, schema, tname)
+}
+
+func (d *duckDBConnector) DB() *sql.DB {
+	return d.db
+}
+
+// Ping implements DBConnector.
+func (d *duckDBConnector) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
+func (d *duckDBConnector) Insert(ctx context.Context, tx *sql.Tx, tableName string, columns []string, values []any) error {
+	insertStmt := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES %s",
+		tableName,
+		strings.Join(columns, ", "),
+		slPlaceholders(len(columns), len(values)/len(columns)),
+	)
+	_, err := tx.ExecContext(ctx, insertStmt, values...)
+	return err
+}
+
+func (d *duckDBConnector) InsertIgnore(ctx context.Context, tx *sql.Tx, tableName string, columns []string, values []any) error {
+	insertStmt := fmt.Sprintf(
+		"INSERT OR IGNORE INTO %s (%s) VALUES %s",
+		tableName,
+		strings.Join(columns, ", "),
+		slPlaceholders(len(columns), len(values)/len(columns)),
+	)
+	_, err := tx.ExecContext(ctx, insertStmt, values...)
+	return err
+}
+
+func (d *duckDBConnector) Delete(ctx context.Context, tx *sql.Tx, tableName string, columns []string, values []any) error {
+	var columnStr string
+	var placeholderStr string
+	if len(columns) > 1 {
+		columnStr = "(" + strings.Join(columns, ", ") + ")"
+		placeholderStr = slPlaceholders(len(columns), len(values)/len(columns))
+	} else {
+		columnStr = columns[0]
+		placeholderStr = strings.Join(slices.Repeat([]string{"?"}, len(values)), ", ")
+	}
+
+	deleteStmt := fmt.Sprintf(
+		"DELETE FROM %s WHERE %s IN (%s)",
+		tableName,
+		columnStr,
+		placeholderStr,
+	)
+	_, err := tx.ExecContext(ctx, deleteStmt, values...)
+	return err
+}
+
+// Upsert implements DBConnector.
+//
+// DuckDB's `INSERT OR REPLACE` updates the given columns of the row that has the same primary key, so pKeys are not used.
+func (d *duckDBConnector) Upsert(ctx context.Context, tx *sql.Tx, tableName string, columns, pKeys []string, values []any) error {
+	insertStmt := fmt.Sprintf(
+		"INSERT OR REPLACE INTO %s (%s) VALUES %s",
+		tableName,
+		strings.Join(columns, ", "),
+		slPlaceholders(len(columns), len(values)/len(columns)),
+	)
+	_, err := tx.ExecContext(ctx, insertStmt, values...)
+	return err
+}
+
+// Truncate implements DBConnector.
+//
+// DuckDB's TRUNCATE is an alias of DELETE, so it uses DELETE directly.
+func (d *duckDBConnector) Truncate(ctx context.Context, tx *sql.Tx, tableName string) error {
+	truncateSrc := fmt.Sprintf("DELETE FROM %s;", tableName)
+	_, err := tx.ExecContext(ctx, truncateSrc)
+	return err
+}
+
+var _ DBConnector = (*duckDBConnector)(nil)
@@ -0,0 +1,156 @@
+//go:build duckdb
+
+package dbtestify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+// skipWithoutDuckDB does nothing because DuckDB is built in.
+func skipWithoutDuckDB(t *testing.T) {}
+
+func newDuckDBTestConnector(t *testing.T) DBConnector {
+	t.Helper()
+	dbc, err := NewDBConnector(t.Context(), "duckdb://:memory:")
+	assert.NoError(t, err)
+	t.Cleanup(func() { dbc.DB().Close() })
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(100) NOT NULL, email VARCHAR);
+		CREATE TABLE orders (order_id INTEGER, product_id INTEGER, quantity INTEGER, PRIMARY KEY (order_id, product_id));
+		CREATE TABLE accounts (email VARCHAR UNIQUE, tenant VARCHAR, code VARCHAR, UNIQUE (tenant, code));
+		CREATE VIEW user_names AS SELECT name FROM users;
+	`))
+	assert.NoError(t, err)
+	return dbc
+}
+
+func TestDBConnectorDuckDB(t *testing.T) {
+	dbc := newDuckDBTestConnector(t)
+	ctx := t.Context()
+
+	tnames, err := dbc.TableNames(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"accounts", "orders", "users"}, tnames)
+
+	pkeys, err := dbc.PrimaryKeys(ctx, "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"order_id", "product_id"}, pkeys)
+
+	pkeys, err = dbc.PrimaryKeys(ctx, "main.users")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id"}, pkeys)
+
+	ukeys, err := dbc.UniqueKeys(ctx, "accounts")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(ukeys))
+	assert.SliceContains(t, ukeys, []string{"email"})
+	assert.SliceContains(t, ukeys, []string{"code", "tenant"})
+
+	ukeys, err = dbc.UniqueKeys(ctx, "users")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(ukeys))
+
+	columns, err := dbc.ColumnTypes(ctx, "users")
+	assert.NoError(t, err)
+	assert.Equal(t, []ColumnTypeMeta{
+		{Name: "id", DBType: "INTEGER", Nullable: false},
+		{Name: "name", DBType: "VARCHAR", Nullable: false},
+		{Name: "email", DBType: "VARCHAR", Nullable: true},
+	}, columns)
+
+	_, err = dbc.DB().ExecContext(ctx, TrimIndent(t, `
+		CREATE TABLE products (sku VARCHAR, variant VARCHAR, PRIMARY KEY (sku, variant));
+		CREATE TABLE purchases (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id), sku VARCHAR, variant VARCHAR, FOREIGN KEY (sku, variant) REFERENCES products(sku, variant));
+	`))
+	assert.NoError(t, err)
+	fks, err := dbc.ForeignKeys(ctx, "purchases")
+	assert.NoError(t, err)
+	assert.Equal(t, []ForeignKey{
+		{Table: "purchases", Column: "user_id", RefTable: "users", RefColumn: "id"},
+		{Table: "purchases", Column: "sku", RefTable: "products", RefColumn: "sku"},
+		{Table: "purchases", Column: "variant", RefTable: "products", RefColumn: "variant"},
+	}, fks)
+}
+
+func TestDuckDBSeedAndAssert(t *testing.T) {
+	dbc := newDuckDBTestConnector(t)
+	ctx := t.Context()
+
+	seed := func(src string, opt SeedOpt) {
+		t.Helper()
+		data, err := ParseYAML(strings.NewReader(TrimIndent(t, src)))
+		assert.NoError(t, err)
+		assert.NoError(t, Seed(ctx, dbc, data, opt))
+	}
+	match := func(src string) bool {
+		t.Helper()
+		data, err := ParseYAML(strings.NewReader(TrimIndent(t, src)))
+		assert.NoError(t, err)
+		ok, _, err := Assert(ctx, dbc, data, AssertOpt{})
+		assert.NoError(t, err)
+		return ok
+	}
+
+	seed(`
+		users:
+		- { id: 1, name: Frank, email: frank@example.com }
+		- { id: 2, name: Grace }
+		orders:
+		- { order_id: 1, product_id: 100, quantity: 5 }
+		- { order_id: 2, product_id: 101, quantity: 3 }
+		`, SeedOpt{})
+	assert.True(t, match(`
+		users:
+		- { id: 1, name: Frank, email: frank@example.com }
+		- { id: 2, name: Grace, email: [null] }
+		`))
+
+	t.Run("upsert and insert ignore", func(t *testing.T) {
+		seed(`
+			users:
+			- { id: 2, name: Heidi, email: heidi@example.com }
+			- { id: 3, name: Ivy }
+			orders:
+			- { order_id: 1, product_id: 100, quantity: 10 }
+			- { order_id: 3, product_id: 102, quantity: 1 }
+			`, SeedOpt{Operations: map[string]Operation{
+			"users":  UpsertOperation,
+			"orders": InsertIgnoreOperation,
+		}})
+		assert.True(t, match(`
+			users:
+			- { id: 1, name: Frank }
+			- { id: 2, name: Heidi, email: heidi@example.com }
+			- { id: 3, name: Ivy }
+			orders:
+			- { order_id: 1, product_id: 100, quantity: 5 }
+			- { order_id: 2, product_id: 101, quantity: 3 }
+			- { order_id: 3, product_id: 102, quantity: 1 }
+			`))
+	})
+
+	t.Run("delete composite key", func(t *testing.T) {
+		seed(`
+			orders:
+			- { order_id: 1, product_id: 100 }
+			- { order_id: 3, product_id: 102 }
+			`, SeedOpt{Operations: map[string]Operation{"orders": DeleteOperation}})
+		assert.True(t, match(`
+			orders:
+			- { order_id: 2, product_id: 101, quantity: 3 }
+			`))
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		seed(`
+			users:
+			- { id: 1 }
+			`, SeedOpt{Operations: map[string]Operation{"users": TruncateOperation}})
+		var count int
+		assert.NoError(t, dbc.DB().QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&count))
+		assert.Equal(t, 0, count)
+	})
+}
@@ -0,0 +1,363 @@
+package dbtestify
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	_ "github.com/microsoft/go-mssqldb"
+)
+
+type mssqlDBConnector struct {
+	db *sql.DB
+}
+
+func (m *mssqlDBConnector) TableNames(ctx context.Context, schema ...string) ([]string, error) {
+	var s string
+	if len(schema) == 0 {
+		err := m.db.QueryRowContext(ctx, `SELECT SCHEMA_NAME();`).Scan(&s)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		s = schema[0]
+	}
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT
+			t.TABLE_NAME
+		FROM
+			INFORMATION_SCHEMA.TABLES AS t
+		WHERE
+			t.TABLE_SCHEMA = @p1
+		AND
+			t.TABLE_TYPE = 'BASE TABLE'
+		ORDER BY
+			t.TABLE_NAME;
+	`, s)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []string
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, name)
+	}
+	return result, rows.Err()
+}
+
+func (m *mssqlDBConnector) PrimaryKeys(ctx context.Context, table string) ([]string, error) {
+	var schema, tname string
+	f := strings.SplitN(table, ".", 2)
+	if len(f) == 2 {
+		schema = f[0]
+		tname = f[1]
+	} else {
+		err := m.db.QueryRowContext(ctx, `SELECT SCHEMA_NAME();`).Scan(&schema)
+		if err != nil {
+			return nil, err
+		}
+		tname = table
+	}
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT
+			kcu.COLUMN_NAME
+		FROM
+			INFORMATION_SCHEMA.TABLE_CONSTRAINTS AS tc
+		JOIN
+			INFORMATION_SCHEMA.KEY_COLUMN_USAGE AS kcu
+		ON
+			tc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME
+		AND
+			tc.TABLE_SCHEMA = kcu.TABLE_SCHEMA
+		WHERE
+			tc.CONSTRAINT_TYPE = 'PRIMARY KEY'
+		AND
+			tc.TABLE_SCHEMA = @p1
+		AND
+			tc.TABLE_NAME = @p2
+		ORDER BY
+			kcu.COLUMN_NAME;
+	`, schema, tname)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []string
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, name)
+	}
+	return result, rows.Err()
+}
+
+// UniqueKeys implements DBConnector.
+func (m *mssqlDBConnector) UniqueKeys(ctx context.Context, table string) ([][]string, error) {
+	var schema, tname string
+	f := strings.SplitN(table, ".", 2)
+	if len(f) == 2 {
+		schema = f[0]
+		tname = f[1]
+	} else {
+		err := m.db.QueryRowContext(ctx, `SELECT SCHEMA_NAME();`).Scan(&schema)
+		if err != nil {
+			return nil, err
+		}
+		tname = table
+	}
+	return queryUniqueKeys(ctx, m.db, `
+		SELECT
+			tc.CONSTRAINT_NAME,
+			kcu.COLUMN_NAME
+		FROM
+			INFORMATION_SCHEMA.TABLE_CONSTRAINTS AS tc
+		JOIN
+			INFORMATION_SCHEMA.KEY_COLUMN_USAGE AS kcu
+		ON
+			tc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME
+		AND
+			tc.TABLE_SCHEMA = kcu.TABLE_SCHEMA
+		WHERE
+			tc.CONSTRAINT_TYPE = 'UNIQUE'
+		AND
+			tc.TABLE_SCHEMA = @p1
+		AND
+			tc.TABLE_NAME = @p2
+		ORDER BY
+			tc.CONSTRAINT_NAME,
+			kcu.COLUMN_NAME;
+	`, schema, tname)
+}
+
+// ForeignKeys implements DBConnector.
+//
+// INFORMATION_SCHEMA of SQL Server doesn't have the positions in the referenced key, so the catalog views are used.
+func (m *mssqlDBConnector) ForeignKeys(ctx context.Context, table string) ([]ForeignKey, error) {
+	var schema, tname string
+	f := strings.SplitN(table, ".", 2)
+	if len(f) == 2 {
+		schema = f[0]
+		tname = f[1]
+	} else {
+		err := m.db.QueryRowContext(ctx, `SELECT SCHEMA_NAME();`).Scan(&schema)
+		if err != nil {
+			return nil, err
+		}
+		tname = table
+	}
+	return queryForeignKeys(ctx, m.db, table, schema, `
+		SELECT
+			pc.name,
+			SCHEMA_NAME(rt.schema_id),
+			rt.name,
+			rc.name
+		FROM
+			sys.foreign_key_columns AS fkc
+		JOIN
+			sys.tables AS pt
+		ON
+			fkc.parent_object_id = pt.object_id
+		JOIN
+			sys.columns AS pc
+		ON
+			fkc.parent_object_id = pc.object_id
+		AND
+			fkc.parent_column_id = pc.column_id
+		JOIN
+			sys.tables AS rt
+		ON
+			fkc.referenced_object_id = rt.object_id
+		JOIN
+			sys.columns AS rc
+		ON
+			fkc.referenced_object_id = rc.object_id
+		AND
+			fkc.referenced_column_id = rc.column_id
+		WHERE
+			SCHEMA_NAME(pt.schema_id) = @p1
+		AND
+			pt.name = @p2
+		ORDER BY
+			fkc.constraint_object_id,
+			fkc.constraint_column_id;
+	`, schema, tname)
+}
+
+func (m *mssqlDBConnector) ColumnTypes(ctx context.Context, tableName string) ([]ColumnTypeMeta, error) {
+	var schema, tname string
+	f := strings.SplitN(tableName, ".", 2)
+	if len(f) == 2 {
+		schema = f[0]
+		tname = f[1]
+	} else {
+		err := m.db.QueryRowContext(ctx, `SELECT SCHEMA_NAME();`).Scan(&schema)
+		if err != nil {
+			return nil, err
+		}
+		tname = tableName
+	}
+	return queryColumnTypes(ctx, m.db, `
+		SELECT
+			c.COLUMN_NAME,
+			c.DATA_TYPE,
+			c.IS_NULLABLE,
+			c.CHARACTER_MAXIMUM_LENGTH,
+			CASE WHEN c.COLUMN_DEFAULT IS NOT NULL OR COLUMNPROPERTY(OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME)), c.COLUMN_NAME, 'IsIdentity') = 1 THEN 'YES' ELSE 'NO' END
+		FROM
+			INFORMATION_SCHEMA.COLUMNS AS c
+		WHERE
+			c.TABLE_SCHEMA = @p1
+		AND
+			c.TABLE_NAME = @p2
+		ORDER BY
+			c.ORDINAL_POSITION;
+	`, schema, tname)
+}
+
+func (m *mssqlDBConnector) DB() *sql.DB {
+	return m.db
+}
+
+func (m *mssqlDBConnector) Ping(ctx context.Context) error {
+	return m.db.PingContext(ctx)
+}
+
+func msPlaceholders(columns, rows int) string {
+	c := 1
+	var placeholders []string
+	for r := 0; r < rows; r++ {
+		var rp []string
+		for i := 0; i < columns; i++ {
+			rp = append(rp, "@p"+strconv.Itoa(c))
+			c++
+		}
+		placeholders = append(placeholders, "("+strings.Join(rp, ", ")+")")
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+// execWithIdentityInsert executes the statement with IDENTITY_INSERT enabled if the table has an identity column.
+// SQL Server rejects explicit values for identity columns without it, but datasets usually have ids.
+func (m *mssqlDBConnector) execWithIdentityInsert(ctx context.Context, tx *sql.Tx, tableName, stmt string, values []any) error {
+	var hasIdentity sql.NullInt64
+	err := tx.QueryRowContext(ctx, `SELECT OBJECTPROPERTY(OBJECT_ID(@p1), 'TableHasIdentity');`, tableName).Scan(&hasIdentity)
+	if err != nil {
+		return err
+	}
+	if hasIdentity.Int64 == 1 {
+		stmt = fmt.Sprintf("SET IDENTITY_INSERT %s ON; %s SET IDENTITY_INSERT %s OFF;", tableName, stmt, tableName)
+	}
+	_, err = tx.ExecContext(ctx, stmt, values...)
+	return err
+}
+
+func (m *mssqlDBConnector) Insert(ctx context.Context, tx *sql.Tx, tableName string, columns []string, values []any) error {
+	insertStmt := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES %s;",
+		tableName,
+		strings.Join(columns, ", "),
+		msPlaceholders(len(columns), len(values)/len(columns)),
+	)
+	return m.execWithIdentityInsert(ctx, tx, tableName, insertStmt, values)
+}
+
+func (m *mssqlDBConnector) Delete(ctx context.Context, tx *sql.Tx, tableName string, columns []string, values []any) error {
+	var condition string
+	if len(columns) > 1 {
+		// SQL Server doesn't support row value constructor in IN clause
+		var conditions []string
+		c := 1
+		for r := 0; r < len(values)/len(columns); r++ {
+			var cc []string
+			for _, column := range columns {
+				cc = append(cc, column+" = @p"+strconv.Itoa(c))
+				c++
+			}
+			conditions = append(conditions, "("+strings.Join(cc, " AND ")+")")
+		}
+		condition = strings.Join(conditions, " OR ")
+	} else {
+		var placeholders []string
+		for i := range len(values) {
+			placeholders = append(placeholders, "@p"+strconv.Itoa(i+1))
+		}
+		condition = fmt.Sprintf("%s IN (%s)", columns[0], strings.Join(placeholders, ", "))
+	}
+
+	deleteStmt := fmt.Sprintf(
+		"DELETE FROM %s WHERE %s;",
+		tableName,
+		condition,
+	)
+	_, err := tx.ExecContext(ctx, deleteStmt, values...)
+	return err
+}
+
+// mergeStmt builds MERGE statement that inserts rows not matched by primary keys.
+// If update is true, matched rows are updated too.
+func mergeStmt(tableName string, columns, pKeys []string, rows int, update bool) string {
+	var conditions []string
+	for _, pKey := range pKeys {
+		conditions = append(conditions, "target."+pKey+" = source."+pKey)
+	}
+	var assigns []string
+	for _, column := range columns {
+		if !slices.Contains(pKeys, column) {
+			assigns = append(assigns, column+" = source."+column)
+		}
+	}
+	var sourceColumns []string
+	for _, column := range columns {
+		sourceColumns = append(sourceColumns, "source."+column)
+	}
+	var matched string
+	if update && len(assigns) > 0 {
+		matched = " WHEN MATCHED THEN UPDATE SET " + strings.Join(assigns, ", ")
+	}
+	return fmt.Sprintf(
+		"MERGE INTO %s AS target USING (VALUES %s) AS source (%s) ON %s%s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s);",
+		tableName,
+		msPlaceholders(len(columns), rows),
+		strings.Join(columns, ", "),
+		strings.Join(conditions, " AND "),
+		matched,
+		strings.Join(columns, ", "),
+		strings.Join(sourceColumns, ", "),
+	)
+}
+
+func (m *mssqlDBConnector) Upsert(ctx context.Context, tx *sql.Tx, tableName string, columns, pKeys []string, values []any) error {
+	upsertStmt := mergeStmt(tableName, columns, pKeys, len(values)/len(columns), true)
+	return m.execWithIdentityInsert(ctx, tx, tableName, upsertStmt, values)
+}
+
+func (m *mssqlDBConnector) InsertIgnore(ctx context.Context, tx *sql.Tx, tableName string, columns []string, values []any) error {
+	pKeys, err := m.PrimaryKeys(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	if len(pKeys) == 0 {
+		// MERGE needs keys to match rows, and no rows conflict without primary keys
+		return m.Insert(ctx, tx, tableName, columns, values)
+	}
+	insertStmt := mergeStmt(tableName, columns, pKeys, len(values)/len(columns), false)
+	return m.execWithIdentityInsert(ctx, tx, tableName, insertStmt, values)
+}
+
+func (m *mssqlDBConnector) Truncate(ctx context.Context, tx *sql.Tx, tableName string) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("TRUNCATE TABLE %s;", tableName))
+	return err
+}
+
+var _ DBConnector = (*mssqlDBConnector)(nil)
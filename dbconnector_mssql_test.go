@@ -0,0 +1,129 @@
+package dbtestify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mssql"
+)
+
+func Test_mergeStmt(t *testing.T) {
+	assert.Equal(t,
+		"MERGE INTO member AS target USING (VALUES (@p1, @p2), (@p3, @p4)) AS source (id, name) ON target.id = source.id WHEN MATCHED THEN UPDATE SET name = source.name WHEN NOT MATCHED THEN INSERT (id, name) VALUES (source.id, source.name);",
+		mergeStmt("member", []string{"id", "name"}, []string{"id"}, 2, true))
+	assert.Equal(t,
+		"MERGE INTO member AS target USING (VALUES (@p1, @p2)) AS source (id, name) ON target.id = source.id WHEN NOT MATCHED THEN INSERT (id, name) VALUES (source.id, source.name);",
+		mergeStmt("member", []string{"id", "name"}, []string{"id"}, 1, false))
+}
+
+func TestDBConnectorSQLServer(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	ctx := context.Background()
+
+	initSQL, err := os.Open(filepath.Join("testdata", "db-connector-test-init-mssql.sql"))
+	assert.NoError(t, err)
+	defer initSQL.Close()
+
+	mssqlContainer, err := mssql.Run(ctx, "mcr.microsoft.com/mssql/server:2022-CU14-ubuntu-22.04",
+		mssql.WithAcceptEULA(),
+		mssql.WithPassword("Passw0rd!"),
+		mssql.WithInitSQL(initSQL),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		if err := testcontainers.TerminateContainer(mssqlContainer); err != nil {
+			t.Fatalf("failed to terminate mssqlContainer: %s", err)
+		}
+	})
+	connStr, err := mssqlContainer.ConnectionString(ctx)
+	assert.NoError(t, err)
+
+	ctx2, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, err := NewDBConnector(ctx2, connStr)
+	assert.NoError(t, err)
+
+	// with schema
+	tnames, err := db.TableNames(ctx2, "dbo")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"book_authors", "member", "orders", "student_course_enrollments"}, tnames)
+
+	// without schema
+	tnames, err = db.TableNames(ctx2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"book_authors", "member", "orders", "student_course_enrollments"}, tnames)
+
+	// with schema
+	pkeys, err := db.PrimaryKeys(ctx2, "dbo.orders")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"order_id", "product_id"}, pkeys)
+
+	// without schema
+	pkeys, err = db.PrimaryKeys(ctx2, "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"order_id", "product_id"}, pkeys)
+
+	// mssql:// prefix
+	db2, err := NewDBConnector(ctx2, strings.Replace(connStr, "sqlserver://", "mssql://", 1))
+	assert.NoError(t, err)
+	assert.NoError(t, db2.DB().PingContext(ctx2))
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		_operation:
+		  orders: insert
+		member:
+		- { id: 1, name: Frank, email: frank@example.com }
+		- { id: 2, name: Grace }
+		orders:
+		- { order_id: 1, product_id: 100, quantity: 5, price: 1000 }
+		- { order_id: 2, product_id: 101, quantity: 3, price: 500 }
+		`)))
+	assert.NoError(t, err)
+	err = Seed(ctx2, db, data, SeedOpt{})
+	assert.NoError(t, err)
+
+	expected, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		member:
+		- { id: 1, name: Frank, email: frank@example.com }
+		- { id: 2, name: Grace, email: null }
+		orders:
+		- { order_id: 1, product_id: 100, quantity: 5, price: 1000 }
+		- { order_id: 2, product_id: 101, quantity: 3, price: 500 }
+		`)))
+	assert.NoError(t, err)
+	ok, _, err := Assert(ctx2, db, expected, AssertOpt{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	tx, err := db.DB().Begin()
+	assert.NoError(t, err)
+	defer tx.Rollback()
+
+	err = db.Upsert(ctx2, tx, "member", []string{"email", "id", "name"}, []string{"id"}, []any{"grace@example.com", 2, "Grace", nil, 3, "Heidi"})
+	assert.NoError(t, err)
+	err = db.InsertIgnore(ctx2, tx, "member", []string{"id", "name"}, []any{1, "Frankie", 4, "Ivan"})
+	assert.NoError(t, err)
+	err = db.Delete(ctx2, tx, "orders", []string{"order_id", "product_id"}, []any{1, 100})
+	assert.NoError(t, err)
+
+	var count int
+	err = tx.QueryRowContext(ctx2, "SELECT COUNT(*) FROM member").Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+	var name string
+	err = tx.QueryRowContext(ctx2, "SELECT name FROM member WHERE id = 1").Scan(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, "Frank", name)
+	err = tx.QueryRowContext(ctx2, "SELECT COUNT(*) FROM orders").Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
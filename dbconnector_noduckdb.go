@@ -0,0 +1,21 @@
+//go:build !duckdb
+
+package dbtestify
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// errNoDuckDB is returned for DuckDB when it is built without `duckdb` build tag.
+// go-duckdb needs cgo, so it is not linked by default.
+var errNoDuckDB = fmt.Errorf("%w: DuckDB support is not built in. Build with -tags duckdb", ErrInvalidDBDriver)
+
+func newDuckDBConnector(db *sql.DB) (DBConnector, error) {
+	return nil, errNoDuckDB
+}
+
+func openDuckDB(ctx context.Context, source string) (DBConnector, error) {
+	return nil, errNoDuckDB
+}
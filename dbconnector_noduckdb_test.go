@@ -0,0 +1,30 @@
+//go:build !duckdb
+
+package dbtestify
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+// skipWithoutDuckDB skips the test that needs DuckDB. Run tests with -tags duckdb to enable them.
+func skipWithoutDuckDB(t *testing.T) {
+	t.Helper()
+	t.Skip("DuckDB support is built only with -tags duckdb")
+}
+
+func newDuckDBTestConnector(t *testing.T) DBConnector {
+	t.Helper()
+	skipWithoutDuckDB(t)
+	return nil
+}
+
+func TestDBConnectorWithoutDuckDB(t *testing.T) {
+	_, err := NewDBConnector(t.Context(), "duckdb://:memory:")
+	assert.IsError(t, err, ErrInvalidDBDriver)
+	assert.Contains(t, err.Error(), "-tags duckdb")
+
+	_, err = NewDBConnectorFromDB(nil, "duckdb")
+	assert.IsError(t, err, ErrInvalidDBDriver)
+}
@@ -1,11 +1,22 @@
 package gounittest
 
 import (
+	"context"
+	"database/sql"
 	"embed"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/shibukawa/dbtestify"
 	"github.com/shibukawa/dbtestify/assertdb"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
 )
 
 //go:embed dataset/*
@@ -38,3 +49,397 @@ func TestCounter(t *testing.T) {
 
 	assertdb.AssertDB(t, "sqlite://file:counter.db", dataSet, "dataset/expect.yaml", nil)
 }
+
+func TestSnapshotAndAssert(t *testing.T) {
+	assertdb.SeedDataSet(t, "sqlite://file:counter.db", dataSet, "dataset/initial.yaml", nil)
+	dbPath, err := filepath.Abs("counter.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbConn := "sqlite://file:" + dbPath
+
+	t.Chdir(t.TempDir())
+	snapshots := os.DirFS(".")
+
+	// first run: snapshot file is created
+	assertdb.SnapshotAndAssert(t, dbConn, snapshots, "snapshot/counters.yaml", []string{"counters"})
+	content, err := os.ReadFile("snapshot/counters.yaml")
+	if err != nil {
+		t.Fatalf("Snapshot file is not created: %v", err)
+	}
+	if string(content) != "counters:\n- { name: main_counter, value: 0 }\n" {
+		t.Errorf("Unexpected snapshot: %s", content)
+	}
+
+	// second run: database is asserted against the snapshot file
+	assertdb.SnapshotAndAssert(t, dbConn, snapshots, "snapshot/counters.yaml", []string{"counters"})
+}
+
+func TestAssertDBColumns(t *testing.T) {
+	db, err := sql.Open("sqlite3", dbFileName)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS orders (
+		id INTEGER PRIMARY KEY,
+		status TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		updated_at TEXT
+	);`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	assertdb.SeedDataSet(t, "sqlite://file:counter.db", dataSet, "dataset/orders.yaml", nil)
+
+	// updated_at is different from the database, but it is not in the column list
+	assertdb.AssertDBColumns(t, "sqlite://file:counter.db", dataSet, "dataset/orders_expect.yaml", []string{"status", "amount"})
+
+	// column names are compared case-insensitively
+	r := &recorder{TB: t}
+	assertdb.AssertDBColumns(r, "sqlite://file:counter.db", dataSet, "dataset/orders_expect.yaml", []string{"STATUS", "AMOUNT", "UPDATED_AT"})
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "column updated_at") {
+		t.Errorf("Unexpected errors: %v", r.errors)
+	}
+}
+
+func TestAssertRowCount(t *testing.T) {
+	assertdb.SeedDataSet(t, "sqlite://file:counter.db", dataSet, "dataset/initial.yaml", nil)
+
+	assertdb.AssertRowCount(t, "sqlite://file:counter.db", "counters", 1, "")
+	assertdb.AssertRowCount(t, "sqlite://file:counter.db", "counters", 1, "name = 'main_counter'")
+	assertdb.AssertRowCount(t, "sqlite://file:counter.db", "counters", 0, "value > 0")
+}
+
+func TestSeedAndAssert(t *testing.T) {
+	db, err := InitDB()
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	db.Close()
+
+	assertdb.SeedAndAssert(t, "sqlite://file:counter.db", dataSet, "dataset/hundred.yaml", "dataset/hundred.yaml", nil, nil)
+}
+
+func TestSeedAndAssertSame(t *testing.T) {
+	db, err := sql.Open("sqlite3", dbFileName)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS orders (
+		id INTEGER PRIMARY KEY,
+		status TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		updated_at TEXT
+	);`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	// seeding twice should produce the same state
+	assertdb.SeedAndAssertSame(t, "sqlite://file:counter.db", dataSet, "dataset/orders.yaml", nil, nil)
+	assertdb.SeedAndAssertSame(t, "sqlite://file:counter.db", dataSet, "dataset/orders.yaml", nil, nil)
+}
+
+// assertdb helpers accept testing.TB, so they can be used in benchmarks too.
+func BenchmarkSeedAndAssert(b *testing.B) {
+	for b.Loop() {
+		assertdb.SeedDataSet(b, "sqlite://file:counter.db", dataSet, "dataset/initial.yaml", nil)
+		assertdb.AssertDB(b, "sqlite://file:counter.db", dataSet, "dataset/initial.yaml", nil)
+		assertdb.AssertRowCount(b, "sqlite://file:counter.db", "counters", 1, "")
+	}
+}
+
+func TestSession(t *testing.T) {
+	dbc, err := dbtestify.NewDBConnector(t.Context(), "sqlite://file:counter.db")
+	if err != nil {
+		t.Fatalf("Failed to create DB connector: %v", err)
+	}
+	defer dbc.DB().Close()
+	s := assertdb.NewSession(dbc)
+
+	t.Run("seed and assert", func(t *testing.T) {
+		s.SeedAndAssert(t, dataSet, "dataset/hundred.yaml", "dataset/hundred.yaml", nil, nil)
+		s.SeedDataSet(t, dataSet, "dataset/initial.yaml", nil)
+		s.AssertDB(t, dataSet, "dataset/initial.yaml", nil)
+	})
+
+	// sub tests share the session in parallel
+	t.Run("parallel", func(t *testing.T) {
+		for _, name := range []string{"a", "b", "c"} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				s.AssertDB(t, dataSet, "dataset/initial.yaml", nil)
+				s.AssertRowCount(t, "counters", 1, "value = 0")
+			})
+		}
+	})
+}
+
+func TestAssertCounts(t *testing.T) {
+	assertdb.SeedDataSet(t, "sqlite://file:counter.db", dataSet, "dataset/initial.yaml", nil)
+
+	db, err := InitDB()
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	IncrementCounter(db)
+
+	// value is changed, but the number of rows is the same
+	assertdb.AssertCounts(t, "sqlite://file:counter.db", dataSet, "dataset/initial.yaml", nil)
+}
+
+func TestSeedDataSets(t *testing.T) {
+	// base fixture and additional rows are merged
+	assertdb.SeedDataSets(t, "sqlite://file:counter.db", dataSet, []string{"dataset/initial.yaml", "dataset/extra_counter.yaml"}, nil)
+
+	assertdb.AssertRowCount(t, "sqlite://file:counter.db", "counters", 2, "")
+	assertdb.AssertRowCount(t, "sqlite://file:counter.db", "counters", 1, "name = 'sub_counter' AND value = 10")
+}
+
+func TestAssertOrder(t *testing.T) {
+	assertdb.SeedDataSets(t, "sqlite://file:counter.db", dataSet, []string{"dataset/initial.yaml", "dataset/extra_counter.yaml"}, nil)
+
+	assertdb.AssertOrder(t, "sqlite://file:counter.db", "counters", "value DESC", []any{"sub_counter", "main_counter"})
+	assertdb.AssertOrder(t, "sqlite://file:counter.db", "counters", "value", []any{"main_counter", "sub_counter"})
+
+	t.Run("composite primary keys", func(t *testing.T) {
+		db, err := sql.Open("sqlite3", dbFileName)
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+		_, err = db.Exec(`
+		DROP TABLE IF EXISTS order_items;
+		CREATE TABLE order_items (
+			order_id INTEGER NOT NULL,
+			product_id INTEGER NOT NULL,
+			price INTEGER NOT NULL,
+			PRIMARY KEY (order_id, product_id)
+		);
+		INSERT INTO order_items (order_id, product_id, price) VALUES (1, 100, 300), (1, 101, 100), (2, 100, 200);`)
+		if err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+		assertdb.AssertOrder(t, "sqlite://file:counter.db", "order_items", "price", []any{[]any{1, 101}, []any{2, 100}, []any{1, 100}})
+	})
+
+	t.Run("different order", func(t *testing.T) {
+		r := &errorRecorder{TB: t}
+		assertdb.AssertOrder(r, "sqlite://file:counter.db", "counters", "value", []any{"sub_counter", "main_counter"})
+		if len(r.errors) != 1 {
+			t.Fatalf("AssertOrder should report an error, but: %v", r.errors)
+		}
+		if !strings.Contains(r.errors[0], "-expected +actual") {
+			t.Errorf("AssertOrder should report the diff, but: %s", r.errors[0])
+		}
+	})
+}
+
+// errorRecorder records Errorf calls instead of failing the test.
+type errorRecorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *errorRecorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCleanUp(t *testing.T) {
+	t.Run("seed", func(t *testing.T) {
+		assertdb.SeedDataSets(t, "sqlite://file:counter.db", dataSet, []string{"dataset/initial.yaml", "dataset/extra_counter.yaml"}, nil)
+		assertdb.CleanUp(t, "sqlite://file:counter.db", []string{"counters"})
+		assertdb.AssertRowCount(t, "sqlite://file:counter.db", "counters", 2, "")
+	})
+	// cleanup of the sub test is already called
+	assertdb.AssertRowCount(t, "sqlite://file:counter.db", "counters", 0, "")
+
+	t.Run("all tables", func(t *testing.T) {
+		db, err := sql.Open("sqlite3", dbFileName)
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+		_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS orders (
+			id INTEGER PRIMARY KEY,
+			status TEXT NOT NULL,
+			amount INTEGER NOT NULL,
+			updated_at TEXT
+		);`)
+		if err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+
+		t.Run("seed", func(t *testing.T) {
+			assertdb.CleanUpAll(t, "sqlite://file:counter.db")
+			assertdb.SeedDataSet(t, "sqlite://file:counter.db", dataSet, "dataset/initial.yaml", nil)
+			assertdb.SeedDataSet(t, "sqlite://file:counter.db", dataSet, "dataset/orders.yaml", nil)
+		})
+		assertdb.AssertRowCount(t, "sqlite://file:counter.db", "counters", 0, "")
+		assertdb.AssertRowCount(t, "sqlite://file:counter.db", "orders", 0, "")
+	})
+}
+
+// TestCleanUpAllPostgreSQL checks that the table referenced by a foreign key is truncated by a user without the superuser privilege.
+func TestCleanUpAllPostgreSQL(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	pgContainer, err := postgres.Run(ctx, "postgres:15.3-alpine",
+		postgres.WithDatabase("cleanup"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).WithStartupTimeout(5*time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pgContainer.Terminate(ctx)
+	adminConn, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("pgx", adminConn)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers(id));
+		INSERT INTO customers (id, name) VALUES (1, 'Frank');
+		INSERT INTO orders (id, customer_id) VALUES (1, 1);
+		CREATE ROLE tester LOGIN PASSWORD 'tester';
+		GRANT ALL ON customers, orders TO tester;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+	dbConn := strings.Replace(adminConn, "postgres:postgres@", "tester:tester@", 1)
+
+	t.Run("seed", func(t *testing.T) {
+		assertdb.CleanUpAll(t, dbConn)
+		assertdb.AssertRowCount(t, dbConn, "orders", 1, "")
+	})
+	assertdb.AssertRowCount(t, dbConn, "customers", 0, "")
+	assertdb.AssertRowCount(t, dbConn, "orders", 0, "")
+}
+
+// recorder records the logs and errors of assertdb helpers instead of failing the test.
+type recorder struct {
+	testing.TB
+	logs   []string
+	errors []string
+	onLog  func(n int)
+}
+
+func (r *recorder) Logf(format string, args ...any) {
+	r.logs = append(r.logs, fmt.Sprintf(format, args...))
+	if r.onLog != nil {
+		r.onLog(len(r.logs))
+	}
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertDBWithRetry(t *testing.T) {
+	t.Run("consistent at third attempt", func(t *testing.T) {
+		assertdb.SeedDataSet(t, "sqlite://file:counter.db", dataSet, "dataset/initial.yaml", nil)
+		db, err := InitDB()
+		if err != nil {
+			t.Fatalf("Failed to initialize database: %v", err)
+		}
+		defer db.Close()
+
+		// the asynchronous job finishes after the second failure
+		r := &recorder{TB: t, onLog: func(n int) {
+			if n == 2 {
+				IncrementCounter(db)
+			}
+		}}
+		assertdb.AssertDBWithRetry(r, "sqlite://file:counter.db", dataSet, "dataset/expect.yaml", 5, 10*time.Millisecond)
+		if len(r.logs) != 2 {
+			t.Errorf("Expected 2 failed attempts, but %d: %v", len(r.logs), r.logs)
+		}
+		if len(r.errors) != 0 {
+			t.Errorf("Unexpected errors: %v", r.errors)
+		}
+	})
+
+	t.Run("never consistent", func(t *testing.T) {
+		assertdb.SeedDataSet(t, "sqlite://file:counter.db", dataSet, "dataset/initial.yaml", nil)
+
+		r := &recorder{TB: t}
+		assertdb.AssertDBWithRetry(r, "sqlite://file:counter.db", dataSet, "dataset/expect.yaml", 3, 10*time.Millisecond)
+		if len(r.logs) != 3 {
+			t.Errorf("Expected 3 failed attempts, but %d: %v", len(r.logs), r.logs)
+		}
+		if len(r.errors) != 1 || !strings.Contains(r.errors[0], "after 3 attempts") {
+			t.Errorf("Unexpected errors: %v", r.errors)
+		}
+	})
+}
+
+func TestAssertDBParallel(t *testing.T) {
+	db, err := sql.Open("sqlite3", dbFileName)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS orders (
+		id INTEGER PRIMARY KEY,
+		status TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		updated_at TEXT
+	);`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	assertdb.SeedDataSets(t, "sqlite://file:counter.db", dataSet, []string{"dataset/initial.yaml", "dataset/orders.yaml"}, nil)
+
+	t.Run("independent tables", func(t *testing.T) {
+		assertdb.AssertDBParallel(t, "sqlite://file:counter.db", dataSet, []string{"dataset/initial.yaml", "dataset/orders.yaml"}, nil)
+	})
+
+	t.Run("failures of all files", func(t *testing.T) {
+		r := &recorder{TB: t}
+		assertdb.AssertDBParallel(r, "sqlite://file:counter.db", dataSet, []string{"dataset/expect.yaml", "dataset/orders.yaml", "dataset/missing.yaml"}, nil)
+		if len(r.errors) != 2 {
+			t.Fatalf("Expected 2 errors, but %d: %v", len(r.errors), r.errors)
+		}
+		// reported in the order of files
+		if !strings.Contains(r.errors[0], "dataset/expect.yaml") || !strings.Contains(r.errors[1], "dataset/missing.yaml") {
+			t.Errorf("Unexpected errors: %v", r.errors)
+		}
+	})
+
+	t.Run("diffs in the order of files", func(t *testing.T) {
+		folder := fstest.MapFS{
+			"counters.yaml": {Data: []byte("counters:\n- { name: main_counter, value: 100 }\n")},
+			"orders.yaml":   {Data: []byte("orders:\n- { id: 1, status: paid, amount: 9999 }\n")},
+		}
+		r := &recorder{TB: t}
+		assertdb.AssertDBParallel(r, "sqlite://file:counter.db", folder, []string{"orders.yaml", "counters.yaml"}, nil)
+		var diffs []string
+		for _, l := range r.logs {
+			if strings.Contains(l, "9999") || strings.Contains(l, "100") {
+				diffs = append(diffs, l)
+			}
+		}
+		if len(diffs) != 2 || !strings.Contains(diffs[0], "9999") || strings.Contains(diffs[0], "counters") || !strings.Contains(diffs[1], "counters") {
+			t.Errorf("Unexpected diffs: %q", r.logs)
+		}
+	})
+}
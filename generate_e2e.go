@@ -0,0 +1,92 @@
+package dbtestify
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultAPIBaseURL is the URL of the API server started by `dbtestify http` with the default port.
+const DefaultAPIBaseURL = "http://localhost:8000"
+
+// GeneratePlaywrightHooks generates a Playwright spec file per data set file in root.
+//
+// Each spec file has a `test.beforeEach` hook that seeds the data set by `POST /api/seed/{data set path}` of the API server at baseURL
+// (default: DefaultAPIBaseURL) and a test stub. The result is the map of the file path like "users/initial.spec.ts" and its content.
+func GeneratePlaywrightHooks(root fs.FS, baseURL string) (map[string][]byte, error) {
+	return generateJSHooks(root, baseURL, ".spec.ts", func(w *strings.Builder, name, seedURL string) {
+		fmt.Fprintf(w, "import { test } from '@playwright/test';\n\n")
+		fmt.Fprintf(w, "test.beforeEach(async () => {\n")
+		fmt.Fprintf(w, "  const res = await fetch(%s, { method: 'POST' });\n", jsString(seedURL))
+		fmt.Fprintf(w, "  if (!res.ok) {\n")
+		fmt.Fprintf(w, "    throw new Error(`dbtestify seed failed: ${res.status} ${await res.text()}`);\n")
+		fmt.Fprintf(w, "  }\n")
+		fmt.Fprintf(w, "});\n\n")
+		fmt.Fprintf(w, "test.fixme(%s, async ({ page }) => {\n", jsString(name))
+		fmt.Fprintf(w, "  // TODO: write the test for the seeded data\n")
+		fmt.Fprintf(w, "});\n")
+	})
+}
+
+// GenerateCypressHooks generates a Cypress spec file per data set file in root.
+//
+// Each spec file has a `beforeEach` block that seeds the data set by `POST /api/seed/{data set path}` of the API server at baseURL
+// (default: DefaultAPIBaseURL) and a pending test. The result is the map of the file path like "users/initial.cy.js" and its content.
+// It also has the "cypress.json" stub.
+func GenerateCypressHooks(root fs.FS, baseURL string) (map[string][]byte, error) {
+	result, err := generateJSHooks(root, baseURL, ".cy.js", func(w *strings.Builder, name, seedURL string) {
+		fmt.Fprintf(w, "describe(%s, () => {\n", jsString(name))
+		fmt.Fprintf(w, "  beforeEach(() => {\n")
+		fmt.Fprintf(w, "    cy.request('POST', %s);\n", jsString(seedURL))
+		fmt.Fprintf(w, "  });\n\n")
+		fmt.Fprintf(w, "  it('TODO: write the test for the seeded data');\n")
+		fmt.Fprintf(w, "});\n")
+	})
+	if err != nil {
+		return nil, err
+	}
+	result["cypress.json"] = []byte("{}\n")
+	return result, nil
+}
+
+// generateJSHooks walks the data set files in root and calls write per file with the seed API URL.
+func generateJSHooks(root fs.FS, baseURL, ext string, write func(w *strings.Builder, name, seedURL string)) (map[string][]byte, error) {
+	if baseURL == "" {
+		baseURL = DefaultAPIBaseURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	result := map[string][]byte{}
+	err := fs.WalkDir(root, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		e := path.Ext(p)
+		if d.IsDir() || (e != ".yaml" && e != ".yml") {
+			return nil
+		}
+		segments := strings.Split(p, "/")
+		for i, s := range segments {
+			segments[i] = url.PathEscape(s)
+		}
+		var w strings.Builder
+		fmt.Fprintf(&w, "// Generated by dbtestify from %s.\n", p)
+		write(&w, strings.TrimSuffix(path.Base(p), e), baseURL+"/api/seed/"+strings.Join(segments, "/"))
+		result[strings.TrimSuffix(p, e)+ext] = []byte(w.String())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no data set found. Data set should be YAML file")
+	}
+	return result, nil
+}
+
+// jsString quotes the string as a JavaScript string literal with single quotes.
+func jsString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`)
+	return "'" + r.Replace(s) + "'"
+}
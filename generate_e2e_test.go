@@ -0,0 +1,101 @@
+package dbtestify
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestGeneratePlaywrightHooks(t *testing.T) {
+	root := fstest.MapFS{
+		"initial.yaml":         {Data: []byte("users:\n")},
+		"users/admin user.yml": {Data: []byte("users:\n")},
+		"README.md":            {Data: []byte("# data sets\n")},
+	}
+	files, err := GeneratePlaywrightHooks(root, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(files))
+
+	src := string(files["initial.spec.ts"])
+	assert.Contains(t, src, "import { test } from '@playwright/test';\n")
+	assert.Contains(t, src, "test.beforeEach(async () => {\n  const res = await fetch('http://localhost:8000/api/seed/initial.yaml', { method: 'POST' });\n")
+	assert.Contains(t, src, "test.fixme('initial', async ({ page }) => {\n")
+
+	assert.True(t, balanced(src), "unbalanced braces: %s", src)
+
+	src = string(files["users/admin user.spec.ts"])
+	assert.Contains(t, src, "fetch('http://localhost:8000/api/seed/users/admin%20user.yml', { method: 'POST' })")
+
+	t.Run("base URL", func(t *testing.T) {
+		files, err := GeneratePlaywrightHooks(root, "https://example.com:9000/")
+		assert.NoError(t, err)
+		assert.Contains(t, string(files["initial.spec.ts"]), "fetch('https://example.com:9000/api/seed/initial.yaml'")
+	})
+
+	t.Run("no data set", func(t *testing.T) {
+		_, err := GeneratePlaywrightHooks(fstest.MapFS{"README.md": {Data: []byte("")}}, "")
+		assert.Error(t, err)
+	})
+}
+
+func TestGenerateCypressHooks(t *testing.T) {
+	root := fstest.MapFS{
+		"initial.yaml":   {Data: []byte("users:\n")},
+		"users/it's.yml": {Data: []byte("users:\n")},
+	}
+	files, err := GenerateCypressHooks(root, "http://localhost:9000")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(files))
+	assert.Equal(t, "{}\n", string(files["cypress.json"]))
+
+	src := string(files["initial.cy.js"])
+	assert.Contains(t, src, "describe('initial', () => {\n  beforeEach(() => {\n    cy.request('POST', 'http://localhost:9000/api/seed/initial.yaml');\n")
+	assert.True(t, balanced(src), "unbalanced braces: %s", src)
+
+	src = string(files["users/it's.cy.js"])
+	assert.Contains(t, src, `describe('it\'s', () => {`)
+	assert.Contains(t, src, `cy.request('POST', 'http://localhost:9000/api/seed/users/it%27s.yml');`)
+	assert.True(t, balanced(src), "unbalanced braces: %s", src)
+}
+
+// balanced checks that brackets outside of string literals and line comments are balanced.
+func balanced(src string) bool {
+	pairs := map[rune]rune{')': '(', '}': '{', ']': '['}
+	var stack []rune
+	var quote, prev rune
+	escaped, comment := false, false
+	for _, r := range src {
+		last := prev
+		prev = r
+		switch {
+		case comment:
+			comment = r != '\n'
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if r == '\\' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '/' && last == '/':
+			comment = true
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(' || r == '{' || r == '[':
+			stack = append(stack, r)
+		case pairs[r] != 0:
+			if len(stack) == 0 || stack[len(stack)-1] != pairs[r] {
+				return false
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return len(stack) == 0 && quote == 0
+}
+
+func Test_jsString(t *testing.T) {
+	assert.Equal(t, `'it\'s'`, jsString("it's"))
+	assert.Equal(t, `'a\\b\n'`, jsString("a\\b\n"))
+}
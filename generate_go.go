@@ -0,0 +1,133 @@
+package dbtestify
+
+import (
+	"context"
+	"fmt"
+	"go/format"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GoTestOpt is an option for GenerateGoTest.
+type GoTestOpt struct {
+	Package    string // Package name of the generated test file.
+	DBConn     string // Connection string that is written in the generated test file.
+	DataSetDir string // Folder of the data set files that is embedded by embed.FS (default: "dataset").
+}
+
+// GoTestFileName is the file name of the test file generated by GenerateGoTest.
+const GoTestFileName = "dbtestify_test.go"
+
+// GenerateGoTest generates the Go test boilerplate for all tables of the database.
+//
+// The result is the map of the file path (relative to the output folder) and its content:
+//
+//   - dbtestify_test.go: embed.FS declaration, TestMain that seeds "seed.yaml" and a test function per table that asserts "<table>.yaml".
+//   - <DataSetDir>/seed.yaml: the initial data set. All tables are empty.
+//   - <DataSetDir>/<table>.yaml: the expected data set of each table. Columns and types are written as a comment.
+//
+// Generated tests pass as they are. Add rows to the data sets and the code under test to the test functions.
+func GenerateGoTest(ctx context.Context, dbc DBConnector, opt GoTestOpt) (map[string][]byte, error) {
+	if !token.IsIdentifier(opt.Package) {
+		return nil, fmt.Errorf("invalid package name: '%s'", opt.Package)
+	}
+	dir := opt.DataSetDir
+	if dir == "" {
+		dir = "dataset"
+	}
+	tables, err := dbc.TableNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table names: %w", err)
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no table found in the database")
+	}
+
+	result := map[string][]byte{}
+	var src, seed strings.Builder
+	fmt.Fprintf(&src, "// Code generated by dbtestify generate go. Edit the data sets and stubs as you need.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", opt.Package)
+	src.WriteString("import (\n\"context\"\n\"embed\"\n\"fmt\"\n\"os\"\n\"testing\"\n\n\"github.com/shibukawa/dbtestify\"\n\"github.com/shibukawa/dbtestify/assertdb\"\n)\n\n")
+	fmt.Fprintf(&src, "//go:embed %s/*.yaml\nvar dataSet embed.FS\n\n", dir)
+	fmt.Fprintf(&src, "const dbConn = %s\n\n", strconv.Quote(opt.DBConn))
+	fmt.Fprintf(&src, `func TestMain(m *testing.M) {
+		if err := seed(context.Background(), %s); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to seed database: %%v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(m.Run())
+	}
+
+	func seed(ctx context.Context, fileName string) error {
+		dbc, err := dbtestify.NewDBConnector(ctx, dbConn)
+		if err != nil {
+			return err
+		}
+		defer dbc.DB().Close()
+		data, err := dbtestify.ParseYAMLFiles(dataSet, fileName)
+		if err != nil {
+			return err
+		}
+		return dbtestify.Seed(ctx, dbc, data, dbtestify.SeedOpt{})
+	}
+	`, strconv.Quote(path.Join(dir, "seed.yaml")))
+
+	names := map[string]bool{"TestMain": true}
+	for _, table := range tables {
+		columns, err := dbc.ColumnTypes(ctx, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read columns of table %s: %w", table, err)
+		}
+		var comment strings.Builder
+		for i, c := range columns {
+			if i > 0 {
+				comment.WriteString(", ")
+			}
+			fmt.Fprintf(&comment, "%s: %s", c.Name, c.DBType)
+		}
+		fmt.Fprintf(&seed, "%s:\n# - { %s }\n", yamlString(table), comment.String())
+		expectFile := path.Join(dir, table+".yaml")
+		result[expectFile] = fmt.Appendf(nil, "%s:\n# - { %s }\n", yamlString(table), comment.String())
+
+		name := goTestFuncName(table)
+		for i := 2; names[name]; i++ {
+			name = fmt.Sprintf("%s%d", goTestFuncName(table), i)
+		}
+		names[name] = true
+		fmt.Fprintf(&src, "\n// %s asserts the content of %s table.\n//\n// Columns:\n", name, table)
+		for _, c := range columns {
+			fmt.Fprintf(&src, "//   - %s %s\n", c.Name, c.DBType)
+		}
+		fmt.Fprintf(&src, "func %s(t *testing.T) {\n// TODO: call the code under test that modifies %s table\n\nassertdb.AssertDB(t, dbConn, dataSet, %s, nil)\n}\n", name, table, strconv.Quote(expectFile))
+	}
+	result[path.Join(dir, "seed.yaml")] = []byte(seed.String())
+
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+	result[GoTestFileName] = formatted
+	return result, nil
+}
+
+// goTestFuncName converts the table name like "public.user_accounts" into "TestPublicUserAccounts".
+func goTestFuncName(table string) string {
+	var b strings.Builder
+	b.WriteString("Test")
+	upper := true
+	for _, r := range table {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package dbtestify
+
+import (
+	"bytes"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func writeGeneratedFiles(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		assert.NoError(t, os.WriteFile(p, content, 0o644))
+	}
+}
+
+func TestGenerateGoTest(t *testing.T) {
+	dbc := newDuckDBTestConnector(t)
+	_, err := dbc.DB().ExecContext(t.Context(), `CREATE TABLE main (id INTEGER PRIMARY KEY)`)
+	assert.NoError(t, err)
+
+	files, err := GenerateGoTest(t.Context(), dbc, GoTestOpt{Package: "sample", DBConn: "duckdb://sample.db"})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(files))
+
+	src := string(files[GoTestFileName])
+	assert.Contains(t, src, "//go:embed dataset/*.yaml\nvar dataSet embed.FS")
+	assert.Contains(t, src, `const dbConn = "duckdb://sample.db"`)
+	assert.Contains(t, src, `seed(context.Background(), "dataset/seed.yaml")`)
+	assert.Contains(t, src, "func TestUsers(t *testing.T) {")
+	assert.Contains(t, src, `assertdb.AssertDB(t, dbConn, dataSet, "dataset/users.yaml", nil)`)
+	assert.Contains(t, src, "//   - name VARCHAR\n")
+	// "main" table doesn't conflict with TestMain
+	assert.Contains(t, src, "func TestMain2(t *testing.T) {")
+
+	seed, err := ParseYAML(bytes.NewReader(files["dataset/seed.yaml"]))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"accounts", "main", "orders", "users"}, seed.TableNames())
+	assert.Equal(t, "users:\n# - { id: INTEGER, name: VARCHAR, email: VARCHAR }\n", string(files["dataset/users.yaml"]))
+
+	dir := t.TempDir()
+	writeGeneratedFiles(t, dir, files)
+	pkg, err := build.ImportDir(dir, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "sample", pkg.Name)
+	assert.SliceContains(t, pkg.TestImports, "github.com/shibukawa/dbtestify/assertdb")
+	assert.Equal(t, []string{"dataset/*.yaml"}, pkg.TestEmbedPatterns)
+	_, err = parser.ParseFile(token.NewFileSet(), filepath.Join(dir, GoTestFileName), nil, parser.AllErrors)
+	assert.NoError(t, err)
+
+	t.Run("invalid package name", func(t *testing.T) {
+		_, err := GenerateGoTest(t.Context(), dbc, GoTestOpt{Package: "func"})
+		assert.Error(t, err)
+	})
+}
+
+// TestGenerateGoTestCompile type-checks the generated code in this module by go vet.
+func TestGenerateGoTestCompile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go vet of generated code in short mode")
+	}
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command is not found")
+	}
+	dir, err := os.MkdirTemp(".", "generate_go_test")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	dbc := newDuckDBTestConnector(t)
+	files, err := GenerateGoTest(t.Context(), dbc, GoTestOpt{Package: "sample", DBConn: "duckdb://" + filepath.Join(dir, "sample.db")})
+	assert.NoError(t, err)
+	writeGeneratedFiles(t, dir, files)
+
+	cmd := exec.CommandContext(t.Context(), goCmd, "vet", "./"+filepath.Base(dir))
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func Test_goTestFuncName(t *testing.T) {
+	assert.Equal(t, "TestUsers", goTestFuncName("users"))
+	assert.Equal(t, "TestPublicUserAccounts", goTestFuncName("public.user_accounts"))
+	assert.Equal(t, "Test2fa", goTestFuncName("2fa"))
+}
@@ -5,8 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 
 	"github.com/shibukawa/dbtestify"
@@ -27,11 +28,14 @@ type AssertTableResult struct {
 }
 
 type AssertResponse struct {
-	Tables []AssertTableResult `json:"tables"`
+	Tables    []AssertTableResult `json:"tables"`
+	Match     bool                `json:"match"`
+	DiffCount int                 `json:"diff_count"`
+	Summary   string              `json:"summary"`
 }
 
-func assertTable(ctx context.Context, dbc dbtestify.DBConnector, useJson bool, w http.ResponseWriter, path string, reqOpt AssertOpt) (bool, error) {
-	f, err := os.Open(path)
+func assertTable(ctx context.Context, dbc dbtestify.DBConnector, m *metrics, useJson bool, w http.ResponseWriter, dataFS fs.FS, path string, reqOpt AssertOpt) (bool, error) {
+	f, err := dataFS.Open(path)
 	if err != nil {
 		return false, err
 	}
@@ -40,21 +44,33 @@ func assertTable(ctx context.Context, dbc dbtestify.DBConnector, useJson bool, w
 	if err != nil {
 		return false, err
 	}
+	if err := checkTargets(data, reqOpt.Targets); err != nil {
+		return false, err
+	}
 
-	ok, cResult, err := dbtestify.Assert(ctx, dbc, data, dbtestify.AssertOpt{
+	_, cResult, err := dbtestify.Assert(ctx, dbc, data, dbtestify.AssertOpt{
 		IncludeTags:  reqOpt.IncludeTags,
 		ExcludeTags:  reqOpt.ExcludeTags,
 		TargetTables: reqOpt.Targets,
+		Callback:     m.assertCallback(nil),
 	})
 	if err != nil {
 		return false, err
 	}
+	m.recordAssertResult(cResult)
+	aResult := dbtestify.NewAssertResult(cResult)
+	ok := aResult.IsMatch()
+	w.Header().Set("X-Dbtestify-Match", strconv.FormatBool(ok))
 	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(http.StatusConflict)
 	}
 	if useJson {
-		var result AssertResponse
-		for _, tr := range cResult {
+		result := AssertResponse{
+			Match:     ok,
+			DiffCount: aResult.DiffCount(),
+			Summary:   aResult.Summary(),
+		}
+		for _, tr := range aResult.Tables {
 			result.Tables = append(result.Tables, AssertTableResult{
 				Table:       tr.Name,
 				PrimaryKeys: tr.PrimaryKeys,
@@ -65,9 +81,10 @@ func assertTable(ctx context.Context, dbc dbtestify.DBConnector, useJson bool, w
 		e := json.NewEncoder(w)
 		e.Encode(&result)
 	} else {
-		for _, tr := range cResult {
+		for _, tr := range aResult.Tables {
 			dumpDiff(w, tr)
 		}
+		fmt.Fprintln(w, aResult.Summary())
 	}
 	return ok, nil
 }
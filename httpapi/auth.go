@@ -0,0 +1,55 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AuthMiddleware returns a middleware that accepts only requests with the token.
+//
+// The token is read from `Authorization: Bearer <token>` header or `token` query parameter.
+// If the token is empty, all requests are accepted.
+func AuthMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if token == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				reqToken = r.URL.Query().Get("token")
+			}
+			if subtle.ConstantTimeCompare([]byte(reqToken), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="dbtestify"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BasicAuthMiddleware returns a middleware that accepts only requests with `Authorization: Basic <base64>` header
+// of the user and password.
+//
+// If both user and password are empty, all requests are accepted.
+func BasicAuthMiddleware(user, password string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if user == "" && password == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqUser, reqPassword, ok := r.BasicAuth()
+			// compare both to not leak which one is wrong by timing
+			userOK := subtle.ConstantTimeCompare([]byte(reqUser), []byte(user))
+			passwordOK := subtle.ConstantTimeCompare([]byte(reqPassword), []byte(password))
+			if !ok || userOK&passwordOK != 1 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="dbtestify", charset="UTF-8"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
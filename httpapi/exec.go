@@ -0,0 +1,172 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/shibukawa/dbtestify"
+)
+
+type ExecRequest struct {
+	SQL      string `json:"sql"`
+	AllowDDL bool   `json:"allow_ddl"`
+}
+
+type ExecResponse struct {
+	RowsAffected int64         `json:"rows_affected"`
+	Duration     time.Duration `json:"duration"`
+}
+
+// ddlKeywords are the first keywords of statements that change the schema.
+var ddlKeywords = []string{"CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME", "COMMENT", "GRANT", "REVOKE"}
+
+var errUnsupportedMediaType = errors.New("Content-Type should be application/json")
+
+func parseExecRequest(r *http.Request) (*ExecRequest, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return nil, errUnsupportedMediaType
+	}
+	var req ExecRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("request body is empty")
+		}
+		return nil, err
+	}
+	if strings.TrimSpace(req.SQL) == "" {
+		return nil, errors.New("sql is empty")
+	}
+	if !req.AllowDDL {
+		// multiple statements can hide DDL after the first one
+		if hasMultipleStatements(req.SQL) {
+			return nil, errors.New("multiple statements are not allowed")
+		}
+		if isDDL(req.SQL) {
+			return nil, errors.New("DDL statement requires allow_ddl: true")
+		}
+	}
+	return &req, nil
+}
+
+// isDDL reports whether the statement starts with a DDL keyword. Leading comments are skipped.
+func isDDL(query string) bool {
+	fields := strings.Fields(skipComments(query))
+	if len(fields) == 0 {
+		return false
+	}
+	first := strings.TrimRight(fields[0], ";")
+	for _, k := range ddlKeywords {
+		if strings.EqualFold(first, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipComments removes the leading white spaces and comments (-- ... and /* ... */) of the query.
+func skipComments(query string) string {
+	for {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		switch {
+		case strings.HasPrefix(query, "--"):
+			_, rest, found := strings.Cut(query, "\n")
+			if !found {
+				return ""
+			}
+			query = rest
+		case strings.HasPrefix(query, "/*"):
+			_, rest, found := strings.Cut(query[2:], "*/")
+			if !found {
+				return ""
+			}
+			query = rest
+		default:
+			return query
+		}
+	}
+}
+
+// hasMultipleStatements reports whether the query has ";" outside of string literals, quoted identifiers and comments.
+//
+// A trailing ";" is allowed. MySQL escapes quotes with backslashes, but PostgreSQL and SQLite don't,
+// so the query is checked in both ways.
+func hasMultipleStatements(query string) bool {
+	return findSemicolon(query, false) || findSemicolon(query, true)
+}
+
+func findSemicolon(query string, backslashEscape bool) bool {
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			// a doubled quote is an escaped quote in SQL, so it is handled as two literals
+			j := i + 1
+			for ; j < len(query) && query[j] != c; j++ {
+				if backslashEscape && query[j] == '\\' {
+					j++
+				}
+			}
+			i = j
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return false
+			}
+			i += end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return false
+			}
+			i += end + 3
+		case c == ';':
+			return skipComments(strings.TrimLeft(query[i+1:], "; \t\r\n")) != ""
+		}
+	}
+	return false
+}
+
+// execSQL runs the query in a transaction. It is committed on success and rolled back on error.
+func execSQL(ctx context.Context, dbc dbtestify.DBConnector, useJson bool, w io.Writer, query string) error {
+	start := time.Now()
+	tx, err := dbc.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		rows = -1 // the driver doesn't support it
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	result := ExecResponse{
+		RowsAffected: rows,
+		Duration:     time.Since(start),
+	}
+	if useJson {
+		e := json.NewEncoder(w)
+		e.Encode(&result)
+	} else if rows < 0 {
+		fmt.Fprintf(w, "executed (%s)\n", result.Duration)
+	} else {
+		fmt.Fprintf(w, "%d row(s) affected (%s)\n", rows, result.Duration)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/shibukawa/dbtestify"
+)
+
+// HealthResponse is the response of GET /health and GET /ready.
+type HealthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitzero"`
+}
+
+// healthHandler is the liveness probe. It always returns 200 while the server is running.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// readyHandler is the readiness probe. It returns 503 if the database can't be connected.
+func readyHandler(dbconn string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dbc, err := dbtestify.NewDBConnector(r.Context(), dbconn)
+		if err == nil {
+			err = dbc.Ping(r.Context())
+			dbc.DB().Close()
+		}
+		if err != nil {
+			writeHealth(w, http.StatusServiceUnavailable, HealthResponse{Status: "unavailable", Error: err.Error()})
+			return
+		}
+		writeHealth(w, http.StatusOK, HealthResponse{Status: "ok"})
+	}
+}
+
+func writeHealth(w http.ResponseWriter, status int, res HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&res)
+}
@@ -12,15 +12,15 @@ type ListResult struct {
 	DataSets []string `json:"datasets"`
 }
 
-func dumpDataSetList(useJson bool, w io.Writer, root fs.FS, port uint16) {
+func dumpDataSetList(useJson bool, w io.Writer, dataSets []string, port uint16) {
 	if useJson {
 		result := ListResult{
-			DataSets: getTestList(root),
+			DataSets: dataSets,
 		}
 		e := json.NewEncoder(w)
 		e.Encode(&result)
 	} else {
-		for _, ds := range getTestList(root) {
+		for _, ds := range dataSets {
 			fmt.Fprintf(w, "* %s\n", ds)
 			fmt.Fprintf(w, "    * Seed:   curl -X POST http://localhost:%d/api/seed/%s\n", port, ds)
 			fmt.Fprintf(w, "    * Assert: curl http://localhost:%d/api/assert/%s\n", port, ds)
@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/shibukawa/dbtestify"
+)
+
+// metrics records the Prometheus metrics of seed and assert per table.
+//
+// The nil metrics is valid and doesn't record anything (the server is started without metrics).
+type metrics struct {
+	registry         *prometheus.Registry
+	seedDuration     *prometheus.HistogramVec
+	seedErrors       *prometheus.CounterVec
+	assertDuration   *prometheus.HistogramVec
+	assertMismatches *prometheus.CounterVec
+}
+
+// newMetrics creates the metrics with its own registry, so multiple servers in one process don't conflict.
+//
+// Go runtime and process metrics are also registered like promhttp.Handler().
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		seedDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "dbtestify_seed_duration_seconds",
+			Help: "Duration of seeding each table.",
+		}, []string{"table", "task"}),
+		seedErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dbtestify_seed_errors_total",
+			Help: "Number of tables that failed to seed.",
+		}, []string{"table", "task"}),
+		assertDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "dbtestify_assert_duration_seconds",
+			Help: "Duration of fetching and comparing each table.",
+		}, []string{"table"}),
+		assertMismatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "dbtestify_assert_mismatches_total",
+			Help: "Number of tables that didn't match the expected data set.",
+		}, []string{"table"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.seedDuration, m.seedErrors, m.assertDuration, m.assertMismatches,
+	)
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// seedCallback wraps SeedOpt.Callback to record the duration and errors of each task.
+func (m *metrics) seedCallback(next func(targetTable, task string, start bool, err error)) func(targetTable, task string, start bool, err error) {
+	if m == nil {
+		return next
+	}
+	var startTime time.Time
+	return func(targetTable, task string, start bool, err error) {
+		if start {
+			startTime = time.Now()
+		} else {
+			m.seedDuration.WithLabelValues(targetTable, task).Observe(time.Since(startTime).Seconds())
+			if err != nil {
+				m.seedErrors.WithLabelValues(targetTable, task).Inc()
+			}
+		}
+		if next != nil {
+			next(targetTable, task, start, err)
+		}
+	}
+}
+
+// assertCallback wraps AssertOpt.Callback to record the duration of each table.
+func (m *metrics) assertCallback(next func(targetTable string, s dbtestify.MatchStrategy, start bool, err error)) func(targetTable string, s dbtestify.MatchStrategy, start bool, err error) {
+	if m == nil {
+		return next
+	}
+	var startTime time.Time
+	return func(targetTable string, s dbtestify.MatchStrategy, start bool, err error) {
+		if start {
+			startTime = time.Now()
+		} else {
+			m.assertDuration.WithLabelValues(targetTable).Observe(time.Since(startTime).Seconds())
+		}
+		if next != nil {
+			next(targetTable, s, start, err)
+		}
+	}
+}
+
+// recordAssertResult counts the tables that don't match.
+func (m *metrics) recordAssertResult(result []dbtestify.AssertTableResult) {
+	if m == nil {
+		return
+	}
+	for _, t := range result {
+		if t.Status != dbtestify.Match {
+			m.assertMismatches.WithLabelValues(t.Name).Inc()
+		}
+	}
+}
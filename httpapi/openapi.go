@@ -0,0 +1,233 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+)
+
+// buildOpenAPISpec builds the OpenAPI 3.0 document of the API server.
+//
+// Request and response schemas are generated from the Go types, so they follow the JSON output.
+func buildOpenAPISpec() (*openapi3.T, error) {
+	schemas := openapi3.Schemas{}
+	gen := openapi3gen.NewGenerator(openapi3gen.UseAllExportedFields())
+	for name, v := range map[string]any{
+		"ListResult":     ListResult{},
+		"TablesResult":   TablesResult{},
+		"SchemaResult":   SchemaResult{},
+		"SeedOpt":        SeedOpt{},
+		"SeedResponse":   SeedResponse{},
+		"ProgressEvent":  ProgressEvent{},
+		"AssertResponse": AssertResponse{},
+		"ExecRequest":    ExecRequest{},
+		"ExecResponse":   ExecResponse{},
+		"HealthResponse": HealthResponse{},
+	} {
+		ref, err := gen.NewSchemaRefForValue(v, schemas)
+		if err != nil {
+			return nil, fmt.Errorf("can't generate schema for %s: %w", name, err)
+		}
+		schemas[name] = ref
+	}
+	ref := func(name string) *openapi3.SchemaRef {
+		return openapi3.NewSchemaRef("#/components/schemas/"+name, nil)
+	}
+
+	jsonOrText := func(description, schema string) *openapi3.Responses {
+		responses := openapi3.NewResponses()
+		responses.Set("200", &openapi3.ResponseRef{Value: openapi3.NewResponse().
+			WithDescription(description).
+			WithContent(openapi3.Content{
+				"application/json": openapi3.NewMediaType().WithSchemaRef(ref(schema)),
+				"text/plain":       openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema()),
+			})})
+		return responses
+	}
+	withError := func(responses *openapi3.Responses, status int, description string) *openapi3.Responses {
+		responses.Set(fmt.Sprint(status), &openapi3.ResponseRef{Value: openapi3.NewResponse().
+			WithDescription(description).
+			WithContent(openapi3.NewContentWithSchema(openapi3.NewStringSchema(), []string{"text/plain"}))})
+		return responses
+	}
+	pathParam := func(name, description string) *openapi3.ParameterRef {
+		return &openapi3.ParameterRef{Value: openapi3.NewPathParameter(name).
+			WithDescription(description).
+			WithSchema(openapi3.NewStringSchema())}
+	}
+	queryParam := func(name, description string, multi bool) *openapi3.ParameterRef {
+		schema := openapi3.NewStringSchema()
+		if multi {
+			schema = openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema())
+		}
+		return &openapi3.ParameterRef{Value: openapi3.NewQueryParameter(name).
+			WithDescription(description).
+			WithSchema(schema)}
+	}
+	dataSetPath := pathParam("path", "Data set file path in the data set folder")
+	seedParams := openapi3.Parameters{
+		dataSetPath,
+		queryParam("include_tag", "Tag of rows to include", true),
+		queryParam("exclude_tag", "Tag of rows to exclude", true),
+		queryParam("target", "Target table", true),
+		queryParam("truncate", "Table to truncate before seeding", true),
+		&openapi3.ParameterRef{Value: openapi3.NewQueryParameter("batch_size").
+			WithDescription("Number of rows per INSERT (default: 50)").
+			WithSchema(openapi3.NewIntegerSchema())},
+	}
+
+	spec := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       "dbtestify API",
+			Description: "Seed and assert database content with data set files",
+			Version:     "1.0.0",
+		},
+		Servers: openapi3.Servers{
+			// relative to the URL the spec is fetched from, so it works with both HTTP and HTTPS
+			{URL: "/"},
+		},
+		Paths: openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: schemas,
+			SecuritySchemes: openapi3.SecuritySchemes{
+				"bearerAuth": &openapi3.SecuritySchemeRef{Value: openapi3.NewSecurityScheme().WithType("http").WithScheme("bearer")},
+			},
+		},
+		Security: openapi3.SecurityRequirements{
+			// the token is required only when the server is started with --token
+			openapi3.NewSecurityRequirement(),
+			openapi3.NewSecurityRequirement().Authenticate("bearerAuth"),
+		},
+	}
+
+	spec.AddOperation("/api/list", http.MethodGet, &openapi3.Operation{
+		OperationID: "list",
+		Summary:     "Show data set file list",
+		Responses:   jsonOrText("Data set file list", "ListResult"),
+	})
+	spec.AddOperation("/api/tables", http.MethodGet, &openapi3.Operation{
+		OperationID: "tables",
+		Summary:     "Show table list and row counts",
+		Parameters: openapi3.Parameters{
+			queryParam("schema", "Schema name", false),
+		},
+		Responses: withError(jsonOrText("Table list", "TablesResult"), http.StatusInternalServerError, "Database error"),
+	})
+	spec.AddOperation("/api/schema/{table}", http.MethodGet, &openapi3.Operation{
+		OperationID: "schema",
+		Summary:     "Show column types of the table",
+		Parameters: openapi3.Parameters{
+			pathParam("table", "Table name"),
+		},
+		Responses: withError(jsonOrText("Column list", "SchemaResult"), http.StatusNotFound, "Table is not found"),
+	})
+	fixture := openapi3.NewStringSchema().WithFormat("binary")
+	fixture.Description = "YAML content of the data set. The data set file of the path is not used if it exists"
+	fixtureForm := openapi3.NewSchema()
+	fixtureForm.AllOf = openapi3.SchemaRefs{
+		ref("SeedOpt"),
+		openapi3.NewObjectSchema().WithProperty("fixture", fixture).NewRef(),
+	}
+	seedBody := openapi3.NewRequestBody().
+		WithDescription("Seed options. Form fields use the same names as the query parameters of /api/progress").
+		WithContent(openapi3.Content{
+			"application/json":                  openapi3.NewMediaType().WithSchemaRef(ref("SeedOpt")),
+			"application/x-www-form-urlencoded": openapi3.NewMediaType().WithSchemaRef(ref("SeedOpt")),
+			"multipart/form-data":               openapi3.NewMediaType().WithSchema(fixtureForm),
+		})
+	spec.AddOperation("/api/seed/{path}", http.MethodPost, &openapi3.Operation{
+		OperationID: "seed",
+		Summary:     "Seed database content with the specified data set",
+		Parameters:  openapi3.Parameters{dataSetPath},
+		RequestBody: &openapi3.RequestBodyRef{Value: seedBody},
+		Responses:   withError(jsonOrText("Seed result", "SeedResponse"), http.StatusBadRequest, "Invalid request"),
+	})
+	progressResponses := openapi3.NewResponses()
+	progressResponses.Set("200", &openapi3.ResponseRef{Value: openapi3.NewResponse().
+		WithDescription("Server-sent events. Each `data:` line is a ProgressEvent").
+		WithContent(openapi3.Content{
+			"text/event-stream": openapi3.NewMediaType().WithSchemaRef(ref("ProgressEvent")),
+		})})
+	spec.AddOperation("/api/progress/{path}", http.MethodGet, &openapi3.Operation{
+		OperationID: "progress",
+		Summary:     "Seed database content and stream the progress",
+		Parameters:  seedParams,
+		Responses:   withError(progressResponses, http.StatusBadRequest, "Invalid request"),
+	})
+	assertResponses := withError(jsonOrText("Assert result. The database content matches the data set", "AssertResponse"), http.StatusInternalServerError, "Assert error")
+	assertResponses.Set("409", &openapi3.ResponseRef{Value: openapi3.NewResponse().
+		WithDescription("Assert result. The database content doesn't match the data set").
+		WithContent(openapi3.Content{
+			"application/json": openapi3.NewMediaType().WithSchemaRef(ref("AssertResponse")),
+			"text/plain":       openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema()),
+		})})
+	matchHeader := &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+		Description: "`true` if the database content matches the data set, otherwise `false`",
+		Schema:      openapi3.NewBoolSchema().NewRef(),
+	}}}
+	for _, status := range []string{"200", "409"} {
+		assertResponses.Value(status).Value.Headers = openapi3.Headers{"X-Dbtestify-Match": matchHeader}
+	}
+	spec.AddOperation("/api/assert/{path}", http.MethodGet, &openapi3.Operation{
+		OperationID: "assert",
+		Summary:     "Assert database content with the specified data set",
+		Parameters: openapi3.Parameters{
+			dataSetPath,
+			queryParam("include-tag", "Tag of rows to include", true),
+			queryParam("exclude-tag", "Tag of rows to exclude", true),
+			queryParam("target", "Target table", true),
+		},
+		Responses: assertResponses,
+	})
+	snapshotResponses := openapi3.NewResponses()
+	snapshotResponses.Set("200", &openapi3.ResponseRef{Value: openapi3.NewResponse().
+		WithDescription("Written data set").
+		WithContent(openapi3.NewContentWithSchema(openapi3.NewStringSchema(), []string{"text/yaml"}))})
+	spec.AddOperation("/api/snapshot/{path}", http.MethodGet, &openapi3.Operation{
+		OperationID: "snapshot",
+		Summary:     "Write current database content to the data set",
+		Parameters: openapi3.Parameters{
+			dataSetPath,
+			queryParam("tables", "Target table (default: all tables)", true),
+		},
+		Responses: withError(snapshotResponses, http.StatusBadRequest, "Invalid data set path or unknown table"),
+	})
+	execResponses := withError(jsonOrText("Execution result", "ExecResponse"), http.StatusBadRequest, "Invalid request, multiple statements or DDL without allow_ddl")
+	withError(execResponses, http.StatusForbidden, "The server is not started with --allow-exec")
+	withError(execResponses, http.StatusUnsupportedMediaType, "Content-Type is not application/json")
+	withError(execResponses, http.StatusInternalServerError, "SQL error. The transaction is rolled back")
+	spec.AddOperation("/api/exec", http.MethodPost, &openapi3.Operation{
+		OperationID: "exec",
+		Summary:     "Execute SQL in a transaction (requires --allow-exec)",
+		RequestBody: &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().
+			WithRequired(true).
+			WithJSONSchemaRef(ref("ExecRequest"))},
+		Responses: execResponses,
+	})
+	healthResponse := func(description string) *openapi3.ResponseRef {
+		return &openapi3.ResponseRef{Value: openapi3.NewResponse().
+			WithDescription(description).
+			WithJSONSchemaRef(ref("HealthResponse"))}
+	}
+	healthResponses := openapi3.NewResponses()
+	healthResponses.Set("200", healthResponse("The server is running"))
+	spec.AddOperation("/health", http.MethodGet, &openapi3.Operation{
+		OperationID: "health",
+		Summary:     "Liveness probe",
+		Security:    &openapi3.SecurityRequirements{},
+		Responses:   healthResponses,
+	})
+	readyResponses := openapi3.NewResponses()
+	readyResponses.Set("200", healthResponse("The database is reachable"))
+	readyResponses.Set("503", healthResponse("The database is unreachable"))
+	spec.AddOperation("/ready", http.MethodGet, &openapi3.Operation{
+		OperationID: "ready",
+		Summary:     "Readiness probe",
+		Security:    &openapi3.SecurityRequirements{},
+		Responses:   readyResponses,
+	})
+	return spec, nil
+}
@@ -0,0 +1,92 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/shibukawa/dbtestify"
+)
+
+// ProgressEvent is sent as server-sent event for each step of seeding.
+type ProgressEvent struct {
+	Table      string `json:"table"`
+	Task       string `json:"task"`
+	Start      bool   `json:"start,omitzero"`
+	DurationMS int64  `json:"duration_ms,omitzero"`
+	Error      string `json:"error,omitzero"`
+}
+
+// seedWithProgress seeds the database and streams the progress as server-sent events.
+//
+// After seeding, `done` event is sent. If seeding fails, `error` event is sent instead.
+func seedWithProgress(ctx context.Context, dbc dbtestify.DBConnector, m *metrics, w http.ResponseWriter, dataFS fs.FS, path string, reqOpt SeedOpt) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("streaming is not supported")
+	}
+	f, err := dataFS.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := dbtestify.ParseYAML(f)
+	if err != nil {
+		return err
+	}
+	if err := checkTargets(data, reqOpt.Targets); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	send := func(event string, v any) {
+		if event != "" {
+			fmt.Fprintf(w, "event: %s\n", event)
+		}
+		b, _ := json.Marshal(v)
+		fmt.Fprintf(w, "data: %s\n\n", b)
+		flusher.Flush()
+	}
+
+	var startTime time.Time
+	opt := dbtestify.SeedOpt{
+		BatchSize:    reqOpt.BatchSize,
+		Operations:   map[string]dbtestify.Operation{},
+		IncludeTags:  reqOpt.IncludeTags,
+		ExcludeTags:  reqOpt.ExcludeTags,
+		TargetTables: reqOpt.Targets,
+		Callback: m.seedCallback(func(targetTable, task string, start bool, err error) {
+			e := ProgressEvent{
+				Table: targetTable,
+				Task:  task,
+			}
+			if start {
+				startTime = time.Now()
+				e.Start = true
+			} else {
+				e.DurationMS = time.Since(startTime).Milliseconds()
+				if err != nil {
+					e.Error = err.Error()
+				}
+			}
+			send("", e)
+		}),
+	}
+	for t, op := range data.Operation {
+		opt.Operations[t] = op
+	}
+	for _, t := range reqOpt.Truncates {
+		opt.Operations[t] = dbtestify.TruncateOperation
+	}
+	if err := dbtestify.Seed(ctx, dbc, data, opt); err != nil {
+		send("error", map[string]string{"error": err.Error()})
+		return nil
+	}
+	send("done", struct{}{})
+	return nil
+}
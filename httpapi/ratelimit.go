@@ -0,0 +1,48 @@
+package httpapi
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitMiddleware returns a middleware that limits requests per second of each client IP address.
+//
+// Requests over the limit get 429 Too Many Requests with Retry-After header. The burst size is the same as rps.
+// If rps is 0 or less, all requests are accepted.
+func RateLimitMiddleware(rps int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if rps <= 0 {
+			return next
+		}
+		var lock sync.Mutex
+		// the server is for development and CI, so the number of clients is small enough to keep all
+		limiters := make(map[string]*rate.Limiter)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+			lock.Lock()
+			limiter, ok := limiters[ip]
+			if !ok {
+				limiter = rate.NewLimiter(rate.Limit(rps), rps)
+				limiters[ip] = limiter
+			}
+			lock.Unlock()
+
+			reservation := limiter.Reserve()
+			if delay := reservation.Delay(); delay > 0 {
+				reservation.Cancel()
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
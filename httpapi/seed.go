@@ -1,11 +1,14 @@
 package httpapi
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
+	"io/fs"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/shibukawa/dbtestify"
@@ -17,6 +20,8 @@ type SeedOpt struct {
 	BatchSize   int      `json:"batch_size"`
 	Truncates   []string `json:"truncates"`
 	Targets     []string `json:"targets"`
+	// Fixture is the YAML content uploaded as `fixture` part of multipart/form-data. It is used instead of the data set file.
+	Fixture []byte `json:"-"`
 }
 
 type SeedTableResult struct {
@@ -31,14 +36,24 @@ type SeedResponse struct {
 	Tables []SeedTableResult `json:"tables"`
 }
 
-func seedTable(ctx context.Context, dbc dbtestify.DBConnector, useJson bool, w io.Writer, path string, reqOpt SeedOpt) error {
-	f, err := os.Open(path)
+func seedTable(ctx context.Context, dbc dbtestify.DBConnector, m *metrics, useJson bool, w io.Writer, dataFS fs.FS, path string, reqOpt SeedOpt) error {
+	var data *dbtestify.DataSet
+	var err error
+	if reqOpt.Fixture != nil {
+		data, err = dbtestify.ParseYAML(bytes.NewReader(reqOpt.Fixture))
+	} else {
+		var f fs.File
+		f, err = dataFS.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		data, err = dbtestify.ParseYAML(f)
+	}
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	data, err := dbtestify.ParseYAML(f)
-	if err != nil {
+	if err := checkTargets(data, reqOpt.Targets); err != nil {
 		return err
 	}
 
@@ -50,7 +65,7 @@ func seedTable(ctx context.Context, dbc dbtestify.DBConnector, useJson bool, w i
 		IncludeTags:  reqOpt.IncludeTags,
 		ExcludeTags:  reqOpt.ExcludeTags,
 		TargetTables: reqOpt.Targets,
-		Callback: func(targetTable, task string, start bool, err error) {
+		Callback: m.seedCallback(func(targetTable, task string, start bool, err error) {
 			if start {
 				startTime = time.Now()
 			} else if err != nil {
@@ -69,7 +84,7 @@ func seedTable(ctx context.Context, dbc dbtestify.DBConnector, useJson bool, w i
 					Duration: time.Since(startTime),
 				})
 			}
-		},
+		}),
 	}
 	for _, t := range reqOpt.Truncates {
 		opt.Operations[t] = dbtestify.TruncateOperation
@@ -93,3 +108,16 @@ func seedTable(ctx context.Context, dbc dbtestify.DBConnector, useJson bool, w i
 	}
 	return nil
 }
+
+// checkTargets returns an error if some target tables (or patterns) don't match any table in the data set.
+func checkTargets(data *dbtestify.DataSet, targets []string) error {
+	names := data.TableNames()
+	for _, t := range targets {
+		if !slices.ContainsFunc(names, func(name string) bool {
+			return dbtestify.MatchTargetTables([]string{t}, name)
+		}) {
+			return fmt.Errorf("target table '%s' is not in the data set (tables: %s)", t, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
@@ -6,8 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
-	"os"
+	"net/url"
 	"path/filepath"
 	"slices"
 	"strconv"
@@ -23,32 +24,320 @@ func jsonAcceptable(r *http.Request) bool {
 	return strings.Contains(r.Header.Get("Accept"), "application/json")
 }
 
-func Start(ctx context.Context, dir, dbconn string, port uint16) error {
+// ServerOpt is an option for Start.
+type ServerOpt func(c *serverConfig)
+
+type serverConfig struct {
+	token       string
+	basicUser   string
+	basicPass   string
+	corsOrigins []string
+	rateLimit   int
+	allowExec   bool
+	watch       bool
+	dataDir     string
+	noMetrics   bool
+}
+
+// WithToken requires the token for all API requests. See AuthMiddleware.
+func WithToken(token string) ServerOpt {
+	return func(c *serverConfig) {
+		c.token = token
+	}
+}
+
+// WithBasicAuth requires Basic authentication for the endpoints that seed or assert the database. See BasicAuthMiddleware.
+func WithBasicAuth(user, password string) ServerOpt {
+	return func(c *serverConfig) {
+		c.basicUser = user
+		c.basicPass = password
+	}
+}
+
+// WithRateLimit limits POST /api/seed requests per second of each client. See RateLimitMiddleware.
+func WithRateLimit(rps int) ServerOpt {
+	return func(c *serverConfig) {
+		c.rateLimit = rps
+	}
+}
+
+// WithCORSOrigins limits the origins of cross-origin requests. All origins are allowed by default. See CORSMiddleware.
+func WithCORSOrigins(origins ...string) ServerOpt {
+	return func(c *serverConfig) {
+		c.corsOrigins = append(c.corsOrigins, origins...)
+	}
+}
+
+// WithExec enables POST /api/exec that runs arbitrary SQL. It is disabled by default.
+//
+// It requires WithToken.
+func WithExec() ServerOpt {
+	return func(c *serverConfig) {
+		c.allowExec = true
+	}
+}
+
+// WithWatch monitors the data set folder and refreshes the data set list of /api/list when files are added or removed.
+//
+// It requires WithDataDir.
+func WithWatch() ServerOpt {
+	return func(c *serverConfig) {
+		c.watch = true
+	}
+}
+
+// WithoutMetrics disables GET /metrics that exposes the Prometheus metrics of seed and assert.
+func WithoutMetrics() ServerOpt {
+	return func(c *serverConfig) {
+		c.noMetrics = true
+	}
+}
+
+// WithDataDir tells the folder that the data set fs.FS reads from.
+//
+// It is required by /api/snapshot that writes data set files and by WithWatch.
+func WithDataDir(dir string) ServerOpt {
+	return func(c *serverConfig) {
+		c.dataDir = dir
+	}
+}
+
+// Start starts the API server that reads data set files from dataFS.
+//
+// To serve a folder, pass os.DirFS (or os.Root.FS) and WithDataDir. Any fs.FS works for seed and assert,
+// so data sets on object storages can be used via fs.FS adapters.
+func Start(ctx context.Context, dataFS fs.FS, dbconn string, port uint16, opts ...ServerOpt) error {
+	return StartTLS(ctx, dataFS, dbconn, port, "", "", opts...)
+}
+
+// StartTLS is the same as Start, but it serves HTTPS with the certificate and key files.
+//
+// If both certFile and keyFile are empty, it serves plain HTTP.
+func StartTLS(ctx context.Context, dataFS fs.FS, dbconn string, port uint16, certFile, keyFile string, opts ...ServerOpt) error {
+	var config serverConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	useTLS := certFile != "" || keyFile != ""
 	// check parameter
-	root, err := os.OpenRoot(dir)
+	if useTLS && (certFile == "" || keyFile == "") {
+		return errors.New("both certificate file and key file are required for TLS")
+	}
+	if config.allowExec && config.token == "" {
+		return errors.New("token is required to enable exec")
+	}
+	if config.watch && config.dataDir == "" {
+		return errors.New("data set folder is required to watch data sets")
+	}
+	if len(getTestList(dataFS)) == 0 {
+		if config.dataDir != "" {
+			return fmt.Errorf("No data set found in '%s'. Data set should be YAML file.", config.dataDir)
+		}
+		return errors.New("No data set found. Data set should be YAML file.")
+	}
+	err := testDBConnection(ctx, dbconn)
 	if err != nil {
 		return err
 	}
-	if len(getTestList(root.FS())) == 0 {
-		return fmt.Errorf("No data set found in '%s'. Data set should be YAML file.", dir)
-	}
-	err = testDBConnection(ctx, dbconn)
+
+	handler, err := newHandler(ctx, dataFS, dbconn, port, config)
 	if err != nil {
 		return err
 	}
 
-	m := http.NewServeMux()
-	m.HandleFunc("GET /api/list", func(w http.ResponseWriter, r *http.Request) {
+	s := &http.Server{
+		Addr:    ":" + strconv.Itoa(int(port)),
+		Handler: wrapHandler(handler, config),
+	}
+	go func() {
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	}()
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	fmt.Printf(`dbtestify API server
+	
+	GET  %[2]s://localhost:%[1]d/api/list                      : Show data set file list
+	GET  %[2]s://localhost:%[1]d/api/openapi.json              : Show OpenAPI spec of this API
+	GET  %[2]s://localhost:%[1]d/api/tables                    : Show table list and row counts
+	GET  %[2]s://localhost:%[1]d/api/schema/{table}            : Show column types of the table
+	POST %[2]s://localhost:%[1]d/api/seed/{data set path}      : Seed database content with the specified data set
+	GET  %[2]s://localhost:%[1]d/api/progress/{data set path}  : Seed database content and stream the progress (server-sent events)
+	GET  %[2]s://localhost:%[1]d/api/assert/{data set path}    : Assert database content with the specified data set
+	GET  %[2]s://localhost:%[1]d/api/snapshot/{data set path}  : Write current database content to the data set
+	GET  %[2]s://localhost:%[1]d/health                        : Liveness probe
+	GET  %[2]s://localhost:%[1]d/ready                         : Readiness probe (checks the database connection)
+	`, port, scheme)
+	if !config.noMetrics {
+		fmt.Printf("GET  %[2]s://localhost:%[1]d/metrics                       : Show Prometheus metrics\n\t", port, scheme)
+	}
+	if config.allowExec {
+		fmt.Printf("POST %[2]s://localhost:%[1]d/api/exec                      : Execute SQL in a transaction\n\t", port, scheme)
+	}
+
+	if config.watch {
+		fmt.Printf("watching %s\n", config.dataDir)
+	}
+	fmt.Printf("start receiving at :%d\n", port)
+	if useTLS {
+		err = s.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		err = s.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// wrapHandler applies the middlewares to the API handler.
+func wrapHandler(handler http.Handler, config serverConfig) http.Handler {
+	// probes of Kubernetes don't send the token
+	root := http.NewServeMux()
+	root.Handle("GET /health", handler)
+	root.Handle("GET /ready", handler)
+	auth := AuthMiddleware(config.token)
+	basicAuth := BasicAuthMiddleware(config.basicUser, config.basicPass)
+	root.Handle("POST /api/seed/", RateLimitMiddleware(config.rateLimit)(auth(basicAuth(handler))))
+	for _, path := range []string{"/api/seed/", "/api/progress/", "/api/assert/"} {
+		root.Handle(path, auth(basicAuth(handler)))
+	}
+	root.Handle("/", auth(handler))
+	return CORSMiddleware(config.corsOrigins)(root)
+}
+
+func newHandler(ctx context.Context, dataFS fs.FS, dbconn string, port uint16, config serverConfig) (http.Handler, error) {
+	dataSets := newDataSetList(dataFS)
+	if config.watch {
+		if err := dataSets.watch(ctx, config.dataDir); err != nil {
+			return nil, fmt.Errorf("can't watch '%s': %w", config.dataDir, err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	var m *metrics
+	if !config.noMetrics {
+		m = newMetrics()
+		mux.Handle("GET /metrics", m.handler())
+	}
+	mux.HandleFunc("GET /health", healthHandler)
+	mux.HandleFunc("GET /ready", readyHandler(dbconn))
+	mux.HandleFunc("GET /api/list", func(w http.ResponseWriter, r *http.Request) {
 		useJson := jsonAcceptable(r)
 		if useJson {
 			w.Header().Set("Content-Type", "application/json")
 		} else {
 			w.Header().Set("Content-Type", "text/plain")
 		}
-		dumpDataSetList(useJson, w, root.FS(), port)
+		dumpDataSetList(useJson, w, dataSets.get(), port)
+	})
+
+	mux.HandleFunc("GET /api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		spec, err := buildOpenAPISpec()
+		if err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf("spec error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(spec)
 	})
 
-	m.HandleFunc("POST /api/seed/{path...}", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("GET /api/tables", func(w http.ResponseWriter, r *http.Request) {
+		useJson := jsonAcceptable(r)
+		dbc, err := dbtestify.NewDBConnector(ctx, dbconn)
+		if err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf(`database connection error: %v`, err), http.StatusInternalServerError)
+			return
+		}
+		defer dbc.DB().Close()
+		var schema []string
+		if s := r.URL.Query().Get("schema"); s != "" {
+			schema = append(schema, s)
+		}
+		tables, err := getTableList(r.Context(), dbc, schema...)
+		if err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf("table list error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if useJson {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "text/plain")
+		}
+		dumpTableList(useJson, w, tables)
+	})
+
+	mux.HandleFunc("GET /api/schema/{table}", func(w http.ResponseWriter, r *http.Request) {
+		useJson := jsonAcceptable(r)
+		dbc, err := dbtestify.NewDBConnector(ctx, dbconn)
+		if err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf(`database connection error: %v`, err), http.StatusInternalServerError)
+			return
+		}
+		defer dbc.DB().Close()
+		table := r.PathValue("table")
+		columns, err := dbc.ColumnTypes(r.Context(), table)
+		if err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf("schema error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if len(columns) == 0 {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf("table '%s' is not found", table), http.StatusNotFound)
+			return
+		}
+		if useJson {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "text/plain")
+		}
+		dumpSchema(useJson, w, columns)
+	})
+
+	mux.HandleFunc("GET /api/snapshot/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		path := r.PathValue("path")
+		if ext := filepath.Ext(path); !filepath.IsLocal(path) || (ext != ".yaml" && ext != ".yml") {
+			http.Error(w, fmt.Sprintf("invalid snapshot path '%s': it should be YAML file in the data set folder", path), http.StatusBadRequest)
+			return
+		}
+		if config.dataDir == "" {
+			http.Error(w, "snapshot is not available: data set folder is not specified", http.StatusNotImplemented)
+			return
+		}
+		var tables []string
+		for _, t := range r.URL.Query()["tables"] {
+			tables = append(tables, strings.Split(t, ",")...)
+		}
+		dbc, err := dbtestify.NewDBConnector(ctx, dbconn)
+		if err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf(`database connection error: %v`, err), http.StatusInternalServerError)
+			return
+		}
+		defer dbc.DB().Close()
+		content, err := takeSnapshot(r.Context(), dbc, config.dataDir, path, tables)
+		if errors.Is(err, errUnknownTable) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf("snapshot error: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/yaml")
+		w.Write(content)
+	})
+
+	mux.HandleFunc("POST /api/seed/{path...}", func(w http.ResponseWriter, r *http.Request) {
 		useJson := jsonAcceptable(r)
 
 		opt, err := parseSeedRequest(r)
@@ -68,14 +357,36 @@ func Start(ctx context.Context, dir, dbconn string, port uint16) error {
 			http.Error(w, fmt.Sprintf(`database connection error: %v`, err), http.StatusInternalServerError)
 			return
 		}
-		err = seedTable(r.Context(), dbc, useJson, w, filepath.Join(dir, path), *opt)
+		defer dbc.DB().Close()
+		err = seedTable(r.Context(), dbc, m, useJson, w, dataFS, path, *opt)
 		if err != nil {
 			w.Header().Set("Content-Type", "text/plain")
 			http.Error(w, fmt.Sprintf("preparation error: %v", err), http.StatusInternalServerError)
 		}
 	})
 
-	m.HandleFunc("GET /api/assert/{path...}", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("GET /api/progress/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		opt, err := parseSeedQuery(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error parsing request: %v", err), http.StatusBadRequest)
+			return
+		}
+		path := r.PathValue("path")
+		dbc, err := dbtestify.NewDBConnector(ctx, dbconn)
+		if err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf(`database connection error: %v`, err), http.StatusInternalServerError)
+			return
+		}
+		defer dbc.DB().Close()
+		err = seedWithProgress(r.Context(), dbc, m, w, dataFS, path, *opt)
+		if err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf("preparation error: %v", err), http.StatusInternalServerError)
+		}
+	})
+
+	mux.HandleFunc("GET /api/assert/{path...}", func(w http.ResponseWriter, r *http.Request) {
 		useJson := jsonAcceptable(r)
 
 		opt := parseAssertRequest(r)
@@ -92,32 +403,49 @@ func Start(ctx context.Context, dir, dbconn string, port uint16) error {
 			http.Error(w, fmt.Sprintf(`database connection error: %v`, err), http.StatusInternalServerError)
 			return
 		}
-		_, err = assertTable(r.Context(), dbc, useJson, w, filepath.Join(dir, path), opt)
+		defer dbc.DB().Close()
+		_, err = assertTable(r.Context(), dbc, m, useJson, w, dataFS, path, opt)
 		if err != nil {
 			w.Header().Set("Content-Type", "text/plain")
 			http.Error(w, fmt.Sprintf("assert error: %v", err), http.StatusInternalServerError)
 		}
 	})
 
-	s := &http.Server{
-		Addr:    ":" + strconv.Itoa(int(port)),
-		Handler: m,
-	}
-	go func() {
-		<-ctx.Done()
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		s.Shutdown(ctx)
-	}()
-	fmt.Printf(`dbtestify API server
-	
-	GET  http://localhost:%[1]d/api/list                    : Show data set file list
-	POST http://localhost:%[1]d/api/seed/{data set path}    : Seed database content with the specified data set
-	GET  http://localhost:%[1]d/api/assert/{data set path}  : Assert database content with the specified data set
-	`, port)
+	mux.HandleFunc("POST /api/exec", func(w http.ResponseWriter, r *http.Request) {
+		if !config.allowExec {
+			http.Error(w, "exec is disabled. Start the server with --allow-exec to enable it", http.StatusForbidden)
+			return
+		}
+		useJson := jsonAcceptable(r)
 
-	fmt.Printf("start receiving at :%d\n", port)
-	return s.ListenAndServe()
+		req, err := parseExecRequest(r)
+		if errors.Is(err, errUnsupportedMediaType) {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		} else if err != nil {
+			http.Error(w, fmt.Sprintf("Error parsing request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if useJson {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "text/plain")
+		}
+		dbc, err := dbtestify.NewDBConnector(ctx, dbconn)
+		if err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf(`database connection error: %v`, err), http.StatusInternalServerError)
+			return
+		}
+		defer dbc.DB().Close()
+		err = execSQL(r.Context(), dbc, useJson, w, req.SQL)
+		if err != nil {
+			w.Header().Set("Content-Type", "text/plain")
+			http.Error(w, fmt.Sprintf("exec error: %v", err), http.StatusInternalServerError)
+		}
+	})
+
+	return mux, nil
 }
 
 func parseSeedRequest(r *http.Request) (*SeedOpt, error) {
@@ -145,19 +473,64 @@ func parseSeedRequest(r *http.Request) (*SeedOpt, error) {
 			if err != nil {
 				return nil, err
 			}
-		}
-		opt.IncludeTags = append(r.Form["i"], r.Form["include_tag"]...)
-		opt.ExcludeTags = append(r.Form["e"], r.Form["exclude_tag"]...)
-		opt.Targets = append(r.Form["t"], r.Form["target"]...)
-		opt.Truncates = r.Form["truncate"]
-		if batchSize := r.Form.Get("batch_size"); batchSize != "" {
-			batchSizeInt, err := strconv.Atoi(batchSize)
+			fixture, err := parseFixturePart(r)
 			if err != nil {
 				return nil, err
 			}
-			opt.BatchSize = batchSizeInt
+			opt.Fixture = fixture
+		}
+		if err := parseSeedForm(r.Form, &opt); err != nil {
+			return nil, err
 		}
 	}
+	normalizeSeedOpt(&opt)
+	return &opt, nil
+}
+
+// parseFixturePart reads the YAML content of `fixture` part of the parsed multipart form.
+//
+// It accepts both a file part (curl -F fixture=@seed.yaml) and a value part (curl -F "fixture=<seed.yaml").
+// It returns nil if the part doesn't exist.
+func parseFixturePart(r *http.Request) ([]byte, error) {
+	f, _, err := r.FormFile("fixture")
+	if errors.Is(err, http.ErrMissingFile) {
+		if v, ok := r.MultipartForm.Value["fixture"]; ok {
+			return []byte(v[0]), nil
+		}
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// parseSeedQuery reads the seed options from query parameters. The parameter names are the same as form.
+func parseSeedQuery(r *http.Request) (*SeedOpt, error) {
+	var opt SeedOpt
+	if err := parseSeedForm(r.URL.Query(), &opt); err != nil {
+		return nil, err
+	}
+	normalizeSeedOpt(&opt)
+	return &opt, nil
+}
+
+func parseSeedForm(form url.Values, opt *SeedOpt) error {
+	opt.IncludeTags = append(form["i"], form["include_tag"]...)
+	opt.ExcludeTags = append(form["e"], form["exclude_tag"]...)
+	opt.Targets = append(form["t"], form["target"]...)
+	opt.Truncates = form["truncate"]
+	if batchSize := form.Get("batch_size"); batchSize != "" {
+		batchSizeInt, err := strconv.Atoi(batchSize)
+		if err != nil {
+			return err
+		}
+		opt.BatchSize = batchSizeInt
+	}
+	return nil
+}
+
+func normalizeSeedOpt(opt *SeedOpt) {
 	slices.Sort(opt.IncludeTags)
 	slices.Sort(opt.ExcludeTags)
 	slices.Sort(opt.Targets)
@@ -165,7 +538,6 @@ func parseSeedRequest(r *http.Request) (*SeedOpt, error) {
 	if opt.BatchSize == 0 {
 		opt.BatchSize = 50
 	}
-	return &opt, nil
 }
 
 func parseAssertRequest(r *http.Request) AssertOpt {
@@ -188,6 +560,10 @@ func testDBConnection(ctx context.Context, dbconn string) error {
 	if err != nil {
 		return err
 	}
+	defer dbc.DB().Close()
+	if err := dbc.Ping(ctx); err != nil {
+		return err
+	}
 	_, err = dbc.TableNames(ctx)
 	return err
 }
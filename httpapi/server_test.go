@@ -2,14 +2,37 @@ package httpapi
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
 	"mime/multipart"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/alecthomas/assert/v2"
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/shibukawa/dbtestify"
 )
 
 func TestParseSeedRequest(t *testing.T) {
@@ -101,6 +124,25 @@ func TestParseSeedRequest(t *testing.T) {
 				BatchSize:   100,
 			},
 		},
+		{
+			name: "multipart with fixture",
+			createRequest: func() *http.Request {
+				var requestBody bytes.Buffer
+				writer := multipart.NewWriter(&requestBody)
+				writer.WriteField("target", "user")
+				part, _ := writer.CreateFormFile("fixture", "seed.yaml")
+				io.WriteString(part, "user:\n- { id: 1 }\n")
+				writer.Close()
+				req, _ := http.NewRequest("POST", "/api/seed/", &requestBody)
+				req.Header.Set("Content-Type", writer.FormDataContentType())
+				return req
+			},
+			expected: SeedOpt{
+				Targets:   []string{"user"},
+				BatchSize: 50,
+				Fixture:   []byte("user:\n- { id: 1 }\n"),
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -114,6 +156,77 @@ func TestParseSeedRequest(t *testing.T) {
 	}
 }
 
+func TestSeedFixtureUpload(t *testing.T) {
+	server, dir := newTestServerWithDir(t, `CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`)
+	// the file on disk is ignored when fixture part exists
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "user.yaml"), []byte("user:\n- { id: 9, name: Disk }\n"), 0o644))
+
+	post := func(path string, write func(writer *multipart.Writer)) (int, string) {
+		t.Helper()
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		write(writer)
+		writer.Close()
+		res, err := http.Post(server.URL+"/api/seed/"+path, writer.FormDataContentType(), &body)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		content, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		return res.StatusCode, string(content)
+	}
+	names := func() []string {
+		t.Helper()
+		dbc, err := dbtestify.NewDBConnector(t.Context(), "sqlite://file:"+filepath.Join(dir, "test.db"))
+		assert.NoError(t, err)
+		defer dbc.DB().Close()
+		rows, err := dbc.DB().QueryContext(t.Context(), "SELECT name FROM user ORDER BY id")
+		assert.NoError(t, err)
+		defer rows.Close()
+		var result []string
+		for rows.Next() {
+			var name string
+			assert.NoError(t, rows.Scan(&name))
+			result = append(result, name)
+		}
+		return result
+	}
+
+	t.Run("file part", func(t *testing.T) {
+		status, body := post("user.yaml", func(writer *multipart.Writer) {
+			part, err := writer.CreateFormFile("fixture", "generated.yaml")
+			assert.NoError(t, err)
+			io.WriteString(part, "user:\n- { id: 1, name: Alice }\n- { id: 2, name: Bob }\n")
+		})
+		assert.Equal(t, http.StatusOK, status, body)
+		assert.Equal(t, []string{"Alice", "Bob"}, names())
+	})
+
+	t.Run("value part without path", func(t *testing.T) {
+		status, body := post("", func(writer *multipart.Writer) {
+			writer.WriteField("fixture", "user:\n- { id: 3, name: Carol }\n")
+		})
+		assert.Equal(t, http.StatusOK, status, body)
+		assert.Equal(t, []string{"Carol"}, names())
+	})
+
+	t.Run("path based", func(t *testing.T) {
+		status, body := post("user.yaml", func(writer *multipart.Writer) {
+			writer.WriteField("batch_size", "10")
+		})
+		assert.Equal(t, http.StatusOK, status, body)
+		assert.Equal(t, []string{"Disk"}, names())
+	})
+
+	t.Run("invalid fixture", func(t *testing.T) {
+		status, _ := post("", func(writer *multipart.Writer) {
+			part, err := writer.CreateFormFile("fixture", "generated.yaml")
+			assert.NoError(t, err)
+			io.WriteString(part, "user: [")
+		})
+		assert.Equal(t, http.StatusInternalServerError, status)
+	})
+}
+
 func TestParseAssertRequest(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -173,3 +286,1132 @@ func TestParseAssertRequest(t *testing.T) {
 		})
 	}
 }
+
+// newTestServer starts the API server for the SQLite database with the given SQL.
+func newTestServer(t *testing.T, initSQL string) *httptest.Server {
+	t.Helper()
+	server, _ := newTestServerWithDir(t, initSQL)
+	return server
+}
+
+// newTestServerWithDir is the same as newTestServer, but also returns the data set folder.
+func newTestServerWithDir(t *testing.T, initSQL string) (*httptest.Server, string) {
+	t.Helper()
+	handler, dir := newTestHandler(t, initSQL)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server, dir
+}
+
+// newTestHandler creates the API handler without starting a server.
+func newTestHandler(t *testing.T, initSQL string, opts ...ServerOpt) (http.Handler, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	dbconn := "sqlite://file:" + filepath.Join(dir, "test.db")
+	dbc, err := dbtestify.NewDBConnector(t.Context(), dbconn)
+	assert.NoError(t, err)
+	_, err = dbc.DB().ExecContext(t.Context(), initSQL)
+	assert.NoError(t, err)
+	assert.NoError(t, dbc.DB().Close())
+
+	root, err := os.OpenRoot(dir)
+	assert.NoError(t, err)
+	t.Cleanup(func() { root.Close() })
+	config := serverConfig{dataDir: dir}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	handler, err := newHandler(t.Context(), root.FS(), dbconn, 8000, config)
+	assert.NoError(t, err)
+	return handler, dir
+}
+
+func TestTablesAPI(t *testing.T) {
+	server := newTestServer(t, `
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE item (id INTEGER PRIMARY KEY);
+		INSERT INTO user (id, name) VALUES (1, 'Frank'), (2, 'Grace');
+	`)
+
+	t.Run("json", func(t *testing.T) {
+		req, err := http.NewRequest("GET", server.URL+"/api/tables", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Accept", "application/json")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+		var result TablesResult
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+		assert.Equal(t, TablesResult{
+			Tables: []TableInfo{
+				{Name: "item", RowCount: 0},
+				{Name: "user", RowCount: 2},
+			},
+		}, result)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/tables")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		body, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "table  rows\nitem   0\nuser   2\n", string(body))
+	})
+}
+
+func TestSchemaAPI(t *testing.T) {
+	server := newTestServer(t, `
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name VARCHAR(100) NOT NULL, email TEXT);
+	`)
+
+	t.Run("json", func(t *testing.T) {
+		req, err := http.NewRequest("GET", server.URL+"/api/schema/user", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Accept", "application/json")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var result SchemaResult
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+		assert.Equal(t, SchemaResult{
+			Columns: []ColumnInfo{
+				{Name: "id", Type: "INTEGER", Nullable: false},
+				{Name: "name", Type: "VARCHAR(100)", Nullable: false, MaxLength: 100},
+				{Name: "email", Type: "TEXT", Nullable: true},
+			},
+		}, result)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/schema/user")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		body, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "column  type          nullable\nid      INTEGER       false\nname    VARCHAR(100)  false\nemail   TEXT          true\n", string(body))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/schema/missing")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
+
+func TestSnapshotAPI(t *testing.T) {
+	server, dir := newTestServerWithDir(t, `
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE item (id INTEGER PRIMARY KEY);
+		INSERT INTO user (id, name) VALUES (1, 'Frank'), (2, 'Grace');
+		INSERT INTO item (id) VALUES (10);
+	`)
+
+	t.Run("all tables", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/snapshot/snapshot/all.yaml")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/yaml", res.Header.Get("Content-Type"))
+		body, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "item:\n- { id: 10 }\nuser:\n- { id: 1, name: Frank }\n- { id: 2, name: Grace }\n", string(body))
+
+		content, err := os.ReadFile(filepath.Join(dir, "snapshot", "all.yaml"))
+		assert.NoError(t, err)
+		assert.Equal(t, string(body), string(content))
+		data, err := dbtestify.ParseYAML(bytes.NewReader(content))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(data.Tables))
+	})
+
+	t.Run("specified tables", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/snapshot/user.yaml?tables=user")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		content, err := os.ReadFile(filepath.Join(dir, "user.yaml"))
+		assert.NoError(t, err)
+		data, err := dbtestify.ParseYAML(bytes.NewReader(content))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(data.Tables))
+		assert.Equal(t, "user", data.Tables[0].Name)
+	})
+
+	t.Run("file mode", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/snapshot/new.yaml")
+		assert.NoError(t, err)
+		res.Body.Close()
+		info, err := os.Stat(filepath.Join(dir, "new.yaml"))
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+		// the mode of the existing file is kept
+		assert.NoError(t, os.Chmod(filepath.Join(dir, "new.yaml"), 0o664))
+		res, err = http.Get(server.URL + "/api/snapshot/new.yaml")
+		assert.NoError(t, err)
+		res.Body.Close()
+		info, err = os.Stat(filepath.Join(dir, "new.yaml"))
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o664), info.Mode().Perm())
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/snapshot/user.txt")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("unknown table", func(t *testing.T) {
+		for _, tables := range []string{"unknown", "user,unknown", `user" WHERE 1=0; DROP TABLE user; --`} {
+			res, err := http.Get(server.URL + "/api/snapshot/unknown.yaml?tables=" + url.QueryEscape(tables))
+			assert.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, "tables: %s", tables)
+		}
+		_, err := os.Stat(filepath.Join(dir, "unknown.yaml"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+
+	tests := []struct {
+		name       string
+		token      string
+		header     string
+		query      string
+		wantStatus int
+	}{
+		{
+			name:       "no token setting",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "bearer token",
+			token:      "secret",
+			header:     "Bearer secret",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "query param",
+			token:      "secret",
+			query:      "?token=secret",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no token",
+			token:      "secret",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong token",
+			token:      "secret",
+			header:     "Bearer wrong",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "not bearer",
+			token:      "secret",
+			header:     "Basic secret",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(AuthMiddleware(tt.token)(handler))
+			defer server.Close()
+			req, err := http.NewRequest("GET", server.URL+"/api/list"+tt.query, nil)
+			assert.NoError(t, err)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			res, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestBasicAuthMiddleware(t *testing.T) {
+	handler, dir := newTestHandler(t, `CREATE TABLE user (id INTEGER PRIMARY KEY);`)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "user.yaml"), []byte("user:\n- { id: 1 }\n"), 0o644))
+	server := httptest.NewServer(wrapHandler(handler, serverConfig{basicUser: "admin", basicPass: "secret"}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		user       string
+		password   string
+		wantStatus int
+	}{
+		{
+			name:       "correct credentials",
+			method:     "POST",
+			path:       "/api/seed/user.yaml",
+			user:       "admin",
+			password:   "secret",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong password",
+			method:     "POST",
+			path:       "/api/seed/user.yaml",
+			user:       "admin",
+			password:   "wrong",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong user",
+			method:     "GET",
+			path:       "/api/assert/user.yaml",
+			user:       "guest",
+			password:   "secret",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing header",
+			method:     "GET",
+			path:       "/api/assert/user.yaml",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing header of progress",
+			method:     "GET",
+			path:       "/api/progress/user.yaml",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "list is public",
+			method:     "GET",
+			path:       "/api/list",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "health is public",
+			method:     "GET",
+			path:       "/health",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			assert.NoError(t, err)
+			if tt.user != "" || tt.password != "" {
+				req.SetBasicAuth(tt.user, tt.password)
+			}
+			res, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			if tt.wantStatus == http.StatusUnauthorized {
+				assert.Contains(t, res.Header.Get("WWW-Authenticate"), "Basic")
+			}
+		})
+	}
+
+	t.Run("no setting", func(t *testing.T) {
+		server := httptest.NewServer(BasicAuthMiddleware("", "")(handler))
+		t.Cleanup(server.Close)
+		res, err := http.Post(server.URL+"/api/seed/user.yaml", "", nil)
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+
+	t.Run("burst of concurrent requests", func(t *testing.T) {
+		server := httptest.NewServer(RateLimitMiddleware(3)(handler))
+		t.Cleanup(server.Close)
+
+		var wg sync.WaitGroup
+		statuses := make(chan *http.Response, 10)
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := http.Post(server.URL+"/api/seed/user.yaml", "", nil)
+				assert.NoError(t, err)
+				res.Body.Close()
+				statuses <- res
+			}()
+		}
+		wg.Wait()
+		close(statuses)
+		counts := map[int]int{}
+		for res := range statuses {
+			counts[res.StatusCode]++
+			if res.StatusCode == http.StatusTooManyRequests {
+				assert.Equal(t, "1", res.Header.Get("Retry-After"))
+			}
+		}
+		assert.Equal(t, map[int]int{http.StatusOK: 3, http.StatusTooManyRequests: 7}, counts)
+	})
+
+	t.Run("bucket per IP", func(t *testing.T) {
+		limited := RateLimitMiddleware(1)(handler)
+		do := func(remoteAddr string) int {
+			req := httptest.NewRequest("POST", "/api/seed/user.yaml", nil)
+			req.RemoteAddr = remoteAddr
+			rec := httptest.NewRecorder()
+			limited.ServeHTTP(rec, req)
+			return rec.Code
+		}
+		assert.Equal(t, http.StatusOK, do("192.0.2.1:1000"))
+		// another port of the same host shares the bucket
+		assert.Equal(t, http.StatusTooManyRequests, do("192.0.2.1:1001"))
+		assert.Equal(t, http.StatusOK, do("192.0.2.2:1000"))
+	})
+
+	t.Run("only seed is limited", func(t *testing.T) {
+		server := httptest.NewServer(wrapHandler(handler, serverConfig{rateLimit: 1}))
+		t.Cleanup(server.Close)
+		for range 3 {
+			res, err := http.Get(server.URL + "/api/list")
+			assert.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+		}
+		var statuses []int
+		for range 2 {
+			res, err := http.Post(server.URL+"/api/seed/user.yaml", "", nil)
+			assert.NoError(t, err)
+			res.Body.Close()
+			statuses = append(statuses, res.StatusCode)
+		}
+		assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, statuses)
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		server := httptest.NewServer(RateLimitMiddleware(0)(handler))
+		t.Cleanup(server.Close)
+		for range 20 {
+			res, err := http.Post(server.URL+"/api/seed/user.yaml", "", nil)
+			assert.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+		}
+	})
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+
+	tests := []struct {
+		name           string
+		allowedOrigins []string
+		method         string
+		origin         string
+		wantStatus     int
+		wantOrigin     string
+		wantMethods    string
+	}{
+		{
+			name:        "preflight with wildcard",
+			method:      http.MethodOptions,
+			origin:      "http://localhost:3000",
+			wantStatus:  http.StatusNoContent,
+			wantOrigin:  "*",
+			wantMethods: "GET, POST",
+		},
+		{
+			name:       "actual request with wildcard",
+			method:     http.MethodGet,
+			origin:     "http://localhost:3000",
+			wantStatus: http.StatusOK,
+			wantOrigin: "*",
+		},
+		{
+			name:           "preflight from allowed origin",
+			allowedOrigins: []string{"http://localhost:3000"},
+			method:         http.MethodOptions,
+			origin:         "http://localhost:3000",
+			wantStatus:     http.StatusNoContent,
+			wantOrigin:     "http://localhost:3000",
+			wantMethods:    "GET, POST",
+		},
+		{
+			name:           "actual request from other origin",
+			allowedOrigins: []string{"http://localhost:3000"},
+			method:         http.MethodPost,
+			origin:         "http://example.com",
+			wantStatus:     http.StatusOK,
+			wantOrigin:     "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// preflight requests don't have token
+			server := httptest.NewServer(CORSMiddleware(tt.allowedOrigins)(AuthMiddleware("")(handler)))
+			defer server.Close()
+			req, err := http.NewRequest(tt.method, server.URL+"/api/seed/test.yaml", nil)
+			assert.NoError(t, err)
+			req.Header.Set("Origin", tt.origin)
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			res, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			assert.Equal(t, tt.wantOrigin, res.Header.Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.wantMethods, res.Header.Get("Access-Control-Allow-Methods"))
+			if tt.wantMethods != "" {
+				assert.Equal(t, "Content-Type, Authorization", res.Header.Get("Access-Control-Allow-Headers"))
+			}
+		})
+	}
+
+	t.Run("preflight is not blocked by token", func(t *testing.T) {
+		server := httptest.NewServer(CORSMiddleware(nil)(AuthMiddleware("secret")(handler)))
+		defer server.Close()
+		req, err := http.NewRequest(http.MethodOptions, server.URL+"/api/seed/test.yaml", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Origin", "http://localhost:3000")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	})
+}
+
+// writeSelfSignedCert writes a self-signed certificate for localhost and returns the file paths.
+func writeSelfSignedCert(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return certFile, keyFile, cert
+}
+
+func TestStartTLS(t *testing.T) {
+	dir := t.TempDir()
+	dbconn := "sqlite://file:" + filepath.Join(dir, "test.db")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "users.yaml"), []byte("user:\n- { id: 1 }\n"), 0o644))
+	certFile, keyFile, cert := writeSelfSignedCert(t, t.TempDir())
+
+	// find a free port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- StartTLS(ctx, os.DirFS(dir), dbconn, port, certFile, keyFile, WithDataDir(dir))
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		Timeout:   time.Second,
+	}
+	endpoint := fmt.Sprintf("https://localhost:%d/api/list", port)
+	var res *http.Response
+	for range 50 {
+		res, err = client.Get(endpoint)
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.True(t, res.TLS != nil && res.TLS.HandshakeComplete)
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "users.yaml")
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	t.Run("key file is required", func(t *testing.T) {
+		err := StartTLS(t.Context(), os.DirFS(dir), dbconn, port, certFile, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("exec requires token", func(t *testing.T) {
+		err := Start(t.Context(), os.DirFS(dir), dbconn, port, WithDataDir(dir), WithExec())
+		assert.Error(t, err)
+	})
+}
+
+// flushRecorder counts the number of flushes
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+func TestProgressAPI(t *testing.T) {
+	handler, dir := newTestHandler(t, `
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE item (id INTEGER PRIMARY KEY);
+		INSERT INTO item (id) VALUES (1);
+	`)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "user.yaml"), []byte("user:\n- { id: 1, name: Frank }\n"), 0o644))
+
+	t.Run("success", func(t *testing.T) {
+		rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		req := httptest.NewRequest("GET", "/api/progress/user.yaml?truncate=item", nil)
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+
+		var events []ProgressEvent
+		var lastEvent string
+		for _, block := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n") {
+			lines := strings.Split(block, "\n")
+			if strings.HasPrefix(lines[0], "event: ") {
+				lastEvent = strings.TrimPrefix(lines[0], "event: ")
+				continue
+			}
+			var e ProgressEvent
+			assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[0], "data: ")), &e))
+			e.DurationMS = 0
+			events = append(events, e)
+		}
+		// order of truncation is not fixed
+		assert.Equal(t, 6, len(events))
+		truncates := []string{events[0].Table, events[2].Table}
+		slices.Sort(truncates)
+		assert.Equal(t, []string{"item", "user"}, truncates)
+		assert.Equal(t, ProgressEvent{Table: events[0].Table, Task: "truncate", Start: true}, events[0])
+		assert.Equal(t, ProgressEvent{Table: events[0].Table, Task: "truncate"}, events[1])
+		assert.Equal(t, []ProgressEvent{
+			{Table: "user", Task: "insert", Start: true},
+			{Table: "user", Task: "insert"},
+		}, events[4:])
+		assert.Equal(t, "done", lastEvent)
+		assert.Equal(t, len(events)+1, rec.flushes)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("missing:\n- { id: 1 }\n"), 0o644))
+		rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		req := httptest.NewRequest("GET", "/api/progress/broken.yaml", nil)
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "event: error\n")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		req := httptest.NewRequest("GET", "/api/progress/missing.yaml", nil)
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, 0, rec.flushes)
+	})
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	server := newTestServer(t, `CREATE TABLE user (id INTEGER PRIMARY KEY);`)
+
+	res, err := http.Get(server.URL + "/api/openapi.json")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+
+	spec, err := openapi3.NewLoader().LoadFromData(body)
+	assert.NoError(t, err)
+	assert.NoError(t, spec.Validate(t.Context()))
+	for _, path := range []string{"/api/list", "/api/seed/{path}", "/api/assert/{path}", "/api/tables", "/api/schema/{table}", "/api/exec", "/health", "/ready"} {
+		assert.NotZero(t, spec.Paths.Find(path), "path %s is missing", path)
+	}
+	seed := spec.Paths.Find("/api/seed/{path}").Post
+	assert.NotZero(t, seed)
+	assert.NotZero(t, seed.RequestBody.Value.Content.Get("application/json").Schema.Value.Properties["batch_size"])
+	assert.Zero(t, seed.RequestBody.Value.Content.Get("application/json").Schema.Value.Properties["Fixture"])
+	assert.NotZero(t, seed.RequestBody.Value.Content.Get("multipart/form-data").Schema.Value.AllOf[1].Value.Properties["fixture"])
+	assert.NotZero(t, spec.Components.Schemas["AssertResponse"].Value.Properties["tables"])
+}
+
+func TestTargetTables(t *testing.T) {
+	server, dir := newTestServerWithDir(t, `
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE item (id INTEGER PRIMARY KEY);
+	`)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "user.yaml"), []byte("user:\n- { id: 1, name: Frank }\nitem:\n- { id: 1 }\n"), 0o644))
+
+	t.Run("seed", func(t *testing.T) {
+		res, err := http.PostForm(server.URL+"/api/seed/user.yaml", url.Values{"t": {"user"}})
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		res, err = http.PostForm(server.URL+"/api/seed/user.yaml", url.Values{"t": {"us*"}})
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		res, err = http.PostForm(server.URL+"/api/seed/user.yaml", url.Values{"t": {"users"}})
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Contains(t, string(body), "target table 'users' is not in the data set (tables: item, user)")
+	})
+
+	t.Run("assert", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/assert/user.yaml?target=user")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		res, err = http.Get(server.URL + "/api/assert/user.yaml?target=users")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Contains(t, string(body), "target table 'users' is not in the data set")
+	})
+}
+
+func TestAssertSummary(t *testing.T) {
+	server, dir := newTestServerWithDir(t, `
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		INSERT INTO user (id, name) VALUES (1, 'Frank'), (2, 'Grace');
+	`)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "user.yaml"), []byte("user:\n- { id: 1, name: Frank }\n- { id: 2, name: Heidi }\n"), 0o644))
+
+	req, err := http.NewRequest("GET", server.URL+"/api/assert/user.yaml", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Accept", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+	var result AssertResponse
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+	assert.False(t, result.Match)
+	assert.Equal(t, 1, result.DiffCount)
+	assert.Equal(t, "1 of 1 table does not match: user (1 row)", result.Summary)
+}
+
+func TestAssertStatus(t *testing.T) {
+	server, dir := newTestServerWithDir(t, `
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		INSERT INTO user (id, name) VALUES (1, 'Frank'), (2, 'Grace');
+	`)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "match.yaml"), []byte("user:\n- { id: 1, name: Frank }\n- { id: 2, name: Grace }\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "mismatch.yaml"), []byte("user:\n- { id: 1, name: Frank }\n- { id: 2, name: Heidi }\n"), 0o644))
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		status int
+		match  string
+	}{
+		{name: "match json", path: "match.yaml", accept: "application/json", status: http.StatusOK, match: "true"},
+		{name: "match text", path: "match.yaml", status: http.StatusOK, match: "true"},
+		{name: "mismatch json", path: "mismatch.yaml", accept: "application/json", status: http.StatusConflict, match: "false"},
+		{name: "mismatch text", path: "mismatch.yaml", status: http.StatusConflict, match: "false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", server.URL+"/api/assert/"+tt.path, nil)
+			assert.NoError(t, err)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			res, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, tt.status, res.StatusCode)
+			assert.Equal(t, tt.match, res.Header.Get("X-Dbtestify-Match"))
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/assert/missing.yaml")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Equal(t, "", res.Header.Get("X-Dbtestify-Match"))
+	})
+}
+
+func TestExecAPI(t *testing.T) {
+	initSQL := `
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		INSERT INTO user (id, name) VALUES (1, 'Frank'), (2, 'Grace');
+	`
+	exec := func(handler http.Handler, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/exec", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	countRows := func(t *testing.T, dir, query string) int {
+		t.Helper()
+		dbc, err := dbtestify.NewDBConnector(t.Context(), "sqlite://file:"+filepath.Join(dir, "test.db"))
+		assert.NoError(t, err)
+		defer dbc.DB().Close()
+		var count int
+		assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), query).Scan(&count))
+		return count
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		handler, _ := newTestHandler(t, initSQL)
+		rec := exec(handler, `{"sql": "DELETE FROM user"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "--allow-exec")
+	})
+
+	t.Run("dml", func(t *testing.T) {
+		handler, dir := newTestHandler(t, initSQL, WithExec())
+		rec := exec(handler, `{"sql": "UPDATE user SET name = 'Heidi'"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var result ExecResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+		assert.Equal(t, int64(2), result.RowsAffected)
+		assert.True(t, result.Duration > 0)
+		assert.Equal(t, 2, countRows(t, dir, "SELECT COUNT(*) FROM user WHERE name = 'Heidi'"))
+	})
+
+	t.Run("ddl", func(t *testing.T) {
+		handler, dir := newTestHandler(t, initSQL, WithExec())
+		rec := exec(handler, `{"sql": "CREATE INDEX user_name ON user (name)"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "allow_ddl")
+
+		rec = exec(handler, `{"sql": "create index user_name ON user (name)", "allow_ddl": true}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 1, countRows(t, dir, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'user_name'"))
+	})
+
+	t.Run("ddl hidden after comments or other statements", func(t *testing.T) {
+		handler, dir := newTestHandler(t, initSQL, WithExec())
+		for _, query := range []string{
+			`/* comment */ DROP TABLE user`,
+			"-- comment\n DROP TABLE user",
+			`SELECT 1; DROP TABLE user`,
+			`SELECT 1 /* ; */; DROP TABLE user; -- comment`,
+			`SELECT 'a\'; DROP TABLE user; --'`,
+		} {
+			body, _ := json.Marshal(ExecRequest{SQL: query})
+			rec := exec(handler, string(body))
+			assert.Equal(t, http.StatusBadRequest, rec.Code, "sql: %s", query)
+		}
+		assert.Equal(t, 1, countRows(t, dir, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'user'"))
+
+		for _, query := range []string{
+			`UPDATE user SET name = 'a;b' WHERE id = 1;`,
+			`UPDATE user SET name = 'it''s; -- not a comment' WHERE id = 2; -- comment`,
+		} {
+			body, _ := json.Marshal(ExecRequest{SQL: query})
+			rec := exec(handler, string(body))
+			assert.Equal(t, http.StatusOK, rec.Code, "sql: %s, body: %s", query, rec.Body.String())
+		}
+	})
+
+	t.Run("content type", func(t *testing.T) {
+		handler, _ := newTestHandler(t, initSQL, WithExec())
+		for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/exec", strings.NewReader(`{"sql": "DELETE FROM user"}`))
+			if contentType != "" {
+				req.Header.Set("Content-Type", contentType)
+			}
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code, "content-type: %s", contentType)
+		}
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		handler, dir := newTestHandler(t, initSQL, WithExec())
+		rec := exec(handler, `{"sql": "UPDATE user SET name = CASE id WHEN 1 THEN 'Heidi' ELSE NULL END"}`)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), "exec error")
+		assert.Equal(t, 0, countRows(t, dir, "SELECT COUNT(*) FROM user WHERE name = 'Heidi'"))
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		handler, _ := newTestHandler(t, initSQL, WithExec())
+		for _, body := range []string{``, `{"sql": " "}`, `{"query": "DELETE FROM user"}`} {
+			rec := exec(handler, body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, "body: %s", body)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		handler, _ := newTestHandler(t, initSQL, WithExec())
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/exec", strings.NewReader(`{"sql": "DELETE FROM user WHERE id = 1"}`))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, strings.HasPrefix(rec.Body.String(), "1 row(s) affected ("), rec.Body.String())
+	})
+}
+
+func TestWatchDataSets(t *testing.T) {
+	handler, dir := newTestHandler(t, `CREATE TABLE user (id INTEGER PRIMARY KEY);`, WithWatch())
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "user.yaml"), []byte("user:\n- { id: 1 }\n"), 0o644))
+
+	list := func() []string {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/list", nil)
+		req.Header.Set("Accept", "application/json")
+		handler.ServeHTTP(rec, req)
+		var result ListResult
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+		return result.DataSets
+	}
+	waitFor := func(expected []string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			actual := list()
+			if slices.Equal(expected, actual) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("data set list is %v, but expected %v", actual, expected)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor([]string{"sub/user.yaml"})
+
+	// the list is cached until the files are changed
+	assert.Equal(t, []string{"sub/user.yaml"}, list())
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "item.yaml"), []byte("item:\n- { id: 1 }\n"), 0o644))
+	waitFor([]string{"item.yaml", "sub/user.yaml"})
+
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "new"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "new", "tag.yml"), []byte("tag:\n- { id: 1 }\n"), 0o644))
+	waitFor([]string{"item.yaml", "new/tag.yml", "sub/user.yaml"})
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "item.yaml")))
+	waitFor([]string{"new/tag.yml", "sub/user.yaml"})
+}
+
+func TestDataFS(t *testing.T) {
+	dir := t.TempDir()
+	dbconn := "sqlite://file:" + filepath.Join(dir, "test.db")
+	dbc, err := dbtestify.NewDBConnector(t.Context(), dbconn)
+	assert.NoError(t, err)
+	_, err = dbc.DB().ExecContext(t.Context(), `CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`)
+	assert.NoError(t, err)
+	assert.NoError(t, dbc.DB().Close())
+
+	dataFS := fstest.MapFS{
+		"users/initial.yaml": {Data: []byte("user:\n- { id: 1, name: Frank }\n")},
+		"users/expect.yaml":  {Data: []byte("user:\n- { id: 1, name: Frank }\n")},
+		"README.md":          {Data: []byte("# fixtures\n")},
+	}
+	handler, err := newHandler(t.Context(), dataFS, dbconn, 8000, serverConfig{})
+	assert.NoError(t, err)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	t.Run("list", func(t *testing.T) {
+		req, err := http.NewRequest("GET", server.URL+"/api/list", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Accept", "application/json")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		var result ListResult
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+		assert.Equal(t, []string{"users/expect.yaml", "users/initial.yaml"}, result.DataSets)
+	})
+
+	t.Run("seed and assert", func(t *testing.T) {
+		res, err := http.Post(server.URL+"/api/seed/users/initial.yaml", "", nil)
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		res, err = http.Get(server.URL + "/api/assert/users/expect.yaml")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		req := httptest.NewRequest("GET", "/api/progress/users/initial.yaml", nil)
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "event: done")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/assert/users/missing.yaml")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+
+	t.Run("snapshot requires data set folder", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/snapshot/users/snapshot.yaml")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotImplemented, res.StatusCode)
+	})
+
+	t.Run("watch requires data set folder", func(t *testing.T) {
+		err := Start(t.Context(), dataFS, dbconn, 8000, WithWatch())
+		assert.Error(t, err)
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	server, dir := newTestServerWithDir(t, `CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "user.yaml"), []byte("user:\n- { id: 1, name: Frank }\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "mismatch.yaml"), []byte("user:\n- { id: 1, name: Grace }\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.yaml"), []byte("user:\n- { id: 2 }\n"), 0o644))
+
+	for _, path := range []string{"user.yaml", "user.yaml", "invalid.yaml"} {
+		res, err := http.Post(server.URL+"/api/seed/"+path, "", nil)
+		assert.NoError(t, err)
+		res.Body.Close()
+	}
+	for _, path := range []string{"user.yaml", "mismatch.yaml"} {
+		res, err := http.Get(server.URL + "/api/assert/" + path)
+		assert.NoError(t, err)
+		res.Body.Close()
+	}
+
+	res, err := http.Get(server.URL + "/metrics")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	for _, line := range []string{
+		`dbtestify_seed_duration_seconds_count{table="user",task="insert"} 3`,
+		`dbtestify_seed_duration_seconds_count{table="user",task="truncate"} 3`,
+		`dbtestify_seed_errors_total{table="user",task="insert"} 1`,
+		`dbtestify_assert_duration_seconds_count{table="user"} 2`,
+		`dbtestify_assert_mismatches_total{table="user"} 1`,
+		`go_goroutines `,
+	} {
+		assert.Contains(t, string(body), "\n"+line)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		handler, _ := newTestHandler(t, `CREATE TABLE user (id INTEGER PRIMARY KEY);`, WithoutMetrics())
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestHealthAPI(t *testing.T) {
+	handler, _ := newTestHandler(t, `CREATE TABLE user (id INTEGER PRIMARY KEY);`)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	get := func(path string) (int, HealthResponse) {
+		t.Helper()
+		res, err := http.Get(server.URL + path)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		var body HealthResponse
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		return res.StatusCode, body
+	}
+
+	status, body := get("/health")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, HealthResponse{Status: "ok"}, body)
+
+	status, body = get("/ready")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, HealthResponse{Status: "ok"}, body)
+
+	t.Run("database outage", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequestWithContext(ctx, "GET", "/ready", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		var body HealthResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		assert.Equal(t, "unavailable", body.Status)
+		assert.Contains(t, body.Error, "context canceled")
+
+		// liveness doesn't depend on the database
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequestWithContext(ctx, "GET", "/health", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("probes don't require token", func(t *testing.T) {
+		server := httptest.NewServer(wrapHandler(handler, serverConfig{token: "secret"}))
+		t.Cleanup(server.Close)
+		for path, status := range map[string]int{"/health": http.StatusOK, "/ready": http.StatusOK, "/api/list": http.StatusUnauthorized} {
+			res, err := http.Get(server.URL + path)
+			assert.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, status, res.StatusCode, path)
+		}
+	})
+}
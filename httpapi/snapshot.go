@@ -0,0 +1,97 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/shibukawa/dbtestify"
+)
+
+var errUnknownTable = errors.New("unknown table")
+
+// validateTables checks that all tables exist in the database, because the names are embedded in SQL.
+//
+// "schema.table" notation is checked against the tables of the schema.
+func validateTables(ctx context.Context, dbc dbtestify.DBConnector, tables []string) error {
+	known := map[string][]string{}
+	for _, table := range tables {
+		var schema []string
+		name := table
+		if s, t, found := strings.Cut(table, "."); found {
+			schema, name = []string{s}, t
+		}
+		key := strings.Join(schema, "")
+		names, ok := known[key]
+		if !ok {
+			var err error
+			names, err = dbc.TableNames(ctx, schema...)
+			if err != nil {
+				return err
+			}
+			known[key] = names
+		}
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("%w: '%s'", errUnknownTable, table)
+		}
+	}
+	return nil
+}
+
+// takeSnapshot writes the current rows of the tables to the path under dir and returns the YAML content.
+//
+// If tables is empty, all tables are captured. The file is replaced atomically.
+func takeSnapshot(ctx context.Context, dbc dbtestify.DBConnector, dir, path string, tables []string) ([]byte, error) {
+	if len(tables) == 0 {
+		var err error
+		tables, err = dbc.TableNames(ctx)
+		if err != nil {
+			return nil, err
+		}
+	} else if err := validateTables(ctx, dbc, tables); err != nil {
+		return nil, err
+	}
+	data, err := dbtestify.Snapshot(ctx, dbc, tables)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := data.WriteYAML(&buf); err != nil {
+		return nil, err
+	}
+
+	fullPath := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(filepath.Dir(fullPath), ".snapshot-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	// CreateTemp makes the file readable only by the owner. Keep the mode of the overwritten file instead
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(fullPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(f.Name(), fullPath); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
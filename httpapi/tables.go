@@ -0,0 +1,97 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/shibukawa/dbtestify"
+)
+
+type TableInfo struct {
+	Name     string `json:"name"`
+	RowCount int    `json:"row_count"`
+}
+
+type TablesResult struct {
+	Tables []TableInfo `json:"tables"`
+}
+
+func getTableList(ctx context.Context, dbc dbtestify.DBConnector, schema ...string) ([]TableInfo, error) {
+	names, err := dbc.TableNames(ctx, schema...)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]TableInfo, 0, len(names))
+	for _, name := range names {
+		table := name
+		if len(schema) > 0 {
+			table = schema[0] + "." + name
+		}
+		var count int
+		err := dbc.DB().QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count rows of table %s: %w", table, err)
+		}
+		result = append(result, TableInfo{Name: name, RowCount: count})
+	}
+	return result, nil
+}
+
+func dumpTableList(useJson bool, w io.Writer, tables []TableInfo) {
+	if useJson {
+		result := TablesResult{
+			Tables: tables,
+		}
+		e := json.NewEncoder(w)
+		e.Encode(&result)
+	} else {
+		width := len("table")
+		for _, t := range tables {
+			width = max(width, len(t.Name))
+		}
+		fmt.Fprintf(w, "%-*s  %s\n", width, "table", "rows")
+		for _, t := range tables {
+			fmt.Fprintf(w, "%-*s  %d\n", width, t.Name, t.RowCount)
+		}
+	}
+}
+
+type ColumnInfo struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Nullable  bool   `json:"nullable"`
+	MaxLength int    `json:"max_length,omitzero"`
+}
+
+type SchemaResult struct {
+	Columns []ColumnInfo `json:"columns"`
+}
+
+func dumpSchema(useJson bool, w io.Writer, columns []dbtestify.ColumnTypeMeta) {
+	if useJson {
+		var result SchemaResult
+		for _, c := range columns {
+			result.Columns = append(result.Columns, ColumnInfo{
+				Name:      c.Name,
+				Type:      c.DBType,
+				Nullable:  c.Nullable,
+				MaxLength: c.MaxLength,
+			})
+		}
+		e := json.NewEncoder(w)
+		e.Encode(&result)
+	} else {
+		nameWidth := len("column")
+		typeWidth := len("type")
+		for _, c := range columns {
+			nameWidth = max(nameWidth, len(c.Name))
+			typeWidth = max(typeWidth, len(c.DBType))
+		}
+		fmt.Fprintf(w, "%-*s  %-*s  %s\n", nameWidth, "column", typeWidth, "type", "nullable")
+		for _, c := range columns {
+			fmt.Fprintf(w, "%-*s  %-*s  %v\n", nameWidth, c.Name, typeWidth, c.DBType, c.Nullable)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// dataSetList returns the data set files in the folder.
+//
+// Without watch, the folder is read for each call. With watch, the list is cached until the files are changed.
+type dataSetList struct {
+	root   fs.FS
+	cached bool
+
+	lock     sync.Mutex
+	dataSets []string
+	valid    bool
+}
+
+func newDataSetList(root fs.FS) *dataSetList {
+	return &dataSetList{root: root}
+}
+
+func (l *dataSetList) get() []string {
+	if !l.cached {
+		return getTestList(l.root)
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !l.valid {
+		l.dataSets = getTestList(l.root)
+		l.valid = true
+	}
+	return l.dataSets
+}
+
+func (l *dataSetList) invalidate() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.valid = false
+}
+
+// watch monitors the folder and its sub folders, and invalidates the cached list when files are changed.
+//
+// It stops when ctx is canceled.
+func (l *dataSetList) watch(ctx context.Context, dir string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := addWatchDirs(watcher, dir); err != nil {
+		watcher.Close()
+		return err
+	}
+	l.cached = true
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if e.Has(fsnotify.Create) {
+					// sub folders are not watched recursively
+					if s, err := os.Stat(e.Name); err == nil && s.IsDir() {
+						addWatchDirs(watcher, e.Name)
+					}
+				}
+				if e.Has(fsnotify.Create) || e.Has(fsnotify.Remove) || e.Has(fsnotify.Rename) {
+					l.invalidate()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Fprintf(os.Stderr, "watch error: %v\n", err)
+				// events may be lost
+				l.invalidate()
+			}
+		}
+	}()
+	return nil
+}
+
+func addWatchDirs(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,60 @@
+package dbtestify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// evalJSONPath extracts the value at path from the decoded JSON document.
+//
+// Only the simple form like `$.key.subkey[0]` is supported. found is false if the path doesn't exist in the document,
+// and the error is returned if the path itself is invalid.
+func evalJSONPath(doc any, path string) (value any, found bool, err error) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return nil, false, fmt.Errorf("JSONPath should start with '$': %s", path)
+	}
+	current := doc
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" {
+				return nil, false, fmt.Errorf("empty key in JSONPath: %s", path)
+			}
+			rest = rest[end:]
+			obj, ok := current.(map[string]any)
+			if !ok {
+				return nil, false, nil
+			}
+			current, ok = obj[key]
+			if !ok {
+				return nil, false, nil
+			}
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, false, fmt.Errorf("']' is missing in JSONPath: %s", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, false, fmt.Errorf("invalid index '%s' in JSONPath: %s", rest[1:end], path)
+			}
+			rest = rest[end+1:]
+			arr, ok := current.([]any)
+			if !ok || index >= len(arr) {
+				return nil, false, nil
+			}
+			current = arr[index]
+		default:
+			return nil, false, fmt.Errorf("unexpected character '%c' in JSONPath: %s", rest[0], path)
+		}
+	}
+	return current, true, nil
+}
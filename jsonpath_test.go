@@ -0,0 +1,47 @@
+package dbtestify
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func Test_evalJSONPath(t *testing.T) {
+	var doc any
+	assert.NoError(t, json.Unmarshal([]byte(`{"user":{"id":42,"tags":["a",{"name":"b"}]},"list":[[1,2]]}`), &doc))
+
+	tests := []struct {
+		path      string
+		want      any
+		wantFound bool
+		wantErr   bool
+	}{
+		{path: "$", want: doc, wantFound: true},
+		{path: "$.user.id", want: 42.0, wantFound: true},
+		{path: "$.user.tags[0]", want: "a", wantFound: true},
+		{path: "$.user.tags[1].name", want: "b", wantFound: true},
+		{path: "$.list[0][1]", want: 2.0, wantFound: true},
+		{path: "$.user.missing"},
+		{path: "$.user.tags[2]"},
+		{path: "$.user.id.sub"},
+		{path: "$.user[0]"},
+		{path: "user.id", wantErr: true},
+		{path: "$..id", wantErr: true},
+		{path: "$.user.tags[x]", wantErr: true},
+		{path: "$.user.tags[0", wantErr: true},
+		{path: "$user", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, found, err := evalJSONPath(doc, tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package dbtestify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// SchemaColumn represents an expected column definition in schema file.
+//
+// Fields that are not specified are not checked.
+type SchemaColumn struct {
+	Type      string `yaml:"type"` // Compared with ColumnTypeMeta.DBType case-insensitively.
+	Nullable  *bool  `yaml:"nullable"`
+	MaxLength *int   `yaml:"max_length"`
+}
+
+// Schema represents expected columns of tables. The key of the outer map is a table name and the inner one is a column name.
+type Schema map[string]map[string]SchemaColumn
+
+// ParseSchemaYAML reads a YAML formatted schema from the provided reader.
+//
+//	user:
+//	  id: { type: integer, nullable: false }
+//	  name: { type: varchar, nullable: false, max_length: 100 }
+func ParseSchemaYAML(r io.Reader) (Schema, error) {
+	result := Schema{}
+	d := yaml.NewDecoder(r)
+	if err := d.Decode(&result); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SchemaDiff represents a difference between the expected schema and the actual database.
+type SchemaDiff struct {
+	Table  string
+	Column string
+	Field  string // "column" (missing column), "type", "nullable" or "max_length"
+	Expect any
+	Actual any
+}
+
+func (d SchemaDiff) String() string {
+	if d.Field == "column" {
+		return fmt.Sprintf("%s.%s: column is not found", d.Table, d.Column)
+	}
+	return fmt.Sprintf("%s.%s: %s is '%v', but expected '%v'", d.Table, d.Column, d.Field, d.Actual, d.Expect)
+}
+
+// AssertSchema compares the column types of the database with the expected schema.
+//
+// Columns that exist only in the database are ignored. It returns an empty slice if the schema matches.
+func AssertSchema(ctx context.Context, dbc DBConnector, expected Schema) ([]SchemaDiff, error) {
+	var result []SchemaDiff
+	for _, table := range slices.Sorted(maps.Keys(expected)) {
+		columns, err := dbc.ColumnTypes(ctx, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read column types of table %s: %w", table, err)
+		}
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("table %s is not found", table)
+		}
+		for _, name := range slices.Sorted(maps.Keys(expected[table])) {
+			e := expected[table][name]
+			i := slices.IndexFunc(columns, func(c ColumnTypeMeta) bool {
+				return strings.EqualFold(c.Name, name)
+			})
+			if i == -1 {
+				result = append(result, SchemaDiff{Table: table, Column: name, Field: "column"})
+				continue
+			}
+			a := columns[i]
+			if e.Type != "" && !strings.EqualFold(e.Type, a.DBType) {
+				result = append(result, SchemaDiff{Table: table, Column: name, Field: "type", Expect: e.Type, Actual: a.DBType})
+			}
+			if e.Nullable != nil && *e.Nullable != a.Nullable {
+				result = append(result, SchemaDiff{Table: table, Column: name, Field: "nullable", Expect: *e.Nullable, Actual: a.Nullable})
+			}
+			if e.MaxLength != nil && *e.MaxLength != a.MaxLength {
+				result = append(result, SchemaDiff{Table: table, Column: name, Field: "max_length", Expect: *e.MaxLength, Actual: a.MaxLength})
+			}
+		}
+	}
+	return result, nil
+}
+
+// DataSetProblem represents a mismatch between the data set and the table definition found by ValidateDataSet.
+type DataSetProblem struct {
+	Table  string
+	Column string // Empty if the table is not found.
+	Kind   string // "table" (missing table), "unknown" (column not in the table) or "required" (non-nullable column without default is missing)
+	Row    int    // The first row that has the unknown column or misses the required column.
+}
+
+func (p DataSetProblem) String() string {
+	switch p.Kind {
+	case "table":
+		return fmt.Sprintf("%s: table is not found", p.Table)
+	case "unknown":
+		return fmt.Sprintf("%s.%s: column is not found in the table (row %d)", p.Table, p.Column, p.Row)
+	default:
+		return fmt.Sprintf("%s.%s: non-nullable column without default is missing (row %d)", p.Table, p.Column, p.Row)
+	}
+}
+
+// ValidateDataSet checks the data set against the columns read by DBConnector.ColumnTypes.
+//
+// Every column in the rows should exist in the table, and rows for seeding should have all non-nullable columns that don't have defaults.
+// Required columns are not checked for assert-only rows and the tables whose operation is delete or truncate.
+// Problems are ordered by the table name. It returns an empty slice if there is no problem.
+func ValidateDataSet(ctx context.Context, dbc DBConnector, data *DataSet) ([]DataSetProblem, error) {
+	var result []DataSetProblem
+	tables := slices.SortedFunc(slices.Values(data.Tables), func(a, b *Table) int { return strings.Compare(a.Name, b.Name) })
+	for _, t := range tables {
+		columns, err := dbc.ColumnTypes(ctx, qualifyTable(data.DefaultSchema, t.Name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read column types of table %s: %w", t.Name, err)
+		}
+		if len(columns) == 0 {
+			result = append(result, DataSetProblem{Table: t.Name, Kind: "table"})
+			continue
+		}
+		known := map[string]bool{}
+		for _, c := range columns {
+			known[strings.ToLower(c.Name)] = true
+		}
+		reported := map[string]bool{}
+		for i, row := range t.Rows {
+			for _, name := range slices.Sorted(maps.Keys(row)) {
+				if !known[strings.ToLower(name)] && !reported[name] {
+					reported[name] = true
+					result = append(result, DataSetProblem{Table: t.Name, Column: name, Kind: "unknown", Row: i})
+				}
+			}
+		}
+		if op := data.Operation[t.Name]; op == DeleteOperation || op == TruncateOperation {
+			continue
+		}
+		for _, c := range columns {
+			if c.Nullable || c.HasDefault {
+				continue
+			}
+			for i, row := range t.Rows {
+				if t.isAssertOnly(i) {
+					continue
+				}
+				if !slices.ContainsFunc(slices.Collect(maps.Keys(row)), func(name string) bool { return strings.EqualFold(name, c.Name) }) {
+					result = append(result, DataSetProblem{Table: t.Name, Column: c.Name, Kind: "required", Row: i})
+					break
+				}
+			}
+		}
+	}
+	return result, nil
+}
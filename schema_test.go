@@ -0,0 +1,147 @@
+package dbtestify
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestColumnTypesSQLite(t *testing.T) {
+	os.Remove("column_types.db")
+	connStr := "file:column_types.db?cache=shared&mode=rwc"
+
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE user (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(100) NOT NULL,
+			email TEXT
+		);
+	`))
+	assert.NoError(t, err)
+
+	columns, err := dbc.ColumnTypes(t.Context(), "user")
+	assert.NoError(t, err)
+	assert.Equal(t, []ColumnTypeMeta{
+		{Name: "id", DBType: "INTEGER", Nullable: false, HasDefault: true},
+		{Name: "name", DBType: "VARCHAR(100)", Nullable: false, MaxLength: 100},
+		{Name: "email", DBType: "TEXT", Nullable: true},
+	}, columns)
+
+	t.Run("match", func(t *testing.T) {
+		schema, err := ParseSchemaYAML(strings.NewReader(TrimIndent(t, `
+			user:
+			  id: { type: integer, nullable: false }
+			  name: { nullable: false, max_length: 100 }
+			  email: { type: text }
+			`)))
+		assert.NoError(t, err)
+		diffs, err := AssertSchema(t.Context(), dbc, schema)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(diffs))
+	})
+
+	t.Run("not match", func(t *testing.T) {
+		schema, err := ParseSchemaYAML(strings.NewReader(TrimIndent(t, `
+			user:
+			  email: { type: text, nullable: false }
+			  name: { max_length: 200 }
+			  nickname: { type: text }
+			`)))
+		assert.NoError(t, err)
+		diffs, err := AssertSchema(t.Context(), dbc, schema)
+		assert.NoError(t, err)
+		assert.Equal(t, []SchemaDiff{
+			{Table: "user", Column: "email", Field: "nullable", Expect: false, Actual: true},
+			{Table: "user", Column: "name", Field: "max_length", Expect: 200, Actual: 100},
+			{Table: "user", Column: "nickname", Field: "column"},
+		}, diffs)
+		assert.Equal(t, "user.email: nullable is 'true', but expected 'false'", diffs[0].String())
+	})
+
+	t.Run("missing table", func(t *testing.T) {
+		_, err := AssertSchema(t.Context(), dbc, Schema{"group": {"id": {}}})
+		assert.Error(t, err)
+	})
+}
+
+func TestValidateDataSetSQLite(t *testing.T) {
+	os.Remove("validate_data_set.db")
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://file:validate_data_set.db?cache=shared&mode=rwc")
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE user (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'active',
+			email TEXT
+		);
+		CREATE TABLE item (code TEXT PRIMARY KEY, price INTEGER NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	columns, err := dbc.ColumnTypes(t.Context(), "user")
+	assert.NoError(t, err)
+	assert.True(t, columns[2].HasDefault)
+	assert.False(t, columns[1].HasDefault)
+
+	validate := func(src string) []string {
+		t.Helper()
+		data, err := ParseYAML(strings.NewReader(TrimIndent(t, src)))
+		assert.NoError(t, err)
+		problems, err := ValidateDataSet(t.Context(), dbc, data)
+		assert.NoError(t, err)
+		var result []string
+		for _, p := range problems {
+			result = append(result, p.String())
+		}
+		return result
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		// id is rowid, status has default and email is nullable
+		assert.Equal(t, 0, len(validate(`
+			user:
+			- { name: Frank }
+			- { id: 2, name: Grace, status: inactive, email: grace@example.com }
+			item:
+			- { code: A, price: 100 }
+			`)))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"item.price: non-nullable column without default is missing (row 0)",
+			"orders: table is not found",
+			"user.mail: column is not found in the table (row 1)",
+			"user.name: non-nullable column without default is missing (row 1)",
+		}, validate(`
+			user:
+			- { id: 1, name: Frank }
+			- { id: 2, mail: grace@example.com }
+			- { id: 3, mail: heidi@example.com }
+			item:
+			- { code: A }
+			orders:
+			- { id: 1 }
+			`))
+	})
+
+	t.Run("rows not for seeding", func(t *testing.T) {
+		assert.Equal(t, 0, len(validate(`
+			_operation:
+			  item: delete
+			user:
+			- { id: 1, _assert_only: true }
+			item:
+			- { code: A }
+			`)))
+	})
+}
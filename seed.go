@@ -3,43 +3,234 @@ package dbtestify
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"maps"
 	"slices"
+	"strings"
 )
 
+// ErrCyclicDependency is returned when the `_depends_on` directive of the dataset has a cycle.
+var ErrCyclicDependency = errors.New("cyclic dependency")
+
+// ErrSeedFailed is returned when a database operation fails during seeding.
+//
+// RowIndex is the index of the failed row in the dataset when the batch has only one row, otherwise -1.
+// BatchStart is the index of the first row of the failed batch. Both are -1 for truncate.
+type ErrSeedFailed struct {
+	TableName  string
+	RowIndex   int
+	BatchStart int
+	Cause      error
+}
+
+func (e ErrSeedFailed) Error() string {
+	switch {
+	case e.BatchStart < 0:
+		return fmt.Sprintf("failed to truncate table '%s': %v", e.TableName, e.Cause)
+	case e.RowIndex >= 0:
+		return fmt.Sprintf("failed to seed table '%s' at row %d: %v", e.TableName, e.RowIndex, e.Cause)
+	default:
+		return fmt.Sprintf("failed to seed table '%s' at batch starting from row %d: %v", e.TableName, e.BatchStart, e.Cause)
+	}
+}
+
+func (e ErrSeedFailed) Unwrap() error {
+	return e.Cause
+}
+
+// ErrPartialCommit is returned by Seed with SeedOpt.Parallel when a transaction failed to commit after other groups of tables were committed.
+//
+// The tables in Committed keep the seeded data, and other tables are rolled back.
+type ErrPartialCommit struct {
+	Committed []string
+	Cause     error
+}
+
+func (e ErrPartialCommit) Error() string {
+	return fmt.Sprintf("failed to commit after tables [%s] were committed: %v", strings.Join(e.Committed, ", "), e.Cause)
+}
+
+func (e ErrPartialCommit) Unwrap() error {
+	return e.Cause
+}
+
+// seedFailed wraps the error of the batch that starts from batchStart and has batchLen rows.
+func seedFailed(tableName string, batchStart, batchLen int, err error) error {
+	rowIndex := -1
+	if batchLen == 1 {
+		rowIndex = batchStart
+	}
+	return ErrSeedFailed{TableName: tableName, RowIndex: rowIndex, BatchStart: batchStart, Cause: err}
+}
+
 // DefaultBatchSize is the default number of rows to process in a single batch during seeding.
 var DefaultBatchSize = 50
 
 // SeedOpt defines options for the seeding process.
 type SeedOpt struct {
-	BatchSize    int                                                   // default: 50
-	Operations   map[string]Operation                                  // Operations to apply to each table. If empty, defaults to ClearInsertOperation.
-	IncludeTags  []string                                              // Tags to filter rows of dataset.
-	ExcludeTags  []string                                              // Tags to filter rows of dataset.
-	TargetTables []string                                              // Only specified tables will be processed.
-	Callback     func(targetTable, task string, start bool, err error) // Callback function to report progress and errors during the seeding process.
+	BatchSize          int                                                   // default: 50
+	TableBatchSize     map[string]int                                        // BatchSize per table. Zero or missing entry falls back to BatchSize.
+	Operations         map[string]Operation                                  // Operations to apply to each table. If empty, defaults to ClearInsertOperation.
+	IncludeTags        []string                                              // Tags to filter rows of dataset.
+	ExcludeTags        []string                                              // Tags to filter rows of dataset.
+	TargetTables       []string                                              // Only specified tables will be processed. Glob patterns like `audit_*` are allowed.
+	DisableForeignKeys bool                                                  // Suspend foreign key checks during seeding if DBConnector implements ForeignKeyController.
+	Parallel           bool                                                  // Seed tables that don't have `_depends_on` relationships concurrently. Each table group uses its own transaction. Ignored for SQLite and SeedWithTx, and falls back to one transaction if the connection pool is smaller than the groups.
+	Workers            int                                                   // Number of concurrent workers for Parallel. default: runtime.NumCPU()
+	Callback           func(targetTable, task string, start bool, err error) // Callback function to report progress and errors during the seeding process.
+	BeforeTableHook    TableHook                                             // Called for each table of the dataset before truncating tables.
+	AfterTableHook     TableHook                                             // Called for each table of the dataset after its operation, before the transaction is committed.
+	ViewAliases        map[string]string                                     // Tables in the database used instead of the tables (usually views) in the dataset, like {"active_users": "users"}.
+	Savepoints         bool                                                  // Run each table's operation in a savepoint and continue with other tables on failure. All failures are returned joined by errors.Join.
+
+	defaultSchema string // DataSet.DefaultSchema set by SeedWithTx
+}
+
+// TableHook is called during seeding with the active transaction to issue additional SQL like resetting sequences.
+//
+// If it returns an error, seeding stops and the transaction is rolled back. With SeedOpt.Parallel, hooks can be called from multiple goroutines.
+type TableHook func(ctx context.Context, dbc DBConnector, tx *sql.Tx, tableName string) error
+
+// batchSize returns the batch size for the table.
+func (o SeedOpt) batchSize(tableName string) int {
+	if n := o.TableBatchSize[tableName]; n > 0 {
+		return n
+	}
+	return o.BatchSize
+}
+
+// targetTable returns the table in the database that DML for the table of the dataset is issued against.
+//
+// Callbacks, hooks and errors still use the name in the dataset.
+func (o SeedOpt) targetTable(tableName string) string {
+	if alias, ok := o.ViewAliases[tableName]; ok {
+		tableName = alias
+	}
+	return qualifyTable(o.defaultSchema, tableName)
+}
+
+// qualifyTable prepends the schema to the table name if the name doesn't have a schema.
+func qualifyTable(schema, tableName string) string {
+	if schema == "" || strings.Contains(tableName, ".") {
+		return tableName
+	}
+	return schema + "." + tableName
 }
 
 // Seed initializes the database with the provided dataset, applying the specified operations.
+//
+// If the dataset doesn't have `_depends_on`, tables are seeded in the order of the foreign keys read by DBConnector.ForeignKeys.
+// If opt.Parallel is true, Callback can be called from multiple goroutines.
 func Seed(ctx context.Context, dbc DBConnector, data *DataSet, opt SeedOpt) error {
-	if opt.BatchSize == 0 {
-		opt.BatchSize = DefaultBatchSize
+	if len(data.DependsOn) == 0 {
+		// read before beginning the transaction not to wait for another connection during it
+		dependsOn, err := foreignKeyDependencies(ctx, dbc, data, opt)
+		if err != nil {
+			return err
+		}
+		if len(dependsOn) > 0 {
+			d := *data
+			d.DependsOn = dependsOn
+			data = &d
+		}
+	}
+	if _, ok := dbc.(*sqliteDBConnector); opt.Parallel && !ok {
+		// SQLite allows only one writer at a time
+		return seedParallel(ctx, dbc, data, opt)
 	}
+	return seedSequential(ctx, dbc, data, opt)
+}
+
+// seedSequential seeds all tables in one transaction.
+func seedSequential(ctx context.Context, dbc DBConnector, data *DataSet, opt SeedOpt) error {
 	tx, err := dbc.DB().BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
+	failures, err := seedWithTx(ctx, dbc, tx, data, opt)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return errors.Join(failures...)
+}
+
+// SeedWithTx is the same as Seed, but it uses the provided transaction instead of beginning a new one.
+//
+// It doesn't commit nor rollback the transaction. The caller should do it.
+func SeedWithTx(ctx context.Context, dbc DBConnector, tx *sql.Tx, data *DataSet, opt SeedOpt) error {
+	failures, err := seedWithTx(ctx, dbc, tx, data, opt)
+	if err != nil {
+		return err
+	}
+	return errors.Join(failures...)
+}
+
+// SeedBatch inserts limit rows from offset of the table in the provided transaction, so that a large table can be seeded in chunks.
+//
+// Rows are processed in the order of the dataset. Rows with `_seed_only` are inserted and rows with `_assert_only` are skipped as Seed.
+// The operation of the table in opt is used, but the table is not truncated. Truncate it before the first chunk if needed.
+// If limit is 0, it inserts all rows from offset. The row indexes of ErrSeedFailed are the ones in the whole table.
+func SeedBatch(ctx context.Context, dbc DBConnector, tx *sql.Tx, t *Table, offset, limit int, opt SeedOpt) error {
+	if offset < 0 || limit < 0 {
+		return fmt.Errorf("table '%s': offset and limit should not be negative: offset=%d, limit=%d", t.Name, offset, limit)
+	}
+	if opt.BatchSize == 0 {
+		opt.BatchSize = DefaultBatchSize
+	}
+	start := min(offset, len(t.Rows))
+	end := len(t.Rows)
+	if limit > 0 {
+		end = min(start+limit, end)
+	}
+	switch op := opt.Operations[t.Name]; op {
+	case ClearInsertOperation, "":
+		return insertRange(ctx, dbc, tx, t, start, end, opt, InsertOperation)
+	case InsertOperation, UpsertOperation, InsertIgnoreOperation:
+		return insertRange(ctx, dbc, tx, t, start, end, opt, op)
+	default:
+		return fmt.Errorf("table '%s': SeedBatch doesn't support '%s' operation", t.Name, op)
+	}
+}
+
+// seedWithTx is the body of SeedWithTx. failures are the errors of the tables rolled back to their savepoints with opt.Savepoints,
+// and the transaction is still available to commit the other tables.
+func seedWithTx(ctx context.Context, dbc DBConnector, tx *sql.Tx, data *DataSet, opt SeedOpt) (failures []error, err error) {
+	if opt.BatchSize == 0 {
+		opt.BatchSize = DefaultBatchSize
+	}
+	opt.defaultSchema = data.DefaultSchema
+	tables, err := sortTablesByDependency(data.Tables, data.DependsOn)
+	if err != nil {
+		return nil, err
+	}
+	var fkc ForeignKeyController
+	if opt.DisableForeignKeys {
+		if c, ok := dbc.(ForeignKeyController); ok {
+			if err := c.DisableForeignKeys(ctx, tx); err != nil {
+				return nil, err
+			}
+			fkc = c
+			// restore on error not to return the connection to the pool without foreign key checks
+			defer func() {
+				if fkc != nil {
+					fkc.EnableForeignKeys(context.WithoutCancel(ctx), tx)
+				}
+			}()
+		}
+	}
 	// truncate first
 	ops := map[string]Operation{}
 	for t, op := range opt.Operations {
 		ops[t] = op
 	}
-	for _, t := range data.Tables {
-		if len(opt.TargetTables) > 0 {
-			if !slices.Contains(opt.TargetTables, t.Name) {
-				continue
-			}
+	for _, t := range tables {
+		if !MatchTargetTables(opt.TargetTables, t.Name) {
+			continue
 		}
 		switch opt.Operations[t.Name] {
 		case ClearInsertOperation:
@@ -50,143 +241,440 @@ func Seed(ctx context.Context, dbc DBConnector, data *DataSet, opt SeedOpt) erro
 			ops[t.Name] = TruncateOperation
 		}
 	}
-	for t, op := range ops {
-		if op == TruncateOperation {
-			if opt.Callback != nil {
-				opt.Callback(t, "truncate", true, nil)
+	if opt.BeforeTableHook != nil {
+		for _, t := range tables {
+			if !MatchTargetTables(opt.TargetTables, t.Name) {
+				continue
 			}
-			err := dbc.Truncate(ctx, tx, t)
-			if opt.Callback != nil {
-				opt.Callback(t, "truncate", false, err)
-			}
-			if err != nil {
-				return err
+			if err := opt.BeforeTableHook(ctx, dbc, tx, t.Name); err != nil {
+				return nil, fmt.Errorf("before table hook of %s failed: %w", t.Name, err)
 			}
 		}
 	}
-	for _, t := range data.Tables {
-		if len(opt.TargetTables) > 0 {
-			if !slices.Contains(opt.TargetTables, t.Name) {
-				continue
-			}
+	// errors of the tables rolled back to their savepoints, used only with opt.Savepoints
+	var errs []error
+	failed := map[string]bool{}
+	inSavepoint := func(tableName string, fn func() error) error {
+		if !opt.Savepoints {
+			return fn()
 		}
-		switch opt.Operations[t.Name] {
-		case ClearInsertOperation:
-			fallthrough
-		case "":
-			fallthrough
-		case InsertOperation:
-			if opt.Callback != nil {
-				opt.Callback(t.Name, "insert", true, nil)
+		name := savepointName(tableName)
+		if _, err := tx.ExecContext(ctx, savepointSQL(dbc, "SAVEPOINT", name)); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			if _, rerr := tx.ExecContext(ctx, savepointSQL(dbc, "ROLLBACK TO SAVEPOINT", name)); rerr != nil {
+				return errors.Join(err, rerr)
 			}
-			err := processInsertOperation(ctx, dbc, tx, t, opt, false)
-			if opt.Callback != nil {
-				opt.Callback(t.Name, "insert", false, nil)
+			var e ErrSeedFailed
+			if !errors.As(err, &e) {
+				err = ErrSeedFailed{TableName: tableName, RowIndex: -1, BatchStart: 0, Cause: err}
 			}
-			if err != nil {
+			errs = append(errs, err)
+			failed[tableName] = true
+			return nil
+		}
+		if stmt := savepointSQL(dbc, "RELEASE SAVEPOINT", name); stmt != "" {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
 				return err
 			}
-		case UpsertOperation:
+		}
+		return nil
+	}
+	// children are truncated before their parents
+	var truncates []string
+	for _, t := range reverseDependencyOrder(tables, ops) {
+		if ops[t] == TruncateOperation {
+			truncates = append(truncates, t)
+		}
+	}
+	if mt, ok := dbc.(multiTableTruncator); ok && !opt.Savepoints && len(truncates) > 1 {
+		if err := truncateTogether(ctx, mt, tx, truncates, opt); err != nil {
+			return nil, err
+		}
+		truncates = nil
+	}
+	for _, t := range truncates {
+		err := inSavepoint(t, func() error {
 			if opt.Callback != nil {
-				opt.Callback(t.Name, "upsert", true, nil)
+				opt.Callback(t, "truncate", true, nil)
 			}
-			err := processInsertOperation(ctx, dbc, tx, t, opt, true)
+			err := dbc.Truncate(ctx, tx, opt.targetTable(t))
 			if opt.Callback != nil {
-				opt.Callback(t.Name, "upsert", false, nil)
+				opt.Callback(t, "truncate", false, err)
 			}
 			if err != nil {
-				return err
+				return ErrSeedFailed{TableName: t, RowIndex: -1, BatchStart: -1, Cause: err}
 			}
-		case DeleteOperation:
-			if opt.Callback != nil {
-				opt.Callback(t.Name, "delete", true, nil)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	seed := func(t *Table) error {
+		if !MatchTargetTables(opt.TargetTables, t.Name) || failed[t.Name] {
+			return nil
+		}
+		err := inSavepoint(t.Name, func() error {
+			return seedTable(ctx, dbc, tx, t, opt)
+		})
+		if err != nil {
+			return err
+		}
+		if opt.AfterTableHook != nil && !failed[t.Name] {
+			if err := opt.AfterTableHook(ctx, dbc, tx, t.Name); err != nil {
+				return fmt.Errorf("after table hook of %s failed: %w", t.Name, err)
 			}
-			err := processDeleteOperation(ctx, dbc, tx, t, opt)
-			if opt.Callback != nil {
-				opt.Callback(t.Name, "delete", false, err)
+		}
+		return nil
+	}
+	// rows of children are deleted before their parents, and then the rest are inserted in the dependency order
+	for i := len(tables) - 1; i >= 0; i-- {
+		if opt.Operations[tables[i].Name] == DeleteOperation {
+			if err := seed(tables[i]); err != nil {
+				return nil, err
 			}
-			if err != nil {
-				return err
+		}
+	}
+	for _, t := range tables {
+		if opt.Operations[t.Name] != DeleteOperation {
+			if err := seed(t); err != nil {
+				return nil, err
 			}
 		}
 	}
-	tx.Commit()
+	if fkc != nil {
+		if err := fkc.EnableForeignKeys(ctx, tx); err != nil {
+			return nil, err
+		}
+		fkc = nil
+	}
+	return errs, nil
+}
+
+// seedTable applies the operation of opt to the table except truncation.
+func seedTable(ctx context.Context, dbc DBConnector, tx *sql.Tx, t *Table, opt SeedOpt) error {
+	switch opt.Operations[t.Name] {
+	case ClearInsertOperation:
+		fallthrough
+	case "":
+		fallthrough
+	case InsertOperation:
+		if opt.Callback != nil {
+			opt.Callback(t.Name, "insert", true, nil)
+		}
+		err := processInsertOperation(ctx, dbc, tx, t, opt, InsertOperation)
+		if opt.Callback != nil {
+			opt.Callback(t.Name, "insert", false, err)
+		}
+		if err != nil {
+			return err
+		}
+	case UpsertOperation:
+		if opt.Callback != nil {
+			opt.Callback(t.Name, "upsert", true, nil)
+		}
+		err := processInsertOperation(ctx, dbc, tx, t, opt, UpsertOperation)
+		if opt.Callback != nil {
+			opt.Callback(t.Name, "upsert", false, err)
+		}
+		if err != nil {
+			return err
+		}
+	case InsertIgnoreOperation:
+		if opt.Callback != nil {
+			opt.Callback(t.Name, "insert-ignore", true, nil)
+		}
+		err := processInsertOperation(ctx, dbc, tx, t, opt, InsertIgnoreOperation)
+		if opt.Callback != nil {
+			opt.Callback(t.Name, "insert-ignore", false, err)
+		}
+		if err != nil {
+			return err
+		}
+	case DeleteOperation:
+		if opt.Callback != nil {
+			opt.Callback(t.Name, "delete", true, nil)
+		}
+		err := processDeleteOperation(ctx, dbc, tx, t, opt)
+		if opt.Callback != nil {
+			opt.Callback(t.Name, "delete", false, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func processInsertOperation(ctx context.Context, dbc DBConnector, tx *sql.Tx, t *Table, opt SeedOpt, upsert bool) error {
+// TruncateTables truncates the tables in one transaction. Tables referenced by foreign keys are truncated after the referencing tables.
+//
+// Foreign keys are disabled only for the databases that can't truncate the related tables at once,
+// because PostgreSQL needs the superuser privilege to disable them.
+func TruncateTables(ctx context.Context, dbc DBConnector, tables []string) error {
+	data := &DataSet{}
+	opt := SeedOpt{Operations: map[string]Operation{}}
+	for _, name := range tables {
+		data.Tables = append(data.Tables, &Table{Name: name})
+		opt.Operations[name] = TruncateOperation
+	}
+	if _, ok := dbc.(multiTableTruncator); !ok {
+		opt.DisableForeignKeys = true
+	}
+	return Seed(ctx, dbc, data, opt)
+}
+
+// multiTableTruncator is implemented by DBConnectors that truncate multiple tables in one statement.
+//
+// PostgreSQL rejects truncating a table referenced by the foreign keys of other tables, even if they are empty,
+// unless they are truncated in the same statement.
+type multiTableTruncator interface {
+	truncateTables(ctx context.Context, tx *sql.Tx, tableNames []string) error
+}
+
+// truncateTogether truncates the tables in one statement. The callback is called for each table.
+func truncateTogether(ctx context.Context, mt multiTableTruncator, tx *sql.Tx, tables []string, opt SeedOpt) error {
+	targets := make([]string, len(tables))
+	for i, t := range tables {
+		targets[i] = opt.targetTable(t)
+		if opt.Callback != nil {
+			opt.Callback(t, "truncate", true, nil)
+		}
+	}
+	err := mt.truncateTables(ctx, tx, targets)
+	if opt.Callback != nil {
+		for _, t := range tables {
+			opt.Callback(t, "truncate", false, err)
+		}
+	}
+	if err != nil {
+		return ErrSeedFailed{TableName: strings.Join(tables, ", "), RowIndex: -1, BatchStart: -1, Cause: err}
+	}
+	return nil
+}
+
+// savepointName returns the savepoint name for the table. Characters other than letters, digits and '_' are replaced with '_'.
+func savepointName(tableName string) string {
+	return "sp_" + strings.Map(func(r rune) rune {
+		if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, tableName)
+}
+
+// savepointSQL returns the statement of the savepoint command for the database. It returns "" if the database doesn't have the command.
+func savepointSQL(dbc DBConnector, command, name string) string {
+	if _, ok := dbc.(*mssqlDBConnector); ok {
+		// SQL Server uses SAVE TRANSACTION and doesn't have RELEASE SAVEPOINT
+		switch command {
+		case "SAVEPOINT":
+			return "SAVE TRANSACTION " + name
+		case "ROLLBACK TO SAVEPOINT":
+			return "ROLLBACK TRANSACTION " + name
+		default:
+			return ""
+		}
+	}
+	return command + " " + name
+}
+
+// reverseDependencyOrder returns the table names of ops so that every table comes before the tables it depends on.
+//
+// tables should be sorted by sortTablesByDependency. The tables that are not in tables come first in alphabetical order.
+func reverseDependencyOrder(tables []*Table, ops map[string]Operation) []string {
+	var result []string
+	for i := len(tables) - 1; i >= 0; i-- {
+		if _, ok := ops[tables[i].Name]; ok && !slices.Contains(result, tables[i].Name) {
+			result = append(result, tables[i].Name)
+		}
+	}
+	var others []string
+	for t := range ops {
+		if !slices.Contains(result, t) {
+			others = append(others, t)
+		}
+	}
+	slices.Sort(others)
+	return append(others, result...)
+}
+
+// sortTablesByDependency returns tables ordered so that every table comes after the tables it depends on.
+//
+// The original order is kept as much as possible. Dependencies to the tables that are not in the dataset are ignored.
+func sortTablesByDependency(tables []*Table, dependsOn map[string][]string) ([]*Table, error) {
+	if len(dependsOn) == 0 {
+		return tables, nil
+	}
+	byName := map[string][]*Table{}
+	var names []string
+	for _, t := range tables {
+		if _, ok := byName[t.Name]; !ok {
+			names = append(names, t.Name)
+		}
+		byName[t.Name] = append(byName[t.Name], t)
+	}
+	sorted, err := TopologicalSort(names, func(t string) []string { return dependsOn[t] })
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Table, 0, len(tables))
+	for _, name := range sorted {
+		result = append(result, byName[name]...)
+	}
+	return result, nil
+}
+
+// foreignKeyDependencies builds `_depends_on` from the foreign keys between the target tables of the dataset.
+//
+// Self references are ignored. It returns nil if the foreign keys have a cycle, so that such tables are seeded in the dataset order.
+func foreignKeyDependencies(ctx context.Context, dbc DBConnector, data *DataSet, opt SeedOpt) (map[string][]string, error) {
+	opt.defaultSchema = data.DefaultSchema
+	byTarget := map[string]string{}
+	var names []string
+	for _, t := range data.Tables {
+		target := opt.targetTable(t.Name)
+		if _, ok := byTarget[target]; ok || !MatchTargetTables(opt.TargetTables, t.Name) {
+			continue
+		}
+		byTarget[target] = t.Name
+		names = append(names, t.Name)
+	}
+	if len(names) < 2 {
+		return nil, nil
+	}
+	dependsOn := map[string][]string{}
+	for _, name := range names {
+		target := opt.targetTable(name)
+		fks, err := dbc.ForeignKeys(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		for _, fk := range fks {
+			ref := fk.RefTable
+			if schema, _, ok := strings.Cut(target, "."); ok && !strings.Contains(ref, ".") {
+				ref = schema + "." + ref
+			}
+			if dep, ok := byTarget[ref]; ok && dep != name && !slices.Contains(dependsOn[name], dep) {
+				dependsOn[name] = append(dependsOn[name], dep)
+			}
+		}
+	}
+	if _, err := TopologicalSort(names, func(t string) []string { return dependsOn[t] }); err != nil {
+		return nil, nil
+	}
+	return dependsOn, nil
+}
+
+func processInsertOperation(ctx context.Context, dbc DBConnector, tx *sql.Tx, t *Table, opt SeedOpt, op Operation) error {
+	return insertRange(ctx, dbc, tx, t, 0, len(t.Rows), opt, op)
+}
+
+// insertRange inserts the rows from start to end of the table every batch size.
+func insertRange(ctx context.Context, dbc DBConnector, tx *sql.Tx, t *Table, start, end int, opt SeedOpt, op Operation) error {
 	var pKeys []string
-	if upsert {
+	if op == UpsertOperation {
 		var err error
-		pKeys, err = dbc.PrimaryKeys(ctx, t.Name)
+		pKeys, err = dbc.PrimaryKeys(ctx, opt.targetTable(t.Name))
 		if err != nil {
 			return err
 		}
 
 	}
 
-	for i := 0; i < len(t.Rows); i += opt.BatchSize {
-		end := i + opt.BatchSize
-		if end > len(t.Rows) {
-			end = len(t.Rows)
+	batchSize := opt.batchSize(t.Name)
+	for i := start; i < end; i += batchSize {
+		if err := insertBatch(ctx, dbc, tx, t, i, min(i+batchSize, end), opt, op, pKeys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertBatch inserts the rows from start to end of the table in one statement.
+func insertBatch(ctx context.Context, dbc DBConnector, tx *sql.Tx, t *Table, start, end int, opt SeedOpt, op Operation, pKeys []string) error {
+	batch := t.Rows[start:end]
+	columnMaps := map[string]bool{}
+	for j, r := range batch {
+		if t.isAssertOnly(start + j) {
+			continue
+		}
+		for k := range maps.Keys(r) {
+			columnMaps[k] = true
 		}
-		batch := t.Rows[i:end]
-		columnMaps := map[string]bool{}
-		for _, r := range batch {
-			for k := range maps.Keys(r) {
-				columnMaps[k] = true
-			}
-		}
-		columns := slices.Sorted(maps.Keys(columnMaps))
-		values := make([]any, 0, len(batch)*len(columns))
-		for j, r := range batch {
-			if filter(t.Tags[i+j], opt.IncludeTags, opt.ExcludeTags) {
-				for _, c := range columns {
-					if val, ok := r[c]; ok {
-						values = append(values, val)
-					} else {
-						values = append(values, nil)
-					}
+	}
+	columns := slices.Sorted(maps.Keys(columnMaps))
+	values := make([]any, 0, len(batch)*len(columns))
+	for j, r := range batch {
+		if !t.isAssertOnly(start+j) && filter(t.Tags[start+j], opt.IncludeTags, opt.ExcludeTags) {
+			for _, c := range columns {
+				if val, ok := r[c]; ok {
+					values = append(values, val)
+				} else {
+					values = append(values, nil)
 				}
 			}
 		}
-		if upsert {
-			if err := dbc.Upsert(ctx, tx, t.Name, columns, pKeys, values); err != nil {
-				return err
-			}
-		} else if err := dbc.Insert(ctx, tx, t.Name, columns, values); err != nil {
-			return err
+	}
+	if len(values) == 0 { // all rows in the batch are filtered out
+		return nil
+	}
+	switch op {
+	case UpsertOperation:
+		if err := dbc.Upsert(ctx, tx, opt.targetTable(t.Name), columns, pKeys, values); err != nil {
+			return seedFailed(t.Name, start, len(batch), err)
+		}
+	case InsertIgnoreOperation:
+		if err := dbc.InsertIgnore(ctx, tx, opt.targetTable(t.Name), columns, values); err != nil {
+			return seedFailed(t.Name, start, len(batch), err)
+		}
+	default:
+		if err := dbc.Insert(ctx, tx, opt.targetTable(t.Name), columns, values); err != nil {
+			return seedFailed(t.Name, start, len(batch), err)
 		}
 	}
 	return nil
 }
 
 func processDeleteOperation(ctx context.Context, dbc DBConnector, tx *sql.Tx, t *Table, opt SeedOpt) error {
-	columns, err := dbc.PrimaryKeys(ctx, t.Name)
+	columns, err := dbc.PrimaryKeys(ctx, opt.targetTable(t.Name))
 	if err != nil {
 		return err
 	}
-	for i := 0; i < len(t.Rows); i += opt.BatchSize {
-		end := i + opt.BatchSize
+	batchSize := opt.batchSize(t.Name)
+	for i := 0; i < len(t.Rows); i += batchSize {
+		end := i + batchSize
 		if end > len(t.Rows) {
 			end = len(t.Rows)
 		}
-		batch := t.Rows[i:end]
-		values := make([]any, 0, len(batch)*len(columns))
-		for j, r := range batch {
-			if filter(t.Tags[i+j], opt.IncludeTags, opt.ExcludeTags) {
-				for _, c := range columns {
-					if val, ok := r[c]; ok {
-						values = append(values, val)
-					} else {
-						values = append(values, nil)
-					}
+		if err := deleteBatch(ctx, dbc, tx, t, i, end, opt, columns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteBatch deletes the rows from start to end of the table by the primary key columns in one statement.
+func deleteBatch(ctx context.Context, dbc DBConnector, tx *sql.Tx, t *Table, start, end int, opt SeedOpt, columns []string) error {
+	batch := t.Rows[start:end]
+	values := make([]any, 0, len(batch)*len(columns))
+	for j, r := range batch {
+		if !t.isAssertOnly(start+j) && filter(t.Tags[start+j], opt.IncludeTags, opt.ExcludeTags) {
+			for _, c := range columns {
+				if val, ok := r[c]; ok {
+					values = append(values, val)
+				} else {
+					values = append(values, nil)
 				}
 			}
 		}
-		if err := dbc.Delete(ctx, tx, t.Name, columns, values); err != nil {
-			return err
-		}
+	}
+	if len(values) == 0 { // all rows in the batch are filtered out
+		return nil
+	}
+	if err := dbc.Delete(ctx, tx, opt.targetTable(t.Name), columns, values); err != nil {
+		return seedFailed(t.Name, start, len(batch), err)
 	}
 	return nil
 }
@@ -0,0 +1,200 @@
+package dbtestify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// seedParallel seeds independent table groups concurrently. Each group has its own transaction.
+//
+// Tables connected by `_depends_on` are in the same group and processed in dependency order.
+// Transactions are committed after all groups succeed, otherwise all of them are rolled back.
+// They are committed one by one, so ErrPartialCommit is returned if a commit fails after others succeeded.
+//
+// All transactions are kept open until the end, so it falls back to seedSequential if the connection pool
+// doesn't have enough connections for the groups and metadata queries like DBConnector.PrimaryKeys.
+func seedParallel(ctx context.Context, dbc DBConnector, data *DataSet, opt SeedOpt) error {
+	tables, err := sortTablesByDependency(data.Tables, data.DependsOn)
+	if err != nil {
+		return err
+	}
+	groups := groupTablesByDependency(tables, data.DependsOn)
+
+	// tables that are only in opt.Operations (e.g. truncate) are processed as an extra group
+	var rest []string
+	for name := range opt.Operations {
+		if !slices.ContainsFunc(tables, func(t *Table) bool { return t.Name == name }) {
+			rest = append(rest, name)
+		}
+	}
+	if len(rest) > 0 {
+		groups = append(groups, nil)
+	}
+	if maxOpen := dbc.DB().Stats().MaxOpenConnections; maxOpen > 0 && len(groups)+parallelConnHeadroom > maxOpen {
+		return seedSequential(ctx, dbc, data, opt)
+	}
+
+	workers := opt.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	txs := make([]*sql.Tx, len(groups))
+	// the first error is reported. Other workers are canceled by it
+	var firstErr error
+	var once sync.Once
+	// errors of the tables rolled back to their savepoints with opt.Savepoints
+	var failures []error
+	var mu sync.Mutex
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	defer func() {
+		for _, tx := range txs {
+			if tx != nil {
+				tx.Rollback()
+			}
+		}
+	}()
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(groups)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				tx, err := dbc.DB().BeginTx(ctx, nil)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				txs[i] = tx
+				groupOpt := opt
+				groupOpt.Operations = map[string]Operation{}
+				names := rest
+				if groups[i] != nil {
+					names = nil
+					for _, t := range groups[i] {
+						names = append(names, t.Name)
+					}
+				}
+				for _, name := range names {
+					if op, ok := opt.Operations[name]; ok {
+						groupOpt.Operations[name] = op
+					}
+				}
+				groupData := &DataSet{
+					Tables:        groups[i],
+					DependsOn:     data.DependsOn,
+					DefaultSchema: data.DefaultSchema,
+				}
+				groupFailures, err := seedWithTx(ctx, dbc, tx, groupData, groupOpt)
+				if err != nil {
+					fail(err)
+				}
+				mu.Lock()
+				failures = append(failures, groupFailures...)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range groups {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	groupNames := make([][]string, len(groups))
+	for i, g := range groups {
+		if g == nil {
+			groupNames[i] = rest
+		}
+		for _, t := range g {
+			groupNames[i] = append(groupNames[i], t.Name)
+		}
+	}
+	err = commitGroups(groupNames, func(i int) error {
+		err := txs[i].Commit()
+		txs[i] = nil
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Join(failures...)
+}
+
+// parallelConnHeadroom is the number of connections seedParallel leaves for the queries outside of the transactions.
+const parallelConnHeadroom = 1
+
+// commitGroups commits the transactions of the groups in order. It stops at the first error, and the error is
+// ErrPartialCommit if some groups are already committed.
+func commitGroups(groupNames [][]string, commit func(i int) error) error {
+	var committed []string
+	for i := range groupNames {
+		if err := commit(i); err != nil {
+			if len(committed) > 0 {
+				return ErrPartialCommit{Committed: committed, Cause: err}
+			}
+			return err
+		}
+		committed = append(committed, groupNames[i]...)
+	}
+	return nil
+}
+
+// groupTablesByDependency splits tables into groups that don't have `_depends_on` relationships with each other.
+//
+// The order of tables in each group is kept.
+func groupTablesByDependency(tables []*Table, dependsOn map[string][]string) [][]*Table {
+	parent := map[string]string{}
+	var find func(name string) string
+	find = func(name string) string {
+		p, ok := parent[name]
+		if !ok || p == name {
+			parent[name] = name
+			return name
+		}
+		root := find(p)
+		parent[name] = root
+		return root
+	}
+	for name, deps := range dependsOn {
+		for _, dep := range deps {
+			parent[find(dep)] = find(name)
+		}
+	}
+	var result [][]*Table
+	index := map[string]int{}
+	for _, t := range tables {
+		root := find(t.Name)
+		i, ok := index[root]
+		if !ok {
+			i = len(result)
+			index[root] = i
+			result = append(result, nil)
+		}
+		result[i] = append(result[i], t)
+	}
+	return result
+}
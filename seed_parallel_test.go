@@ -0,0 +1,357 @@
+package dbtestify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func Test_groupTablesByDependency(t *testing.T) {
+	tables := []*Table{{Name: "customers"}, {Name: "tags"}, {Name: "orders"}, {Name: "products"}, {Name: "order_items"}, {Name: "logs"}}
+	names := func(groups [][]*Table) [][]string {
+		var result [][]string
+		for _, g := range groups {
+			var n []string
+			for _, t := range g {
+				n = append(n, t.Name)
+			}
+			result = append(result, n)
+		}
+		return result
+	}
+	t.Run("no dependency", func(t *testing.T) {
+		groups := groupTablesByDependency(tables, nil)
+		assert.Equal(t, [][]string{{"customers"}, {"tags"}, {"orders"}, {"products"}, {"order_items"}, {"logs"}}, names(groups))
+	})
+	t.Run("connected tables are in the same group", func(t *testing.T) {
+		groups := groupTablesByDependency(tables, map[string][]string{
+			"orders":      {"customers"},
+			"order_items": {"orders", "products"},
+		})
+		assert.Equal(t, [][]string{{"customers", "orders", "products", "order_items"}, {"tags"}, {"logs"}}, names(groups))
+	})
+	t.Run("dependency to the table not in dataset", func(t *testing.T) {
+		groups := groupTablesByDependency(tables, map[string][]string{
+			"tags": {"users"},
+			"logs": {"users"},
+		})
+		assert.Equal(t, [][]string{{"customers"}, {"tags", "logs"}, {"orders"}, {"products"}, {"order_items"}}, names(groups))
+	})
+}
+
+func TestSeedParallelSQLite(t *testing.T) {
+	os.Remove("seed_parallel.db")
+	connStr := "file:seed_parallel.db?cache=shared&mode=rwc"
+
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE products (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		customers:
+		- { id: 1, name: Frank }
+		products:
+		- { id: 1, name: Apple }
+		- { id: 2, name: Banana }
+		`)))
+	assert.NoError(t, err)
+
+	// SQLite falls back to sequential seeding
+	err = Seed(t.Context(), dbc, data, SeedOpt{Parallel: true})
+	assert.NoError(t, err)
+
+	var count int
+	assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM products").Scan(&count))
+	assert.Equal(t, 2, count)
+}
+
+func startSeedParallelPostgreSQL(tb testing.TB) string {
+	ctx := context.Background()
+	pgContainer, err := postgres.Run(ctx, "postgres:15.3-alpine",
+		postgres.WithDatabase("seedtest"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).WithStartupTimeout(5*time.Second)))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		if err := pgContainer.Terminate(ctx); err != nil {
+			tb.Fatalf("failed to terminate pgContainer: %s", err)
+		}
+	})
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return connStr
+}
+
+func TestSeedParallelPostgreSQL(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	connStr := startSeedParallelPostgreSQL(t)
+
+	dbc, err := NewDBConnector(t.Context(), connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers(id));
+		CREATE TABLE products (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	count := func(table string) int {
+		var count int
+		assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM "+table).Scan(&count))
+		return count
+	}
+
+	t.Run("success", func(t *testing.T) {
+		data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+			_depends_on:
+			  orders: [customers]
+			orders:
+			- { id: 1, customer_id: 1 }
+			customers:
+			- { id: 1, name: Frank }
+			products:
+			- { id: 1, name: Apple }
+			- { id: 2, name: Banana }
+			tags:
+			- { id: 1, name: new }
+			`)))
+		assert.NoError(t, err)
+
+		err = Seed(t.Context(), dbc, data, SeedOpt{
+			Parallel: true,
+			Workers:  2,
+			Operations: map[string]Operation{
+				"customers": InsertOperation,
+				"orders":    InsertOperation,
+				"products":  InsertOperation,
+				"tags":      InsertOperation,
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count("customers"))
+		assert.Equal(t, 1, count("orders"))
+		assert.Equal(t, 2, count("products"))
+		assert.Equal(t, 1, count("tags"))
+	})
+
+	t.Run("rollback all on error", func(t *testing.T) {
+		data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+			products:
+			- { id: 3, name: Cherry }
+			tags:
+			- { id: 1, name: duplicated }
+			`)))
+		assert.NoError(t, err)
+
+		err = Seed(t.Context(), dbc, data, SeedOpt{
+			Parallel: true,
+			Operations: map[string]Operation{
+				"products": InsertOperation,
+				"tags":     InsertOperation,
+			},
+		})
+		var e ErrSeedFailed
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, "tags", e.TableName)
+		assert.Equal(t, 2, count("products"))
+		assert.Equal(t, 1, count("tags"))
+	})
+}
+
+func BenchmarkSeedParallelPostgreSQL(b *testing.B) {
+	connStr := startSeedParallelPostgreSQL(b)
+
+	ctx := context.Background()
+	dbc, err := NewDBConnector(ctx, connStr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer dbc.DB().Close()
+
+	var src strings.Builder
+	for i := range 20 {
+		table := fmt.Sprintf("table%02d", i)
+		if _, err := dbc.DB().ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (id INTEGER PRIMARY KEY, name TEXT NOT NULL);", table)); err != nil {
+			b.Fatal(err)
+		}
+		fmt.Fprintf(&src, "%s:\n", table)
+		for j := range 200 {
+			fmt.Fprintf(&src, "- { id: %d, name: name%d }\n", j, j)
+		}
+	}
+	data, err := ParseYAML(strings.NewReader(src.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("sequential", func(b *testing.B) {
+		for b.Loop() {
+			if err := Seed(ctx, dbc, data, SeedOpt{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for b.Loop() {
+			if err := Seed(ctx, dbc, data, SeedOpt{Parallel: true}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func Test_commitGroups(t *testing.T) {
+	groups := [][]string{{"customers", "orders"}, {"products"}, {"tags"}}
+	commitErr := errors.New("serialization failure")
+	failAt := func(n int, calls *[]int) func(i int) error {
+		return func(i int) error {
+			*calls = append(*calls, i)
+			if i == n {
+				return commitErr
+			}
+			return nil
+		}
+	}
+
+	t.Run("all committed", func(t *testing.T) {
+		var calls []int
+		assert.NoError(t, commitGroups(groups, failAt(-1, &calls)))
+		assert.Equal(t, []int{0, 1, 2}, calls)
+	})
+
+	t.Run("first commit failed", func(t *testing.T) {
+		var calls []int
+		err := commitGroups(groups, failAt(0, &calls))
+		assert.Equal(t, commitErr, err)
+		assert.Equal(t, []int{0}, calls)
+	})
+
+	t.Run("partial commit", func(t *testing.T) {
+		var calls []int
+		err := commitGroups(groups, failAt(2, &calls))
+		var e ErrPartialCommit
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, []string{"customers", "orders", "products"}, e.Committed)
+		assert.IsError(t, err, commitErr)
+		assert.Contains(t, err.Error(), "failed to commit after tables [customers, orders, products] were committed")
+		assert.Equal(t, []int{0, 1, 2}, calls)
+	})
+}
+
+// parallelConnector hides the type of the SQLite connector to use the parallel path of Seed.
+type parallelConnector struct {
+	DBConnector
+}
+
+func TestSeedParallelPoolLimited(t *testing.T) {
+	dbc, err := NewDBConnectorWithOpts(t.Context(), "sqlite://file:"+filepath.Join(t.TempDir(), "pool.db"), WithMaxOpenConns(2))
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE products (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		customers:
+		- { id: 1, name: Frank }
+		products:
+		- { id: 1, name: Apple }
+		tags:
+		- { id: 1, name: new }
+		`)))
+	assert.NoError(t, err)
+
+	// 3 groups don't fit in the pool of 2 connections. It used to wait for a connection forever
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	err = Seed(ctx, parallelConnector{dbc}, data, SeedOpt{Parallel: true, Operations: map[string]Operation{"products": UpsertOperation}})
+	assert.NoError(t, err)
+	for _, table := range []string{"customers", "products", "tags"} {
+		var count int
+		assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM "+table).Scan(&count))
+		assert.Equal(t, 1, count, table)
+	}
+}
+
+// The MySQL connector limits the pool to 2 connections by default.
+func TestSeedParallelMySQL(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	ctx := context.Background()
+	mysqlContainer, err := mysql.Run(ctx, "mysql:8",
+		mysql.WithDatabase("seedtest"),
+		mysql.WithUsername("root"),
+		mysql.WithPassword("password"),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		if err := testcontainers.TerminateContainer(mysqlContainer); err != nil {
+			t.Fatalf("failed to terminate mysqlContainer: %s", err)
+		}
+	})
+	connStr, err := mysqlContainer.ConnectionString(ctx, "tls=skip-verify", "multiStatements=true")
+	assert.NoError(t, err)
+
+	dbc, err := NewDBConnector(t.Context(), "mysql://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE products (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		customers:
+		- { id: 1, name: Frank }
+		products:
+		- { id: 1, name: Apple }
+		tags:
+		- { id: 1, name: new }
+		`)))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+	defer cancel()
+	err = Seed(ctx, dbc, data, SeedOpt{Parallel: true, Operations: map[string]Operation{"products": UpsertOperation}})
+	assert.NoError(t, err)
+	for _, table := range []string{"customers", "products", "tags"} {
+		var count int
+		assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM "+table).Scan(&count))
+		assert.Equal(t, 1, count, table)
+	}
+}
@@ -0,0 +1,237 @@
+package dbtestify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SeedStreaming seeds the database with the rows from ParseYAMLStreaming in a transaction.
+//
+// Rows are inserted every BatchSize rows, so the whole dataset is not kept in memory.
+// Parse errors are not visible from SeedStreaming, so use SeedStreamingWithTx to roll back on them.
+func SeedStreaming(ctx context.Context, dbc DBConnector, rows <-chan *TableRow, opt SeedOpt) error {
+	tx, err := dbc.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := SeedStreamingWithTx(ctx, dbc, tx, rows, opt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SeedStreamingWithTx is the same as SeedStreaming, but it uses the provided transaction instead of beginning a new one.
+//
+// It doesn't commit nor rollback the transaction. The caller should do it after checking the error channel of ParseYAMLStreaming:
+//
+//	rows, errs := dbtestify.ParseYAMLStreaming(f)
+//	err := dbtestify.SeedStreamingWithTx(ctx, dbc, tx, rows, opt)
+//	if err == nil {
+//	    err = <-errs
+//	}
+//
+// Tables are processed in the order of the rows. ClearInsertOperation truncates the table when its first row arrives,
+// and TruncateOperation tables in SeedOpt.Operations are truncated first. Parallel and `_depends_on` are not supported.
+func SeedStreamingWithTx(ctx context.Context, dbc DBConnector, tx *sql.Tx, rows <-chan *TableRow, opt SeedOpt) error {
+	defer func() {
+		// unblock the parser if seeding stops in the middle
+		go func() {
+			for range rows {
+			}
+		}()
+	}()
+	if opt.BatchSize == 0 {
+		opt.BatchSize = DefaultBatchSize
+	}
+	var fkc ForeignKeyController
+	if opt.DisableForeignKeys {
+		if c, ok := dbc.(ForeignKeyController); ok {
+			if err := c.DisableForeignKeys(ctx, tx); err != nil {
+				return err
+			}
+			fkc = c
+			// restore on error not to return the connection to the pool without foreign key checks
+			defer func() {
+				if fkc != nil {
+					fkc.EnableForeignKeys(context.WithoutCancel(ctx), tx)
+				}
+			}()
+		}
+	}
+	s := &streamSeeder{ctx: ctx, dbc: dbc, tx: tx, opt: opt, seen: map[string]bool{}}
+	for t, op := range opt.Operations {
+		if op == TruncateOperation && MatchTargetTables(opt.TargetTables, t) {
+			s.seen[t] = true
+			if err := s.truncate(t); err != nil {
+				return err
+			}
+		}
+	}
+	for row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.add(row); err != nil {
+			return err
+		}
+	}
+	if err := s.endTable(); err != nil {
+		return err
+	}
+	if fkc != nil {
+		if err := fkc.EnableForeignKeys(ctx, tx); err != nil {
+			return err
+		}
+		fkc = nil
+	}
+	return nil
+}
+
+// streamSeeder keeps the rows of the current table until the batch is full.
+type streamSeeder struct {
+	ctx  context.Context
+	dbc  DBConnector
+	tx   *sql.Tx
+	opt  SeedOpt
+	seen map[string]bool
+
+	batch      *Table
+	batchStart int // index of the first row of batch in the dataset
+	op         Operation
+	task       string
+	pKeys      []string
+}
+
+func (s *streamSeeder) add(row *TableRow) error {
+	if !MatchTargetTables(s.opt.TargetTables, row.Table) {
+		return nil
+	}
+	if s.batch == nil || s.batch.Name != row.Table {
+		if err := s.endTable(); err != nil {
+			return err
+		}
+		if err := s.startTable(row.Table); err != nil {
+			return err
+		}
+	}
+	if s.task == "" {
+		return nil
+	}
+	if len(s.batch.Rows) == 0 {
+		s.batchStart = row.Index
+	}
+	s.batch.Rows = append(s.batch.Rows, row.Row)
+	s.batch.Tags = append(s.batch.Tags, row.Tags)
+	s.batch.AssertOnly = append(s.batch.AssertOnly, row.AssertOnly)
+	if len(s.batch.Rows) >= s.opt.batchSize(row.Table) {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *streamSeeder) startTable(tableName string) error {
+	first := !s.seen[tableName]
+	s.seen[tableName] = true
+	s.batch = &Table{Name: tableName}
+	s.op = s.opt.Operations[tableName]
+	s.pKeys = nil
+	if first && s.opt.BeforeTableHook != nil {
+		if err := s.opt.BeforeTableHook(s.ctx, s.dbc, s.tx, tableName); err != nil {
+			return fmt.Errorf("before table hook of %s failed: %w", tableName, err)
+		}
+	}
+	switch s.op {
+	case ClearInsertOperation, "":
+		if first {
+			if err := s.truncate(tableName); err != nil {
+				return err
+			}
+		}
+		s.task = "insert"
+	case InsertOperation:
+		s.task = "insert"
+	case UpsertOperation, DeleteOperation:
+		pKeys, err := s.dbc.PrimaryKeys(s.ctx, s.opt.targetTable(tableName))
+		if err != nil {
+			return err
+		}
+		s.pKeys = pKeys
+		s.task = string(s.op)
+	case InsertIgnoreOperation:
+		s.task = "insert-ignore"
+	default:
+		// rows of truncated tables are ignored
+		s.task = ""
+		return nil
+	}
+	if s.opt.Callback != nil {
+		s.opt.Callback(tableName, s.task, true, nil)
+	}
+	return nil
+}
+
+func (s *streamSeeder) endTable() error {
+	if s.batch == nil {
+		return nil
+	}
+	tableName := s.batch.Name
+	var err error
+	if s.task != "" {
+		err = s.flush()
+		if s.opt.Callback != nil {
+			s.opt.Callback(tableName, s.task, false, err)
+		}
+	}
+	s.batch = nil
+	if err != nil {
+		return err
+	}
+	if s.opt.AfterTableHook != nil {
+		if err := s.opt.AfterTableHook(s.ctx, s.dbc, s.tx, tableName); err != nil {
+			return fmt.Errorf("after table hook of %s failed: %w", tableName, err)
+		}
+	}
+	return nil
+}
+
+func (s *streamSeeder) flush() error {
+	if len(s.batch.Rows) == 0 {
+		return nil
+	}
+	var err error
+	if s.op == DeleteOperation {
+		err = deleteBatch(s.ctx, s.dbc, s.tx, s.batch, 0, len(s.batch.Rows), s.opt, s.pKeys)
+	} else {
+		err = insertBatch(s.ctx, s.dbc, s.tx, s.batch, 0, len(s.batch.Rows), s.opt, s.op, s.pKeys)
+	}
+	s.batch.Rows = s.batch.Rows[:0]
+	s.batch.Tags = s.batch.Tags[:0]
+	s.batch.AssertOnly = s.batch.AssertOnly[:0]
+	var e ErrSeedFailed
+	if errors.As(err, &e) {
+		// the index in the batch to the index in the dataset
+		e.BatchStart += s.batchStart
+		if e.RowIndex >= 0 {
+			e.RowIndex += s.batchStart
+		}
+		return e
+	}
+	return err
+}
+
+func (s *streamSeeder) truncate(tableName string) error {
+	if s.opt.Callback != nil {
+		s.opt.Callback(tableName, "truncate", true, nil)
+	}
+	err := s.dbc.Truncate(s.ctx, s.tx, s.opt.targetTable(tableName))
+	if s.opt.Callback != nil {
+		s.opt.Callback(tableName, "truncate", false, err)
+	}
+	if err != nil {
+		return ErrSeedFailed{TableName: tableName, RowIndex: -1, BatchStart: -1, Cause: err}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package dbtestify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestSeedStreamingSQLite(t *testing.T) {
+	os.Remove("seed_streaming_test.db")
+	connStr := "file:seed_streaming_test.db?cache=shared&mode=rwc"
+
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE item (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE log (id INTEGER PRIMARY KEY);
+		INSERT INTO user (id, name) VALUES (100, 'Old');
+		INSERT INTO item (id, name) VALUES (1, 'Old'), (2, 'Keep');
+		INSERT INTO log (id) VALUES (1);
+	`))
+	assert.NoError(t, err)
+
+	src := TrimIndent(t, `
+		user:
+		- { id: 1, name: Frank }
+		- { id: 2, name: Grace, _tag: [ex] }
+		- { id: 3, name: Heidi }
+		- { id: 4, name: Ivy, _assert_only: true }
+		- { id: 5, name: Judy }
+		item:
+		- { id: 1, name: New }
+		- { id: 3, name: Added }
+		`)
+	var tasks []string
+	var recorder *batchRecorder
+	rows, errs := ParseYAMLStreaming(strings.NewReader(src))
+	recorder = &batchRecorder{DBConnector: dbc, batches: map[string][]int{}}
+	err = SeedStreaming(t.Context(), recorder, rows, SeedOpt{
+		BatchSize:   2,
+		ExcludeTags: []string{"ex"},
+		Operations: map[string]Operation{
+			"item": UpsertOperation,
+			"log":  TruncateOperation,
+		},
+		Callback: func(targetTable, task string, start bool, err error) {
+			if start {
+				tasks = append(tasks, task+" "+targetTable)
+			}
+		},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, <-errs)
+	assert.Equal(t, []string{"truncate log", "truncate user", "insert user", "upsert item"}, tasks)
+	// excluded and assert-only rows are not inserted
+	assert.Equal(t, map[string][]int{"user": {1, 1, 1}}, recorder.batches)
+
+	query := func(q string) []string {
+		t.Helper()
+		rows, err := dbc.DB().QueryContext(t.Context(), q)
+		assert.NoError(t, err)
+		defer rows.Close()
+		var result []string
+		for rows.Next() {
+			var id int
+			var name string
+			assert.NoError(t, rows.Scan(&id, &name))
+			result = append(result, fmt.Sprintf("%d:%s", id, name))
+		}
+		return result
+	}
+	assert.Equal(t, []string{"1:Frank", "3:Heidi", "5:Judy"}, query("SELECT id, name FROM user ORDER BY id"))
+	assert.Equal(t, []string{"1:New", "2:Keep", "3:Added"}, query("SELECT id, name FROM item ORDER BY id"))
+	var count int
+	assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM log").Scan(&count))
+	assert.Equal(t, 0, count)
+
+	t.Run("delete and target tables", func(t *testing.T) {
+		rows, _ := ParseYAMLStreaming(strings.NewReader("user:\n- { id: 1 }\n- { id: 3 }\nitem:\n- { id: 1 }\n"))
+		err := SeedStreaming(t.Context(), dbc, rows, SeedOpt{
+			Operations:   map[string]Operation{"user": DeleteOperation},
+			TargetTables: []string{"user"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"5:Judy"}, query("SELECT id, name FROM user ORDER BY id"))
+		assert.Equal(t, []string{"1:New", "2:Keep", "3:Added"}, query("SELECT id, name FROM item ORDER BY id"))
+	})
+
+	t.Run("failed row", func(t *testing.T) {
+		rows, _ := ParseYAMLStreaming(strings.NewReader("user:\n- { id: 1, name: A }\n- { id: 2, name: B }\n- { id: 3, name: C }\n- { id: 4 }\n- { id: 5, name: E }\n"))
+		err := SeedStreaming(t.Context(), dbc, rows, SeedOpt{BatchSize: 1})
+		var e ErrSeedFailed
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, 3, e.RowIndex)
+		assert.Equal(t, 3, e.BatchStart)
+		// rolled back
+		assert.Equal(t, []string{"5:Judy"}, query("SELECT id, name FROM user ORDER BY id"))
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		rows, errs := ParseYAMLStreaming(strings.NewReader("user:\n- { id: 1, name: A }\n- 2\n"))
+		tx, err := dbc.DB().BeginTx(t.Context(), nil)
+		assert.NoError(t, err)
+		defer tx.Rollback()
+		err = SeedStreamingWithTx(t.Context(), dbc, tx, rows, SeedOpt{})
+		assert.NoError(t, err)
+		assert.Error(t, <-errs)
+		assert.NoError(t, tx.Rollback())
+		assert.Equal(t, []string{"5:Judy"}, query("SELECT id, name FROM user ORDER BY id"))
+	})
+}
+
+// peakHeap samples the heap size until stop is called, and returns the max value.
+func peakHeap() (stop func() uint64) {
+	var peak uint64
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var m runtime.MemStats
+		for {
+			runtime.ReadMemStats(&m)
+			peak = max(peak, m.HeapAlloc)
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+	return func() uint64 {
+		close(done)
+		wg.Wait()
+		return peak
+	}
+}
+
+func BenchmarkSeedStreaming(b *testing.B) {
+	os.Remove("seed_streaming_bench.db")
+	dbc, err := NewDBConnector(b.Context(), "sqlite3://file:seed_streaming_bench.db?cache=shared&mode=rwc")
+	assert.NoError(b, err)
+	defer dbc.DB().Close()
+	_, err = dbc.DB().ExecContext(b.Context(), `CREATE TABLE IF NOT EXISTS user (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT NOT NULL)`)
+	assert.NoError(b, err)
+
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		var src strings.Builder
+		src.WriteString("user:\n")
+		for i := range n {
+			fmt.Fprintf(&src, "- { id: %d, name: user%d, email: user%d@example.com }\n", i, i, i)
+		}
+		input := src.String()
+
+		b.Run(fmt.Sprintf("ParseYAML/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			for b.Loop() {
+				runtime.GC()
+				stop := peakHeap()
+				data, err := ParseYAML(strings.NewReader(input))
+				assert.NoError(b, err)
+				assert.NoError(b, Seed(b.Context(), dbc, data, SeedOpt{}))
+				peak = max(peak, stop())
+			}
+			b.ReportMetric(float64(peak), "peak-heap-B")
+		})
+		b.Run(fmt.Sprintf("Streaming/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			for b.Loop() {
+				runtime.GC()
+				stop := peakHeap()
+				rows, errs := ParseYAMLStreaming(strings.NewReader(input))
+				assert.NoError(b, SeedStreaming(b.Context(), dbc, rows, SeedOpt{}))
+				assert.NoError(b, <-errs)
+				peak = max(peak, stop())
+			}
+			b.ReportMetric(float64(peak), "peak-heap-B")
+		})
+	}
+}
@@ -3,8 +3,12 @@ package dbtestify
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -118,6 +122,51 @@ func TestSeedSQLite(t *testing.T) {
 			wantNames:  []string{"Frank", "Grace", "Heidi", "Johnny", "Kate"},
 			wantEmails: []any{"frank@example.com", "grace@example.com", "heidi@example.com", nil, nil},
 		},
+		{
+			name: "insert-ignore operation",
+			args: args{
+				src: TrimIndent(t, `
+					user:
+					- { id: 1, name: Frank, email: frank@example.com }
+					- { id: 5, name: Johnny } # already exists
+					`),
+				opt: SeedOpt{
+					Operations: map[string]Operation{"user": InsertIgnoreOperation},
+				},
+			},
+			wantNames:  []string{"Frank", "John", "Kate"},
+			wantEmails: []any{"frank@example.com", "john@example.com", nil},
+		},
+		{
+			name: "insert operation skips _assert_only rows",
+			args: args{
+				src: TrimIndent(t, `
+					user:
+					- { id: 1, name: Frank, email: frank@example.com }
+					- { id: 2, name: Grace, email: grace@example.com, _assert_only: true }
+					`),
+				opt: SeedOpt{
+					Operations: map[string]Operation{"user": InsertOperation},
+				},
+			},
+			wantNames:  []string{"Frank", "John", "Kate"},
+			wantEmails: []any{"frank@example.com", "john@example.com", nil},
+		},
+		{
+			name: "delete operation skips _assert_only rows",
+			args: args{
+				src: TrimIndent(t, `
+					user:
+					- { id: 5 } # John
+					- { id: 6, _assert_only: true } # Kate
+					`),
+				opt: SeedOpt{
+					Operations: map[string]Operation{"user": DeleteOperation},
+				},
+			},
+			wantNames:  []string{"Kate"},
+			wantEmails: []any{nil},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -129,6 +178,7 @@ func TestSeedSQLite(t *testing.T) {
 
 			dbc, err := NewDBConnector(ctx2, "sqlite3://"+connStr)
 			assert.NoError(t, err)
+			defer dbc.DB().Close()
 
 			_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
 				CREATE TABLE IF NOT EXISTS user (
@@ -289,6 +339,21 @@ func TestSeedPostgreSQL(t *testing.T) {
 			wantNames:  []string{"Frank", "Grace", "Heidi", "Johnny", "Kate"},
 			wantEmails: []any{"frank@example.com", "grace@example.com", "heidi@example.com", nil, nil},
 		},
+		{
+			name: "insert-ignore operation",
+			args: args{
+				src: TrimIndent(t, `
+					member:
+					- { id: 1, name: Frank, email: frank@example.com }
+					- { id: 5, name: Johnny } # already exists
+					`),
+				opt: SeedOpt{
+					Operations: map[string]Operation{"member": InsertIgnoreOperation},
+				},
+			},
+			wantNames:  []string{"Frank", "John", "Kate"},
+			wantEmails: []any{"frank@example.com", "john@example.com", nil},
+		},
 	}
 
 	ctx := context.Background()
@@ -474,6 +539,21 @@ func TestSeedMySQL(t *testing.T) {
 			wantNames:  []string{"Frank", "Grace", "Heidi", "Johnny", "Kate"},
 			wantEmails: []any{"frank@example.com", "grace@example.com", "heidi@example.com", nil, nil},
 		},
+		{
+			name: "insert-ignore operation",
+			args: args{
+				src: TrimIndent(t, `
+					member:
+					- { id: 1, name: Frank, email: frank@example.com }
+					- { id: 5, name: Johnny } # already exists
+					`),
+				opt: SeedOpt{
+					Operations: map[string]Operation{"member": InsertIgnoreOperation},
+				},
+			},
+			wantNames:  []string{"Frank", "John", "Kate"},
+			wantEmails: []any{"frank@example.com", "john@example.com", nil},
+		},
 	}
 
 	ctx := context.Background()
@@ -552,3 +632,1118 @@ func TestSeedMySQL(t *testing.T) {
 		})
 	}
 }
+
+func Test_sortTablesByDependency(t *testing.T) {
+	tables := []*Table{{Name: "orders"}, {Name: "customers"}, {Name: "products"}, {Name: "order_items"}}
+	t.Run("three table chain", func(t *testing.T) {
+		sorted, err := sortTablesByDependency(tables, map[string][]string{
+			"order_items": {"orders", "products"},
+			"orders":      {"customers"},
+		})
+		assert.NoError(t, err)
+		var names []string
+		for _, t := range sorted {
+			names = append(names, t.Name)
+		}
+		assert.Equal(t, []string{"customers", "orders", "products", "order_items"}, names)
+	})
+	t.Run("no dependency", func(t *testing.T) {
+		sorted, err := sortTablesByDependency(tables, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, tables, sorted)
+	})
+	t.Run("cycle", func(t *testing.T) {
+		_, err := sortTablesByDependency(tables, map[string][]string{
+			"orders":    {"customers"},
+			"customers": {"products"},
+			"products":  {"orders"},
+		})
+		assert.IsError(t, err, ErrCyclicDependency)
+		assert.Contains(t, err.Error(), "orders -> customers -> products -> orders")
+	})
+}
+
+func TestSeedDependsOnSQLite(t *testing.T) {
+	os.Remove("seed_depends_on.db")
+	connStr := "file:seed_depends_on.db?cache=shared&mode=rwc&_foreign_keys=on"
+
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers(id));
+		CREATE TABLE order_items (id INTEGER PRIMARY KEY, order_id INTEGER NOT NULL REFERENCES orders(id));
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		_depends_on:
+		  order_items: [orders]
+		  orders: [customers]
+		order_items:
+		- { id: 1, order_id: 1 }
+		orders:
+		- { id: 1, customer_id: 1 }
+		customers:
+		- { id: 1, name: Frank }
+		`)))
+	assert.NoError(t, err)
+
+	err = Seed(t.Context(), dbc, data, SeedOpt{
+		Operations: map[string]Operation{"customers": InsertOperation, "orders": InsertOperation, "order_items": InsertOperation},
+	})
+	assert.NoError(t, err)
+
+	var count int
+	err = dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM order_items").Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	t.Run("clear-insert truncates children first", func(t *testing.T) {
+		var truncated []string
+		err := Seed(t.Context(), dbc, data, SeedOpt{
+			Operations: map[string]Operation{"customers": ClearInsertOperation},
+			Callback: func(targetTable, task string, start bool, err error) {
+				if start && task == "truncate" {
+					truncated = append(truncated, targetTable)
+				}
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"order_items", "orders", "customers"}, truncated)
+
+		var count int
+		assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM order_items").Scan(&count))
+		assert.Equal(t, 1, count)
+	})
+
+	data.DependsOn["customers"] = []string{"order_items"}
+	err = Seed(t.Context(), dbc, data, SeedOpt{})
+	assert.IsError(t, err, ErrCyclicDependency)
+}
+
+func TestSeedForeignKeyOrderSQLite(t *testing.T) {
+	os.Remove("seed_fk_order.db")
+	connStr := "file:seed_fk_order.db?cache=shared&mode=rwc&_foreign_keys=on"
+
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL, referrer_id INTEGER REFERENCES customers(id));
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers(id));
+		CREATE TABLE order_items (id INTEGER PRIMARY KEY, order_id INTEGER NOT NULL REFERENCES orders(id));
+	`))
+	assert.NoError(t, err)
+
+	// child tables come first, and customers refers itself
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		order_items:
+		- { id: 1, order_id: 1 }
+		orders:
+		- { id: 1, customer_id: 2 }
+		customers:
+		- { id: 1, name: Frank }
+		- { id: 2, name: Grace, referrer_id: 1 }
+		`)))
+	assert.NoError(t, err)
+
+	var tasks []string
+	err = Seed(t.Context(), dbc, data, SeedOpt{
+		Operations: map[string]Operation{"customers": InsertOperation, "orders": InsertOperation, "order_items": InsertOperation},
+		Callback: func(targetTable, task string, start bool, err error) {
+			if start {
+				tasks = append(tasks, targetTable)
+			}
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"customers", "orders", "order_items"}, tasks)
+	// the dataset is not modified
+	assert.Equal(t, 0, len(data.DependsOn))
+
+	var count int
+	assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM order_items").Scan(&count))
+	assert.Equal(t, 1, count)
+
+	t.Run("truncate and delete children first", func(t *testing.T) {
+		tasks := map[string][]string{}
+		callback := func(targetTable, task string, start bool, err error) {
+			if start {
+				tasks[task] = append(tasks[task], targetTable)
+			}
+		}
+		err := Seed(t.Context(), dbc, data, SeedOpt{Callback: callback})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"order_items", "orders", "customers"}, tasks["truncate"])
+
+		err = Seed(t.Context(), dbc, data, SeedOpt{
+			Operations: map[string]Operation{"customers": DeleteOperation, "orders": DeleteOperation, "order_items": DeleteOperation},
+			Callback:   callback,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"order_items", "orders", "customers"}, tasks["delete"])
+		for _, table := range []string{"customers", "orders", "order_items"} {
+			var count int
+			assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM "+table).Scan(&count))
+			assert.Equal(t, 0, count, "table: %s", table)
+		}
+	})
+}
+
+// multiTruncateConnector records the tables truncated in one statement instead of PostgreSQL.
+type multiTruncateConnector struct {
+	DBConnector
+	truncated [][]string
+}
+
+func (m *multiTruncateConnector) truncateTables(ctx context.Context, tx *sql.Tx, tableNames []string) error {
+	m.truncated = append(m.truncated, tableNames)
+	for _, t := range tableNames {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSeedTruncateTogether(t *testing.T) {
+	os.Remove("seed_truncate_together.db")
+	sqlite, err := NewDBConnector(t.Context(), "sqlite3://file:seed_truncate_together.db?cache=shared&mode=rwc&_foreign_keys=on")
+	assert.NoError(t, err)
+	defer sqlite.DB().Close()
+	_, err = sqlite.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE customers (id INTEGER PRIMARY KEY);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers(id));
+		INSERT INTO customers (id) VALUES (1);
+		INSERT INTO orders (id, customer_id) VALUES (1, 1);
+	`))
+	assert.NoError(t, err)
+	dbc := &multiTruncateConnector{DBConnector: sqlite}
+	data, err := ParseYAML(strings.NewReader("customers:\n- { id: 2 }\norders:\n- { id: 2, customer_id: 2 }\n"))
+	assert.NoError(t, err)
+
+	var tasks []string
+	err = Seed(t.Context(), dbc, data, SeedOpt{
+		Callback: func(targetTable, task string, start bool, err error) {
+			if start {
+				tasks = append(tasks, task+" "+targetTable)
+			}
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"orders", "customers"}}, dbc.truncated)
+	assert.Equal(t, []string{"truncate orders", "truncate customers", "insert customers", "insert orders"}, tasks)
+
+	// a single table and savepoints use DBConnector.Truncate
+	dbc.truncated = nil
+	assert.NoError(t, Seed(t.Context(), dbc, data, SeedOpt{Savepoints: true}))
+	assert.NoError(t, Seed(t.Context(), dbc, data, SeedOpt{TargetTables: []string{"orders"}}))
+	assert.Equal(t, 0, len(dbc.truncated))
+
+	t.Run("TruncateTables", func(t *testing.T) {
+		// foreign keys are not disabled because it needs the superuser on PostgreSQL
+		fkc := &fkRecordingConnector{multiTruncateConnector: dbc}
+		assert.NoError(t, TruncateTables(t.Context(), fkc, []string{"customers", "orders"}))
+		assert.Equal(t, [][]string{{"orders", "customers"}}, dbc.truncated)
+		assert.False(t, fkc.disabled)
+
+		// other databases disable foreign keys
+		other := &fkRecordingConnector{multiTruncateConnector: &multiTruncateConnector{DBConnector: sqlite}}
+		assert.NoError(t, TruncateTables(t.Context(), struct {
+			DBConnector
+			ForeignKeyController
+		}{other, other}, []string{"customers", "orders"}))
+		assert.True(t, other.disabled)
+	})
+}
+
+// fkRecordingConnector records whether foreign keys are disabled.
+type fkRecordingConnector struct {
+	*multiTruncateConnector
+	disabled bool
+}
+
+func (f *fkRecordingConnector) DisableForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	f.disabled = true
+	return nil
+}
+
+func (f *fkRecordingConnector) EnableForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+
+func TestSeedForeignKeyOrderPostgreSQL(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	connStr := startSeedParallelPostgreSQL(t)
+
+	dbc, err := NewDBConnector(t.Context(), connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE SCHEMA analytics;
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE products (sku TEXT, variant TEXT, PRIMARY KEY (sku, variant));
+		CREATE TABLE orders (
+			id INTEGER PRIMARY KEY,
+			customer_id INTEGER NOT NULL REFERENCES customers(id),
+			sku TEXT NOT NULL,
+			variant TEXT NOT NULL,
+			FOREIGN KEY (variant, sku) REFERENCES products(variant, sku)
+		);
+		CREATE TABLE analytics.events (id INTEGER PRIMARY KEY, order_id INTEGER NOT NULL REFERENCES public.orders(id));
+	`))
+	assert.NoError(t, err)
+
+	fks, err := dbc.ForeignKeys(t.Context(), "orders")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(fks))
+	assert.SliceContains(t, fks, ForeignKey{Table: "orders", Column: "customer_id", RefTable: "customers", RefColumn: "id"})
+	assert.SliceContains(t, fks, ForeignKey{Table: "orders", Column: "variant", RefTable: "products", RefColumn: "variant"})
+	assert.SliceContains(t, fks, ForeignKey{Table: "orders", Column: "sku", RefTable: "products", RefColumn: "sku"})
+
+	fks, err = dbc.ForeignKeys(t.Context(), "analytics.events")
+	assert.NoError(t, err)
+	assert.Equal(t, []ForeignKey{{Table: "analytics.events", Column: "order_id", RefTable: "public.orders", RefColumn: "id"}}, fks)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		analytics.events:
+		- { id: 1, order_id: 1 }
+		public.orders:
+		- { id: 1, customer_id: 1, sku: A, variant: red }
+		public.products:
+		- { sku: A, variant: red }
+		public.customers:
+		- { id: 1, name: Frank }
+		`)))
+	assert.NoError(t, err)
+	assert.NoError(t, Seed(t.Context(), dbc, data, SeedOpt{Operations: map[string]Operation{
+		"analytics.events": InsertOperation, "public.orders": InsertOperation, "public.products": InsertOperation, "public.customers": InsertOperation,
+	}}))
+}
+
+func TestSeedDisableForeignKeysSQLite(t *testing.T) {
+	os.Remove("seed_disable_fk.db")
+	connStr := "file:seed_disable_fk.db?cache=shared&mode=rwc&_foreign_keys=on"
+
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL REFERENCES customers(id));
+	`))
+	assert.NoError(t, err)
+
+	// child table comes first. `_depends_on` is given not to sort tables by foreign keys
+	data := &DataSet{
+		DependsOn: map[string][]string{"customers": {"orders"}},
+		Tables: []*Table{
+			{Name: "orders", Rows: []map[string]any{{"id": 1, "customer_id": 1}}, Tags: [][]string{nil}},
+			{Name: "customers", Rows: []map[string]any{{"id": 1, "name": "Frank"}}, Tags: [][]string{nil}},
+		},
+	}
+
+	err = Seed(t.Context(), dbc, data, SeedOpt{})
+	var e ErrSeedFailed
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "orders", e.TableName)
+
+	err = Seed(t.Context(), dbc, data, SeedOpt{DisableForeignKeys: true})
+	assert.NoError(t, err)
+
+	var count int
+	err = dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM orders").Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestSeedFailedSQLite(t *testing.T) {
+	os.Remove("seed_failed.db")
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://file:seed_failed.db?cache=shared&mode=rwc")
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), "CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);")
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		user:
+		- { id: 1, name: Frank }
+		- { id: 2, name: Grace }
+		- { id: 2, name: Heidi }
+		`)))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data *DataSet
+		opt  SeedOpt
+		want ErrSeedFailed
+	}{
+		{
+			name: "batch",
+			data: data,
+			want: ErrSeedFailed{TableName: "user", RowIndex: -1, BatchStart: 0},
+		},
+		{
+			name: "single row batch",
+			data: data,
+			opt:  SeedOpt{BatchSize: 2},
+			want: ErrSeedFailed{TableName: "user", RowIndex: 2, BatchStart: 2},
+		},
+		{
+			name: "truncate",
+			data: &DataSet{Tables: []*Table{{Name: "missing"}}},
+			want: ErrSeedFailed{TableName: "missing", RowIndex: -1, BatchStart: -1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Seed(t.Context(), dbc, tt.data, tt.opt)
+			var e ErrSeedFailed
+			assert.True(t, errors.As(err, &e))
+			assert.Error(t, e.Cause)
+			assert.Equal(t, errors.Unwrap(err), e.Cause)
+			e.Cause = nil
+			assert.Equal(t, tt.want, e)
+		})
+	}
+}
+
+func TestSeedSchemaQualifiedTableSQLite(t *testing.T) {
+	os.Remove("seed_schema_main.db")
+	os.Remove("seed_schema_analytics.db")
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://file:seed_schema_main.db?mode=rwc")
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+	// attached database is only visible from the same connection
+	dbc.DB().SetMaxOpenConns(1)
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		ATTACH DATABASE 'seed_schema_analytics.db' AS analytics;
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE analytics.events (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL, name TEXT NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		main.user:
+		- { id: 1, name: Frank }
+		analytics.events:
+		- { id: 1, user_id: 1, name: login }
+		- { id: 2, user_id: 1, name: logout }
+		`)))
+	assert.NoError(t, err)
+
+	err = Seed(t.Context(), dbc, data, SeedOpt{TargetTables: []string{"main.user", "analytics.events"}})
+	assert.NoError(t, err)
+
+	var count int
+	assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM analytics.events").Scan(&count))
+	assert.Equal(t, 2, count)
+
+	pkeys, err := dbc.PrimaryKeys(t.Context(), "analytics.events")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id"}, pkeys)
+
+	ok, _, err := Assert(t.Context(), dbc, data, AssertOpt{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestSeedSchemaQualifiedTablePostgreSQL(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	connStr := startSeedParallelPostgreSQL(t)
+
+	dbc, err := NewDBConnector(t.Context(), connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE SCHEMA analytics;
+		CREATE TABLE public.users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE analytics.events (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL, name TEXT NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		public.users:
+		- { id: 1, name: Frank }
+		analytics.events:
+		- { id: 1, user_id: 1, name: login }
+		- { id: 2, user_id: 1, name: logout }
+		`)))
+	assert.NoError(t, err)
+
+	assert.NoError(t, Seed(t.Context(), dbc, data, SeedOpt{}))
+	// upsert and delete use the primary keys of the qualified table
+	assert.NoError(t, Seed(t.Context(), dbc, data, SeedOpt{Operations: map[string]Operation{"analytics.events": UpsertOperation}}))
+
+	ok, _, err := Assert(t.Context(), dbc, data, AssertOpt{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.NoError(t, Seed(t.Context(), dbc, data, SeedOpt{Operations: map[string]Operation{"analytics.events": DeleteOperation}}))
+	var count int
+	assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM analytics.events").Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestSeedDefaultSchemaSQLite(t *testing.T) {
+	os.Remove("seed_default_schema_main.db")
+	os.Remove("seed_default_schema_analytics.db")
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://file:seed_default_schema_main.db?mode=rwc")
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+	// attached database is only visible from the same connection
+	dbc.DB().SetMaxOpenConns(1)
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		ATTACH DATABASE 'seed_default_schema_analytics.db' AS analytics;
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE analytics.events (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL, name TEXT NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		_schema: analytics
+		main.user:
+		- { id: 1, name: Frank }
+		events:
+		- { id: 1, user_id: 1, name: login }
+		- { id: 2, user_id: 1, name: logout }
+		`)))
+	assert.NoError(t, err)
+
+	assert.NoError(t, Seed(t.Context(), dbc, data, SeedOpt{}))
+
+	var count int
+	assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM analytics.events").Scan(&count))
+	assert.Equal(t, 2, count)
+	assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM main.user").Scan(&count))
+	assert.Equal(t, 1, count)
+
+	ok, _, err := Assert(t.Context(), dbc, data, AssertOpt{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestSeedDefaultSchemaPostgreSQL(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	connStr := startSeedParallelPostgreSQL(t)
+
+	dbc, err := NewDBConnector(t.Context(), connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE SCHEMA analytics;
+		CREATE TABLE public.users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE analytics.events (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL, name TEXT NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		_schema: analytics
+		public.users:
+		- { id: 1, name: Frank }
+		events:
+		- { id: 1, user_id: 1, name: login }
+		- { id: 2, user_id: 1, name: logout }
+		`)))
+	assert.NoError(t, err)
+
+	assert.NoError(t, Seed(t.Context(), dbc, data, SeedOpt{}))
+	assert.NoError(t, Seed(t.Context(), dbc, data, SeedOpt{Operations: map[string]Operation{"events": UpsertOperation}}))
+
+	ok, _, err := Assert(t.Context(), dbc, data, AssertOpt{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.NoError(t, Seed(t.Context(), dbc, data, SeedOpt{Operations: map[string]Operation{"events": DeleteOperation}}))
+	var count int
+	assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM analytics.events").Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestTargetTablesPatternSQLite(t *testing.T) {
+	os.Remove("target_tables_pattern.db")
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://file:target_tables_pattern.db?cache=shared&mode=rwc")
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE audit_access (id INTEGER PRIMARY KEY, path TEXT NOT NULL);
+		CREATE TABLE audit_query (id INTEGER PRIMARY KEY, query TEXT NOT NULL);
+		CREATE TABLE payment_log (id INTEGER PRIMARY KEY, amount INTEGER NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		audit_access:
+		- { id: 1, path: /login }
+		audit_query:
+		- { id: 1, query: SELECT 1 }
+		payment_log:
+		- { id: 1, amount: 100 }
+		`)))
+	assert.NoError(t, err)
+
+	var seeded []string
+	err = Seed(t.Context(), dbc, data, SeedOpt{
+		TargetTables: []string{"audit_*"},
+		Callback: func(targetTable, task string, start bool, err error) {
+			if start && task == "insert" {
+				seeded = append(seeded, targetTable)
+			}
+		},
+	})
+	assert.NoError(t, err)
+	slices.Sort(seeded)
+	assert.Equal(t, []string{"audit_access", "audit_query"}, seeded)
+
+	var count int
+	assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM payment_log").Scan(&count))
+	assert.Equal(t, 0, count)
+
+	ok, result, err := Assert(t.Context(), dbc, data, AssertOpt{TargetTables: []string{"audit_*"}})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	var asserted []string
+	for _, r := range result {
+		asserted = append(asserted, r.Name)
+	}
+	slices.Sort(asserted)
+	assert.Equal(t, []string{"audit_access", "audit_query"}, asserted)
+}
+
+func TestSeedTableHookSQLite(t *testing.T) {
+	os.Remove("seed_table_hook.db")
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://file:seed_table_hook.db?cache=shared&mode=rwc")
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL);
+		CREATE TABLE order_count (count INTEGER NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		_depends_on:
+		  orders: [customers]
+		orders:
+		- { id: 1, customer_id: 1 }
+		- { id: 2, customer_id: 1 }
+		customers:
+		- { id: 1, name: Frank }
+		`)))
+	assert.NoError(t, err)
+
+	countOf := func(table string) int {
+		var count int
+		assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM "+table).Scan(&count))
+		return count
+	}
+
+	t.Run("call order", func(t *testing.T) {
+		var called []string
+		err := Seed(t.Context(), dbc, data, SeedOpt{
+			BeforeTableHook: func(ctx context.Context, dbc DBConnector, tx *sql.Tx, tableName string) error {
+				called = append(called, "before:"+tableName)
+				return nil
+			},
+			AfterTableHook: func(ctx context.Context, dbc DBConnector, tx *sql.Tx, tableName string) error {
+				called = append(called, "after:"+tableName)
+				if tableName != "orders" {
+					return nil
+				}
+				// rows of the table are visible in the transaction
+				_, err := tx.ExecContext(ctx, "INSERT INTO order_count (count) SELECT COUNT(*) FROM orders")
+				return err
+			},
+			Callback: func(targetTable, task string, start bool, err error) {
+				if start && task == "insert" {
+					called = append(called, "insert:"+targetTable)
+				}
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"before:customers", "before:orders",
+			"insert:customers", "after:customers",
+			"insert:orders", "after:orders",
+		}, called)
+
+		var count int
+		assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT count FROM order_count").Scan(&count))
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		_, err := dbc.DB().ExecContext(t.Context(), "DELETE FROM orders; DELETE FROM customers;")
+		assert.NoError(t, err)
+
+		hookErr := errors.New("hook error")
+		err = Seed(t.Context(), dbc, data, SeedOpt{
+			AfterTableHook: func(ctx context.Context, dbc DBConnector, tx *sql.Tx, tableName string) error {
+				if tableName == "orders" {
+					return hookErr
+				}
+				return nil
+			},
+		})
+		assert.IsError(t, err, hookErr)
+		assert.Contains(t, err.Error(), "after table hook of orders")
+		assert.Equal(t, 0, countOf("customers"))
+		assert.Equal(t, 0, countOf("orders"))
+
+		err = Seed(t.Context(), dbc, data, SeedOpt{
+			BeforeTableHook: func(ctx context.Context, dbc DBConnector, tx *sql.Tx, tableName string) error {
+				return hookErr
+			},
+		})
+		assert.IsError(t, err, hookErr)
+		assert.Equal(t, 0, countOf("customers"))
+	})
+}
+
+func TestSeedTableHookPostgreSQL(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	connStr := startSeedParallelPostgreSQL(t)
+
+	dbc, err := NewDBConnector(t.Context(), connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), "CREATE TABLE users (id SERIAL PRIMARY KEY, name TEXT NOT NULL);")
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		users:
+		- { id: 1, name: Frank }
+		- { id: 2, name: Grace }
+		`)))
+	assert.NoError(t, err)
+
+	// rows with explicit ids don't advance the sequence
+	resetSequence := func(ctx context.Context, dbc DBConnector, tx *sql.Tx, tableName string) error {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %[1]s", tableName))
+		return err
+	}
+	err = Seed(t.Context(), dbc, data, SeedOpt{AfterTableHook: resetSequence})
+	assert.NoError(t, err)
+
+	var id int
+	err = dbc.DB().QueryRowContext(t.Context(), "INSERT INTO users (name) VALUES ('Heidi') RETURNING id").Scan(&id)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, id)
+}
+
+func TestSeedWithTxSQLite(t *testing.T) {
+	os.Remove("seed_with_tx.db")
+	db, err := sql.Open("sqlite3", "file:seed_with_tx.db?cache=shared&mode=rwc")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = NewDBConnectorFromDB(db, "oracle")
+	assert.IsError(t, err, ErrInvalidDBDriver)
+
+	dbc, err := NewDBConnectorFromDB(db, "sqlite3")
+	assert.NoError(t, err)
+
+	_, err = db.ExecContext(t.Context(), "CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);")
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		user:
+		- { id: 1, name: Frank }
+		`)))
+	assert.NoError(t, err)
+
+	countUsers := func() int {
+		var count int
+		err := db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM user").Scan(&count)
+		assert.NoError(t, err)
+		return count
+	}
+
+	// rollback by the caller
+	tx, err := db.BeginTx(t.Context(), nil)
+	assert.NoError(t, err)
+	err = SeedWithTx(t.Context(), dbc, tx, data, SeedOpt{})
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Rollback())
+	assert.Equal(t, 0, countUsers())
+
+	// commit by the caller
+	tx, err = db.BeginTx(t.Context(), nil)
+	assert.NoError(t, err)
+	err = SeedWithTx(t.Context(), dbc, tx, data, SeedOpt{})
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, 1, countUsers())
+}
+
+// batchRecorder records the number of rows of each Insert call
+type batchRecorder struct {
+	DBConnector
+	batches map[string][]int
+}
+
+func (b *batchRecorder) Insert(ctx context.Context, tx *sql.Tx, tableName string, columns []string, values []any) error {
+	b.batches[tableName] = append(b.batches[tableName], len(values)/len(columns))
+	return b.DBConnector.Insert(ctx, tx, tableName, columns, values)
+}
+
+func TestSeedBatchSQLite(t *testing.T) {
+	os.Remove("seed_batch.db")
+	connStr := "file:seed_batch.db?cache=shared&mode=rwc"
+
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), "CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);")
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		user:
+		- { id: 1, name: Frank }
+		- { id: 2, name: Grace, _seed_only: true }
+		- { id: 3, name: Heidi, _assert_only: true }
+		- { id: 4, name: Ivan, _tag: [skip] }
+		- { id: 5, name: Judy }
+		`)))
+	assert.NoError(t, err)
+	table := data.Tables[0]
+	ids := func(tx *sql.Tx) []int {
+		rows, err := tx.QueryContext(t.Context(), "SELECT id FROM user ORDER BY id")
+		assert.NoError(t, err)
+		defer rows.Close()
+		var result []int
+		for rows.Next() {
+			var id int
+			assert.NoError(t, rows.Scan(&id))
+			result = append(result, id)
+		}
+		return result
+	}
+
+	t.Run("chunks", func(t *testing.T) {
+		tx, err := dbc.DB().BeginTx(t.Context(), nil)
+		assert.NoError(t, err)
+		defer tx.Rollback()
+		opt := SeedOpt{BatchSize: 1, ExcludeTags: []string{"skip"}}
+		for offset := 0; offset < len(table.Rows); offset += 2 {
+			assert.NoError(t, SeedBatch(t.Context(), dbc, tx, table, offset, 2, opt))
+		}
+		assert.Equal(t, []int{1, 2, 5}, ids(tx))
+	})
+
+	t.Run("limit 0 and upsert", func(t *testing.T) {
+		tx, err := dbc.DB().BeginTx(t.Context(), nil)
+		assert.NoError(t, err)
+		defer tx.Rollback()
+		assert.NoError(t, SeedBatch(t.Context(), dbc, tx, table, 3, 0, SeedOpt{}))
+		assert.Equal(t, []int{4, 5}, ids(tx))
+		err = SeedBatch(t.Context(), dbc, tx, table, 0, 0, SeedOpt{Operations: map[string]Operation{"user": UpsertOperation}})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 4, 5}, ids(tx))
+	})
+
+	t.Run("row index in the whole table", func(t *testing.T) {
+		tx, err := dbc.DB().BeginTx(t.Context(), nil)
+		assert.NoError(t, err)
+		defer tx.Rollback()
+		assert.NoError(t, SeedBatch(t.Context(), dbc, tx, table, 4, 1, SeedOpt{}))
+		err = SeedBatch(t.Context(), dbc, tx, table, 4, 1, SeedOpt{})
+		var e ErrSeedFailed
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, 4, e.RowIndex)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tx, err := dbc.DB().BeginTx(t.Context(), nil)
+		assert.NoError(t, err)
+		defer tx.Rollback()
+		assert.Error(t, SeedBatch(t.Context(), dbc, tx, table, -1, 2, SeedOpt{}))
+		assert.Error(t, SeedBatch(t.Context(), dbc, tx, table, 0, 2, SeedOpt{Operations: map[string]Operation{"user": DeleteOperation}}))
+	})
+}
+
+func TestSeedTableBatchSizeSQLite(t *testing.T) {
+	os.Remove("seed_table_batch_size.db")
+	connStr := "file:seed_table_batch_size.db?cache=shared&mode=rwc"
+
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE wide (id INTEGER PRIMARY KEY);
+		CREATE TABLE narrow (id INTEGER PRIMARY KEY);
+		CREATE TABLE other (id INTEGER PRIMARY KEY);
+	`))
+	assert.NoError(t, err)
+
+	var src strings.Builder
+	for _, table := range []string{"wide", "narrow", "other"} {
+		src.WriteString(table + ":\n")
+		for i := range 5 {
+			src.WriteString("- { id: " + strconv.Itoa(i) + " }\n")
+		}
+	}
+	data, err := ParseYAML(strings.NewReader(src.String()))
+	assert.NoError(t, err)
+
+	recorder := &batchRecorder{DBConnector: dbc, batches: map[string][]int{}}
+	err = Seed(t.Context(), recorder, data, SeedOpt{
+		BatchSize: 2,
+		TableBatchSize: map[string]int{
+			"wide":   1,
+			"narrow": 10,
+			"other":  0, // fallback to BatchSize
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]int{
+		"wide":   {1, 1, 1, 1, 1},
+		"narrow": {5},
+		"other":  {2, 2, 1},
+	}, recorder.batches)
+}
+
+func TestSeedBatchWithOnlyExcludedRowsSQLite(t *testing.T) {
+	os.Remove("seed_excluded_batch.db")
+	connStr := "file:seed_excluded_batch.db?cache=shared&mode=rwc"
+
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		user:
+		- { id: 1, name: Frank }
+		- { id: 2, name: Grace, _tag: [user] }
+		- { id: 3, name: Ivy, _tag: [ex_user] }
+		- { id: 4, name: Judy, _tag: [ex_user] }
+		`)))
+	assert.NoError(t, err)
+
+	for _, op := range []Operation{ClearInsertOperation, UpsertOperation, InsertIgnoreOperation, DeleteOperation} {
+		t.Run(string(op), func(t *testing.T) {
+			recorder := &batchRecorder{DBConnector: dbc, batches: map[string][]int{}}
+			err := Seed(t.Context(), recorder, data, SeedOpt{
+				BatchSize:   2,
+				ExcludeTags: []string{"ex_user"},
+				Operations:  map[string]Operation{"user": op},
+			})
+			assert.NoError(t, err)
+			if op == ClearInsertOperation {
+				// the last batch is skipped
+				assert.Equal(t, map[string][]int{"user": {2}}, recorder.batches)
+			}
+		})
+	}
+}
+
+func TestSeedViewAliasesSQLite(t *testing.T) {
+	os.Remove("seed_view_aliases.db")
+	connStr := "file:seed_view_aliases.db?cache=shared&mode=rwc"
+
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, active INTEGER NOT NULL DEFAULT 1);
+		CREATE VIEW active_users AS SELECT id, name FROM users WHERE active = 1;
+		INSERT INTO users (id, name, active) VALUES (100, 'Old', 0);
+	`))
+	assert.NoError(t, err)
+
+	query := func() []string {
+		t.Helper()
+		rows, err := dbc.DB().QueryContext(t.Context(), "SELECT id, name FROM users ORDER BY id")
+		assert.NoError(t, err)
+		defer rows.Close()
+		var result []string
+		for rows.Next() {
+			var id int
+			var name string
+			assert.NoError(t, rows.Scan(&id, &name))
+			result = append(result, fmt.Sprintf("%d:%s", id, name))
+		}
+		return result
+	}
+	seed := func(src string, opt SeedOpt) error {
+		t.Helper()
+		data, err := ParseYAML(strings.NewReader(TrimIndent(t, src)))
+		assert.NoError(t, err)
+		return Seed(t.Context(), dbc, data, opt)
+	}
+
+	// a view can't be modified without alias
+	err = seed(`
+		active_users:
+		- { id: 1, name: Frank }
+		`, SeedOpt{})
+	assert.Error(t, err)
+
+	var tasks []string
+	aliases := map[string]string{"active_users": "users"}
+	err = seed(`
+		active_users:
+		- { id: 1, name: Frank }
+		- { id: 2, name: Grace }
+		`, SeedOpt{
+		ViewAliases: aliases,
+		Callback: func(targetTable, task string, start bool, err error) {
+			if start {
+				tasks = append(tasks, task+" "+targetTable)
+			}
+		},
+	})
+	assert.NoError(t, err)
+	// callbacks report the name in the dataset
+	assert.Equal(t, []string{"truncate active_users", "insert active_users"}, tasks)
+	assert.Equal(t, []string{"1:Frank", "2:Grace"}, query())
+
+	err = seed(`
+		active_users:
+		- { id: 2, name: Heidi }
+		- { id: 3, name: Ivy }
+		`, SeedOpt{ViewAliases: aliases, Operations: map[string]Operation{"active_users": UpsertOperation}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1:Frank", "2:Heidi", "3:Ivy"}, query())
+
+	err = seed(`
+		active_users:
+		- { id: 1 }
+		`, SeedOpt{ViewAliases: aliases, Operations: map[string]Operation{"active_users": DeleteOperation}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2:Heidi", "3:Ivy"}, query())
+
+	t.Run("streaming", func(t *testing.T) {
+		rows, errs := ParseYAMLStreaming(strings.NewReader("active_users:\n- { id: 4, name: Judy }\n"))
+		err := SeedStreaming(t.Context(), dbc, rows, SeedOpt{ViewAliases: aliases})
+		assert.NoError(t, err)
+		assert.NoError(t, <-errs)
+		assert.Equal(t, []string{"4:Judy"}, query())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := seed(`
+			active_users:
+			- { id: 5 }
+			`, SeedOpt{ViewAliases: aliases})
+		var e ErrSeedFailed
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, "active_users", e.TableName)
+	})
+}
+
+func testSeedSavepoints(t *testing.T, dbc DBConnector) {
+	_, err := dbc.DB().ExecContext(t.Context(), TrimIndent(t, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE logs (id INTEGER PRIMARY KEY, message TEXT NOT NULL);
+		INSERT INTO items (id, name) VALUES (10, 'old');
+	`))
+	assert.NoError(t, err)
+
+	data, err := ParseYAML(strings.NewReader(TrimIndent(t, `
+		users:
+		- { id: 1, name: Frank }
+		items:
+		- { id: 1, name: apple }
+		- { id: 1, name: orange }
+		missing:
+		- { id: 1 }
+		logs:
+		- { id: 1, message: hello }
+		`)))
+	assert.NoError(t, err)
+
+	err = Seed(t.Context(), dbc, data, SeedOpt{Savepoints: true, BatchSize: 1})
+	assert.Error(t, err)
+	var failedTables []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var se ErrSeedFailed
+		assert.True(t, errors.As(e, &se))
+		failedTables = append(failedTables, se.TableName)
+		if se.TableName == "items" {
+			assert.Equal(t, 1, se.RowIndex)
+		}
+	}
+	slices.Sort(failedTables)
+	assert.Equal(t, []string{"items", "missing"}, failedTables)
+
+	count := func(table string) int {
+		t.Helper()
+		var c int
+		assert.NoError(t, dbc.DB().QueryRowContext(t.Context(), "SELECT COUNT(*) FROM "+table).Scan(&c))
+		return c
+	}
+	// other tables are committed
+	assert.Equal(t, 1, count("users"))
+	assert.Equal(t, 1, count("logs"))
+	// only the insertion of the failed table is rolled back
+	assert.Equal(t, 0, count("items"))
+
+	t.Run("without savepoints", func(t *testing.T) {
+		err := Seed(t.Context(), dbc, data, SeedOpt{BatchSize: 1, TargetTables: []string{"users", "items", "logs"}})
+		var se ErrSeedFailed
+		assert.True(t, errors.As(err, &se))
+		assert.Equal(t, "items", se.TableName)
+		assert.Equal(t, 1, count("users"))
+	})
+}
+
+func TestSeedSavepointsSQLite(t *testing.T) {
+	os.Remove("seed_savepoints.db")
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://file:seed_savepoints.db?cache=shared&mode=rwc")
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+	testSeedSavepoints(t, dbc)
+}
+
+func TestSeedSavepointsPostgreSQL(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	connStr := startSeedParallelPostgreSQL(t)
+
+	dbc, err := NewDBConnector(t.Context(), connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+	// PostgreSQL aborts the whole transaction on error unless it is rolled back to the savepoint
+	testSeedSavepoints(t, dbc)
+}
+
+func Test_savepointName(t *testing.T) {
+	assert.Equal(t, "sp_users", savepointName("users"))
+	assert.Equal(t, "sp_analytics_events", savepointName("analytics.events"))
+}
@@ -0,0 +1,32 @@
+package dbtestify
+
+import (
+	"context"
+	"fmt"
+)
+
+// Snapshot reads all rows of the specified tables and returns them as a DataSet.
+//
+// Rows are sorted by the primary keys. The result can be written by DataSet.WriteYAML to create the expected data set.
+func Snapshot(ctx context.Context, dbc DBConnector, tables []string) (*DataSet, error) {
+	result := &DataSet{}
+	for _, tableName := range tables {
+		rows, _, err := fetchTableData(ctx, dbc, tableName, fetchOpt{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to take snapshot of table %s: %w", tableName, err)
+		}
+		t := &Table{
+			Name: tableName,
+		}
+		for _, row := range rows {
+			m := make(map[string]any, len(row))
+			for _, v := range row {
+				m[v.Key] = v.Value
+			}
+			t.Rows = append(t.Rows, m)
+			t.Tags = append(t.Tags, nil)
+		}
+		result.Tables = append(result.Tables, t)
+	}
+	return result, nil
+}
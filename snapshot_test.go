@@ -0,0 +1,51 @@
+package dbtestify
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestSnapshotSQLite(t *testing.T) {
+	os.Remove("snapshot.db")
+	connStr := "file:snapshot.db?cache=shared&mode=rwc"
+	dbc, err := NewDBConnector(t.Context(), "sqlite3://"+connStr)
+	assert.NoError(t, err)
+	defer dbc.DB().Close()
+
+	_, err = dbc.DB().ExecContext(t.Context(), `
+		CREATE TABLE user (id INTEGER PRIMARY KEY, name TEXT NOT NULL, age INTEGER);
+		CREATE TABLE tag (name TEXT PRIMARY KEY);
+		INSERT INTO user (id, name, age) VALUES (2, 'Bob', NULL), (1, 'Alice', 20);
+	`)
+	assert.NoError(t, err)
+
+	data, err := Snapshot(t.Context(), dbc, []string{"user", "tag"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(data.Tables))
+	assert.Equal(t, "user", data.Tables[0].Name)
+	assert.Equal(t, []map[string]any{
+		{"id": 1, "name": "Alice", "age": 20},
+		{"id": 2, "name": "Bob", "age": nil},
+	}, data.Tables[0].Rows)
+	assert.Equal(t, "tag", data.Tables[1].Name)
+	assert.Equal(t, 0, len(data.Tables[1].Rows))
+
+	var buf bytes.Buffer
+	assert.NoError(t, data.WriteYAML(&buf))
+	assert.Equal(t, TrimIndent(t, `
+		tag: []
+		user:
+		- { age: 20, id: 1, name: Alice }
+		- { age: null, id: 2, name: Bob }
+		`)+"\n", buf.String())
+
+	ok, _, err := Assert(t.Context(), dbc, data, AssertOpt{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = Snapshot(t.Context(), dbc, []string{"missing"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package dbtestify
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var stripSpacePattern = regexp.MustCompile("(^[ \t]*)")
+
+// TrimIndent removes the indentation of the first line from all lines, so that data sets can be written inline in tests.
+//
+// The leading empty line, the trailing blank line and trailing newlines are removed. Lines that are indented deeper than the first line keep the extra indentation.
+//
+//	data, err := dbtestify.ParseYAML(strings.NewReader(dbtestify.TrimIndent(t, `
+//		user:
+//		- { id: 1, name: Frank }
+//	`)))
+func TrimIndent(t testing.TB, src string) string {
+	t.Helper()
+	lines := strings.Split(src, "\n")
+	if len(lines) > 1 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	// the line of closing backquote
+	if last := lines[len(lines)-1]; len(lines) > 1 && strings.TrimLeft(last, " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	matches := stripSpacePattern.FindStringSubmatch(lines[0])
+	var b strings.Builder
+	for i, line := range lines {
+		b.WriteString(strings.TrimPrefix(line, matches[0]))
+		if i != len(lines)-1 {
+			b.WriteByte('\n')
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
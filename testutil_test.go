@@ -1,30 +1,65 @@
 package dbtestify
 
 import (
-	"regexp"
-	"strings"
 	"testing"
-)
 
-var stripSpacePattern = regexp.MustCompile("(^[ \t]*)")
+	"github.com/alecthomas/assert/v2"
+)
 
-func TrimIndent(t *testing.T, src string, replace ...string) string {
-	t.Helper()
-	lines := strings.Split(src, "\n")
-	if lines[0] == "" {
-		lines = lines[1:]
+func TestTrimIndent(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "tab",
+			src:  "\n\t\tuser:\n\t\t- { id: 1 }\n\t",
+			want: "user:\n- { id: 1 }",
+		},
+		{
+			name: "space",
+			src:  "\n    user:\n      - id: 1\n",
+			want: "user:\n  - id: 1",
+		},
+		{
+			name: "mixed indent",
+			src:  "\n\t  user:\n\t  \t- { id: 1 }\n\t  - { id: 2 }\n",
+			want: "user:\n\t- { id: 1 }\n- { id: 2 }",
+		},
+		{
+			name: "less indented line is kept",
+			src:  "\n\t\tuser:\n\t- { id: 1 }",
+			want: "user:\n\t- { id: 1 }",
+		},
+		{
+			name: "no leading newline",
+			src:  "  user: []\n",
+			want: "user: []",
+		},
+		{
+			name: "empty",
+			src:  "",
+			want: "",
+		},
+		{
+			name: "only newline",
+			src:  "\n",
+			want: "",
+		},
 	}
-	matches := stripSpacePattern.FindStringSubmatch(lines[0])
-	var b strings.Builder
-	for i, line := range lines {
-		b.WriteString(strings.TrimPrefix(line, matches[0]))
-		if i != len(lines)-1 {
-			b.WriteByte('\n')
-		}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, TrimIndent(t, tt.src))
+		})
 	}
-	result := strings.TrimRight(b.String(), "\n")
-	if len(replace) > 1 {
-		result = strings.ReplaceAll(result, replace[0], replace[1])
+}
+
+func BenchmarkTrimIndent(b *testing.B) {
+	for b.Loop() {
+		TrimIndent(b, `
+			user:
+			- { id: 1, name: Frank }
+			`)
 	}
-	return result
 }
@@ -0,0 +1,59 @@
+package dbtestify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchFile calls fn each time the file is changed until ctx is canceled.
+//
+// Rapid changes within debounce are merged into one call. The folder of the file is watched instead of the file itself,
+// because editors may remove or rename the file while saving it. fn is not called while the file doesn't exist.
+func WatchFile(ctx context.Context, fileName string, debounce time.Duration, fn func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(fileName)); err != nil {
+		return err
+	}
+	return watchFile(ctx, watcher.Events, watcher.Errors, fileName, debounce, fn)
+}
+
+// watchFile is the event loop of WatchFile. events and errs are the channels of fsnotify.Watcher.
+func watchFile(ctx context.Context, events <-chan fsnotify.Event, errs <-chan error, fileName string, debounce time.Duration, fn func()) error {
+	target := filepath.Clean(fileName)
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(e.Name) == target && e.Op != fsnotify.Chmod {
+				timer.Reset(debounce)
+			}
+		case _, ok := <-errs:
+			if !ok {
+				return nil
+			}
+			// events may be lost
+			timer.Reset(debounce)
+		case <-timer.C:
+			if _, err := os.Stat(fileName); err != nil {
+				// removed while saving. Create event will come later
+				continue
+			}
+			fn()
+		}
+	}
+}
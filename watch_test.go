@@ -0,0 +1,104 @@
+package dbtestify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/fsnotify/fsnotify"
+)
+
+// fakeWatcher feeds events to watchFile instead of fsnotify.Watcher.
+type fakeWatcher struct {
+	events chan fsnotify.Event
+	errs   chan error
+	calls  chan struct{}
+	done   chan error
+}
+
+func startFakeWatcher(t *testing.T, fileName string) *fakeWatcher {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	w := &fakeWatcher{
+		events: make(chan fsnotify.Event),
+		errs:   make(chan error),
+		calls:  make(chan struct{}, 10),
+		done:   make(chan error, 1),
+	}
+	go func() {
+		w.done <- watchFile(ctx, w.events, w.errs, fileName, 50*time.Millisecond, func() {
+			w.calls <- struct{}{}
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-w.done)
+	})
+	return w
+}
+
+// count returns the number of calls after events are settled.
+func (w *fakeWatcher) count() int {
+	time.Sleep(150 * time.Millisecond)
+	return len(w.calls)
+}
+
+func TestWatchFile(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "seed.yaml")
+	assert.NoError(t, os.WriteFile(fileName, []byte("users:\n"), 0o644))
+
+	t.Run("debounce", func(t *testing.T) {
+		w := startFakeWatcher(t, fileName)
+		for range 3 {
+			w.events <- fsnotify.Event{Name: fileName, Op: fsnotify.Write}
+		}
+		assert.Equal(t, 1, w.count())
+	})
+
+	t.Run("ignore other files and chmod", func(t *testing.T) {
+		w := startFakeWatcher(t, fileName)
+		w.events <- fsnotify.Event{Name: filepath.Join(dir, "other.yaml"), Op: fsnotify.Write}
+		w.events <- fsnotify.Event{Name: fileName, Op: fsnotify.Chmod}
+		assert.Equal(t, 0, w.count())
+	})
+
+	t.Run("removed while saving", func(t *testing.T) {
+		w := startFakeWatcher(t, fileName)
+		assert.NoError(t, os.Remove(fileName))
+		w.events <- fsnotify.Event{Name: fileName, Op: fsnotify.Remove}
+		assert.Equal(t, 0, w.count())
+
+		assert.NoError(t, os.WriteFile(fileName, []byte("users:\n"), 0o644))
+		w.events <- fsnotify.Event{Name: fileName, Op: fsnotify.Create}
+		assert.Equal(t, 1, w.count())
+	})
+
+	t.Run("watcher error", func(t *testing.T) {
+		w := startFakeWatcher(t, fileName)
+		w.errs <- errors.New("queue overflow")
+		assert.Equal(t, 1, w.count())
+	})
+
+	t.Run("fsnotify", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		calls := make(chan struct{}, 10)
+		done := make(chan error, 1)
+		go func() {
+			done <- WatchFile(ctx, fileName, 50*time.Millisecond, func() { calls <- struct{}{} })
+		}()
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, os.WriteFile(fileName, []byte("users:\n- { id: 1 }\n"), 0o644))
+		select {
+		case <-calls:
+		case <-time.After(5 * time.Second):
+			t.Fatal("fn is not called")
+		}
+		cancel()
+		assert.NoError(t, <-done)
+	})
+}